@@ -1,52 +1,247 @@
 package canal
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/withlin/canal-go/client"
+	pb "github.com/withlin/canal-go/protocol"
 	"go.uber.org/zap"
+	"shop-backend/cdc"
+	"shop-backend/dao/mysql"
+	"shop-backend/lifecycle"
+	"shop-backend/models/vo"
 	"shop-backend/rabbitmq"
 	"shop-backend/settings"
+	"strings"
+	"sync"
 	"time"
 )
 
-// Init 初始化并连接canal服务
-func Init(cfg *settings.CanalConfig) {
+const (
+	// 每次获取的变更数据条数
+	batchSize = 100
+	// 重连、重试的初始等待时间以及最大等待时间，每次失败后等待时间翻倍
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+var (
+	statusMu sync.RWMutex
+	// 是否已经连接到canal服务，轮询模式下为是否已经开始轮询
+	connected bool
+	// 未连接的原因
+	statusErr = errors.New("尚未连接到canal服务")
+)
+
+var (
+	positionMu sync.RWMutex
+	// 最后一次成功处理(Ack)的binlog位置
+	lastPosition = new(vo.CanalPositionVO)
+)
+
+// 数据库变更来源
+const (
+	// SourceCanal 通过canal监听binlog
+	SourceCanal = "canal"
+	// SourcePoll 轮询updated_time以及墓碑记录，用于没有canal服务的开发、测试环境
+	SourcePoll = "poll"
+	// SourceNone 不监听数据库变更
+	SourceNone = "none"
+)
+
+// Init 根据配置选择数据库变更来源，并在后台开始监听。服务退出时最先停止监听，正在处理的一批数据处理完成后退出
+func Init(cfg *settings.CanalConfig) error {
+	// 只监听注册过变更事件Handler的表
+	tables := rabbitmq.CDCTables()
+	schema := cfg.Schema
+	if schema == "" {
+		schema = "shop"
+	}
+	switch cfg.Source {
+	case "", SourceCanal:
+		filter := buildFilter(schema, tables)
+		lifecycle.Go("canal", lifecycle.PhaseIntake, func(ctx context.Context) {
+			runCanal(ctx, cfg, filter)
+		})
+	case SourcePoll:
+		// 通过gorm删除时记录墓碑，轮询时发现删除
+		if err := mysql.EnableTombstone(context.Background(), tables); err != nil {
+			return err
+		}
+		interval := time.Duration(cfg.PollInterval) * time.Second
+		if interval <= 0 {
+			interval = time.Second
+		}
+		lifecycle.Go("数据库变更轮询", lifecycle.PhaseIntake, func(ctx context.Context) {
+			runPoller(ctx, schema, tables, interval)
+		})
+	case SourceNone:
+		setStatus(nil)
+		zap.L().Warn("未开启数据库变更监听，缓存只能依赖过期时间更新")
+	default:
+		return fmt.Errorf("不支持的数据库变更来源: %s", cfg.Source)
+	}
+	return nil
+}
+
+// runCanal 连接canal服务并监听数据库变更，连接断开后按照退避时间自动重连，ctx取消后断开连接并返回
+func runCanal(ctx context.Context, cfg *settings.CanalConfig, filter string) {
+	backoff := minBackoff
+	for {
+		connector, err := connect(cfg, filter)
+		if err != nil {
+			setStatus(err)
+			zap.L().Error("连接canal服务失败，等待后重连", zap.Duration("backoff", backoff), zap.Error(err))
+			if !lifecycle.Sleep(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		backoff = minBackoff
+		setStatus(nil)
+		err = listen(ctx, connector)
+		_ = connector.DisConnection()
+		setStatus(fmt.Errorf("canal连接断开: %w", err))
+		if ctx.Err() != nil {
+			zap.L().Info("停止监听数据库变更")
+			return
+		}
+		zap.L().Error("canal连接断开，等待后重连", zap.Duration("backoff", backoff), zap.Error(err))
+		if !lifecycle.Sleep(ctx, backoff) {
+			return
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// Health 检查是否已经连接到canal服务(轮询模式下为是否已经开始轮询)，未开启数据库变更监听时返回nil
+func Health() error {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	if connected {
+		return nil
+	}
+	return statusErr
+}
+
+// setStatus 记录连接状态，err为nil表示已经连接
+func setStatus(err error) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	connected = err == nil
+	if err != nil {
+		statusErr = err
+	}
+}
+
+// LastPosition 获取最后一次成功处理的binlog位置，以及距离变更发生的延迟
+func LastPosition() *vo.CanalPositionVO {
+	positionMu.RLock()
+	defer positionMu.RUnlock()
+	position := *lastPosition
+	if !position.ExecuteTime.IsZero() {
+		position.DelayMs = time.Since(position.ExecuteTime).Milliseconds()
+	}
+	return &position
+}
+
+// connect 连接canal服务并订阅表
+func connect(cfg *settings.CanalConfig, filter string) (*client.SimpleCanalConnector, error) {
 	// 构建canal连接URL
 	connector := client.NewSimpleCanalConnector(cfg.Host,
 		cfg.Port,
 		cfg.User,
 		cfg.Password,
 		cfg.Destination, 60000, 60*60*1000)
-	err := connector.Connect()
-	if err != nil {
-		panic("CanalConfig 初始化失败: " + err.Error())
+	if err := connector.Connect(); err != nil {
+		return nil, err
 	}
 	zap.L().Info("初始化canal服务成功")
 
-	// 监听shop库下的所有表
-	err = connector.Subscribe("shop\\.oms_cart")
-	if err != nil {
-		panic("CanalConfig 监听shop库失败: " + err.Error())
+	if err := connector.Subscribe(filter); err != nil {
+		_ = connector.DisConnection()
+		return nil, err
 	}
-	zap.L().Info("开始监听shop库数据变更")
+	zap.L().Info("开始监听数据库变更", zap.String("filter", filter))
+	return connector, nil
+}
 
-	// 监听数据库变更信息
+// listen 循环获取数据库变更信息，解析为变更事件后发送到MQ中。
+// 只有一批中的所有事件都被MQ确认后才会Ack，否则Rollback，canal会重新投递这一批数据。
+// 与canal的连接出现异常时返回错误，由调用方重连。ctx取消后处理完当前这一批数据再返回
+func listen(ctx context.Context, connector *client.SimpleCanalConnector) error {
+	backoff := minBackoff
 	for {
-		// 每次获取一百条变更数据
-		message, err := connector.Get(100, nil, nil)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		message, err := connector.GetWithOutAck(batchSize, nil, nil)
 		if err != nil {
-			zap.L().Error("canal监听shop库，获取数据库变更信息失败", zap.Error(err))
+			return err
+		}
+		if message == nil {
+			return errors.New("canal客户端未运行")
 		}
 		batchId := message.Id
 		if batchId == -1 || len(message.Entries) <= 0 {
 			// 如果获取到的消息集合为空，1秒后再次获取
-			time.Sleep(1 * time.Second)
+			lifecycle.Sleep(ctx, time.Second)
+			continue
+		}
+		// 将变更事件发送到RabbitMQ中
+		if err = rabbitmq.PublishCDCEvents(ctx, cdc.ParseEntries(message.Entries)); err != nil {
+			zap.L().Error("发送数据库变更事件失败，回滚本批数据", zap.Int64("batchId", batchId), zap.Duration("backoff", backoff), zap.Error(err))
+			if err = connector.RollBack(batchId); err != nil {
+				return err
+			}
+			lifecycle.Sleep(ctx, backoff)
+			backoff = nextBackoff(backoff)
 			continue
 		}
-		// 将消息发送到RabbitMQ中
-		_ = rabbitmq.SendDBInfo2MQ(message.Entries)
+		backoff = minBackoff
+		if err = connector.Ack(batchId); err != nil {
+			return err
+		}
+		updatePosition(message)
 	}
 }
 
-func listen() {
+// updatePosition 记录本批数据中最后一条binlog的位置
+func updatePosition(message *pb.Message) {
+	header := message.Entries[len(message.Entries)-1].GetHeader()
+	positionMu.Lock()
+	lastPosition = &vo.CanalPositionVO{
+		Source:        SourceCanal,
+		BatchID:       message.Id,
+		LogfileName:   header.GetLogfileName(),
+		LogfileOffset: header.GetLogfileOffset(),
+		ExecuteTime:   time.UnixMilli(header.GetExecuteTime()),
+		AckTime:       time.Now(),
+	}
+	positionMu.Unlock()
+	zap.L().Info("处理数据库变更成功",
+		zap.Int64("batchId", message.Id),
+		zap.String("logfileName", header.GetLogfileName()),
+		zap.Int64("logfileOffset", header.GetLogfileOffset()),
+		zap.Int64("delayMs", time.Since(time.UnixMilli(header.GetExecuteTime())).Milliseconds()))
+}
 
+// nextBackoff 等待时间翻倍，不超过最大等待时间
+func nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// buildFilter 构建canal订阅的表过滤规则，例如：shop\.oms_cart,shop\.pms_sku
+func buildFilter(schema string, tables []string) string {
+	filters := make([]string, 0, len(tables))
+	for _, table := range tables {
+		filters = append(filters, schema+"\\."+table)
+	}
+	return strings.Join(filters, ",")
 }
@@ -0,0 +1,242 @@
+package canal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"shop-backend/cdc"
+	"shop-backend/dao/mysql"
+	"shop-backend/lifecycle"
+	"shop-backend/models/vo"
+	"shop-backend/rabbitmq"
+	"strconv"
+	"time"
+)
+
+const (
+	// 每次查询变更的最大行数，超过时按照(updated_time, id)分页查询
+	pollBatchSize = 1000
+	// 每次轮询时回看的时间窗口。updated_time精确到秒，并且事务提交前写入的updated_time可能早于提交时间，
+	// 所以重复查询窗口内的行，并通过已发送的行去重
+	pollOverlap = 10 * time.Second
+	// 墓碑记录保留的时间，超过后被删除
+	tombstoneRetention = time.Hour
+)
+
+// poller 轮询updated_time发现新增、更新，轮询墓碑记录发现删除，发送与canal相同的变更事件
+type poller struct {
+	schema string
+	tables []string
+	// K: 表名 V: 已经处理过的最大updated_time
+	checkpoints map[string]time.Time
+	// K: 表名 V: (K: 行的唯一标识 V: 行的updated_time) 时间窗口内已经发送过的行
+	seen map[string]map[string]time.Time
+	// 已经处理过的最大墓碑记录ID
+	tombstoneID int64
+}
+
+// runPoller 使用轮询代替canal监听数据库变更，用于没有canal服务的开发、测试环境。ctx取消后返回
+func runPoller(ctx context.Context, schema string, tables []string, interval time.Duration) {
+	p := &poller{
+		schema:      schema,
+		tables:      tables,
+		checkpoints: make(map[string]time.Time),
+		seen:        make(map[string]map[string]time.Time),
+	}
+	backoff := minBackoff
+	for {
+		if err := p.init(ctx); err != nil {
+			setStatus(err)
+			zap.L().Error("初始化数据库变更轮询失败，等待后重试", zap.Duration("backoff", backoff), zap.Error(err))
+			if !lifecycle.Sleep(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		break
+	}
+	setStatus(nil)
+	zap.L().Info("开始轮询数据库变更", zap.Strings("tables", tables), zap.Duration("interval", interval))
+	lastCleanup := time.Now()
+	for lifecycle.Sleep(ctx, interval) {
+		for _, table := range p.tables {
+			if err := p.pollTable(ctx, table); err != nil {
+				zap.L().Error("轮询数据库变更失败", zap.String("table", table), zap.Error(err))
+			}
+		}
+		if err := p.pollTombstones(ctx); err != nil {
+			zap.L().Error("轮询墓碑记录失败", zap.Error(err))
+		}
+		if time.Since(lastCleanup) > tombstoneRetention {
+			_ = mysql.DeleteTombstonesBefore(ctx, time.Now().Add(-tombstoneRetention))
+			lastCleanup = time.Now()
+		}
+	}
+}
+
+// init 从当前最新的数据开始轮询，不重放历史数据
+func (p *poller) init(ctx context.Context) error {
+	for _, table := range p.tables {
+		checkpoint, err := mysql.SelectMaxUpdatedTime(ctx, table)
+		if err != nil {
+			return err
+		}
+		// 时间窗口内已经存在的行视为已发送
+		seen := make(map[string]time.Time)
+		err = forEachChangedPage(ctx, table, checkpoint.Add(-pollOverlap), func(rows []map[string]string) error {
+			for _, row := range rows {
+				seen[mysql.RowKey(row)] = parseColumnTime(row["updated_time"])
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		p.checkpoints[table] = checkpoint
+		p.seen[table] = seen
+	}
+	tombstoneID, err := mysql.SelectMaxTombstoneID(ctx)
+	if err != nil {
+		return err
+	}
+	p.tombstoneID = tombstoneID
+	return nil
+}
+
+// pollTable 分页查询时间窗口内发生变更的行，发送新增、更新事件。
+// 每一页发送成功后更新检查点，发送失败时剩余的行在下次轮询重新发送
+func (p *poller) pollTable(ctx context.Context, table string) error {
+	sent := false
+	err := forEachChangedPage(ctx, table, p.checkpoints[table].Add(-pollOverlap), func(rows []map[string]string) error {
+		published, err := p.publishRows(table, rows)
+		sent = sent || published
+		return err
+	})
+	if !sent {
+		return err
+	}
+	// 移除已经在时间窗口之外的行
+	checkpoint := p.checkpoints[table]
+	for key, updatedTime := range p.seen[table] {
+		if updatedTime.Before(checkpoint.Add(-pollOverlap)) {
+			delete(p.seen[table], key)
+		}
+	}
+	updatePollPosition(table, checkpoint)
+	return err
+}
+
+// publishRows 发送一页中没有发送过的行，发送成功后记录已发送的行并更新检查点。返回是否发送了事件
+func (p *poller) publishRows(table string, rows []map[string]string) (bool, error) {
+	events := make([]*cdc.Event, 0)
+	sent := make(map[string]time.Time)
+	checkpoint := p.checkpoints[table]
+	for _, row := range rows {
+		key := mysql.RowKey(row)
+		if _, ok := p.seen[table][key]; ok {
+			continue
+		}
+		updatedTime := parseColumnTime(row["updated_time"])
+		event := &cdc.Event{
+			Schema:      p.schema,
+			Table:       table,
+			Type:        cdc.EventUpdate,
+			After:       row,
+			ExecuteTime: updatedTime,
+		}
+		if row["created_time"] == row["updated_time"] {
+			// 创建后没有更新过，视为新增
+			event.Type = cdc.EventInsert
+		}
+		events = append(events, event)
+		sent[key] = updatedTime
+		if updatedTime.After(checkpoint) {
+			checkpoint = updatedTime
+		}
+	}
+	if len(events) == 0 {
+		return false, nil
+	}
+	if err := rabbitmq.PublishCDCEvents(context.Background(), events); err != nil {
+		return false, err
+	}
+	p.checkpoints[table] = checkpoint
+	for key, updatedTime := range sent {
+		p.seen[table][key] = updatedTime
+	}
+	return true, nil
+}
+
+// forEachChangedPage 从since开始，以(updated_time, id)为游标分页遍历发生变更的行，直到最后一页。
+// 时间窗口内的行超过一页时(例如批量更新)，仍然可以遍历到窗口之后新变更的行
+func forEachChangedPage(ctx context.Context, table string, since time.Time, fn func(rows []map[string]string) error) error {
+	afterTime, afterID := since, int64(0)
+	for {
+		rows, err := mysql.SelectChangedRows(ctx, table, afterTime, afterID, pollBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err = fn(rows); err != nil {
+			return err
+		}
+		if len(rows) < pollBatchSize {
+			return nil
+		}
+		last := rows[len(rows)-1]
+		afterTime = parseColumnTime(last["updated_time"])
+		if afterID, err = strconv.ParseInt(last["id"], 10, 64); err != nil {
+			return fmt.Errorf("解析表 %s 的id失败: %w", table, err)
+		}
+	}
+}
+
+// pollTombstones 查询新的墓碑记录，发送删除事件
+func (p *poller) pollTombstones(ctx context.Context) error {
+	tombstones, err := mysql.SelectTombstones(ctx, p.tombstoneID, pollBatchSize)
+	if err != nil || len(tombstones) == 0 {
+		return err
+	}
+	events := make([]*cdc.Event, 0, len(tombstones))
+	for _, tombstone := range tombstones {
+		row := make(map[string]string)
+		if err = json.Unmarshal([]byte(tombstone.RowData), &row); err != nil {
+			zap.L().Error("反序列化墓碑记录失败", zap.Int64("id", tombstone.ID), zap.Error(err))
+			continue
+		}
+		events = append(events, &cdc.Event{
+			Schema:      p.schema,
+			Table:       tombstone.Table,
+			Type:        cdc.EventDelete,
+			Before:      row,
+			ExecuteTime: tombstone.CreatedTime,
+		})
+	}
+	if err = rabbitmq.PublishCDCEvents(context.Background(), events); err != nil {
+		return err
+	}
+	p.tombstoneID = tombstones[len(tombstones)-1].ID
+	return nil
+}
+
+// updatePollPosition 轮询模式下记录最后处理的表以及updated_time
+func updatePollPosition(table string, checkpoint time.Time) {
+	positionMu.Lock()
+	lastPosition = &vo.CanalPositionVO{
+		Source:      SourcePoll,
+		Table:       table,
+		ExecuteTime: checkpoint,
+		AckTime:     time.Now(),
+	}
+	positionMu.Unlock()
+}
+
+// parseColumnTime 解析列中的时间
+func parseColumnTime(value string) time.Time {
+	t, _ := time.ParseInLocation(mysql.CDCTimeLayout, value, time.Local)
+	return t
+}
@@ -0,0 +1,71 @@
+package cdc
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"sort"
+)
+
+// Handler 处理一个变更事件，返回错误时事件会被重新处理，所以Handler需要是幂等的
+type Handler func(event *Event) error
+
+// Dispatcher 按照表名、变更类型将变更事件分发给注册的Handler。
+// 一个Dispatcher对应MQ中的一个队列，队列只绑定Dispatcher中注册过的表
+type Dispatcher struct {
+	// K: 表名 V: (K: 变更类型 V: Handler集合)
+	handlers map[string]map[EventType][]Handler
+}
+
+// NewDispatcher 创建一个空的变更事件分发器
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[string]map[EventType][]Handler)}
+}
+
+// Register 注册处理指定表、指定变更类型的Handler，未指定变更类型时处理该表所有类型的变更
+func (d *Dispatcher) Register(table string, handler Handler, eventTypes ...EventType) *Dispatcher {
+	if len(eventTypes) == 0 {
+		eventTypes = []EventType{EventInsert, EventUpdate, EventDelete}
+	}
+	if d.handlers[table] == nil {
+		d.handlers[table] = make(map[EventType][]Handler)
+	}
+	for _, eventType := range eventTypes {
+		d.handlers[table][eventType] = append(d.handlers[table][eventType], handler)
+	}
+	return d
+}
+
+// Tables 返回注册过Handler的表名，按字母排序
+func (d *Dispatcher) Tables() []string {
+	tables := make([]string, 0, len(d.handlers))
+	for table := range d.handlers {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// RoutingKeys 返回队列需要绑定的路由，每个注册过的表、变更类型一个路由
+func (d *Dispatcher) RoutingKeys() []string {
+	keys := make([]string, 0)
+	for _, table := range d.Tables() {
+		for _, eventType := range []EventType{EventInsert, EventUpdate, EventDelete} {
+			if len(d.handlers[table][eventType]) != 0 {
+				keys = append(keys, RoutingKey(table, eventType))
+			}
+		}
+	}
+	return keys
+}
+
+// Dispatch 将变更事件交给所有匹配的Handler处理，任意一个Handler失败都会返回错误
+func (d *Dispatcher) Dispatch(event *Event) error {
+	for _, handler := range d.handlers[event.Table][event.Type] {
+		if err := handler(event); err != nil {
+			zap.L().Error("处理数据库变更事件失败",
+				zap.String("table", event.Table), zap.String("type", string(event.Type)), zap.Error(err))
+			return fmt.Errorf("处理 %s 表的 %s 事件失败: %w", event.Table, event.Type, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package cdc
+
+import (
+	"strconv"
+	"time"
+)
+
+// EventType 数据库变更类型
+type EventType string
+
+const (
+	EventInsert EventType = "insert"
+	EventUpdate EventType = "update"
+	EventDelete EventType = "delete"
+)
+
+// Event 一行数据的变更事件，与具体的表无关
+type Event struct {
+	// 发生变更的库名
+	Schema string `json:"schema"`
+	// 发生变更的表名
+	Table string `json:"table"`
+	// 变更类型：insert、update、delete
+	Type EventType `json:"type"`
+	// 变更前的列，K: 列名 V: 列值。新增时为空
+	Before map[string]string `json:"before"`
+	// 变更后的列，K: 列名 V: 列值。删除时为空
+	After map[string]string `json:"after"`
+	// 更新时值发生变化的列名
+	Updated []string `json:"updated"`
+	// binlog中记录的变更执行时间
+	ExecuteTime time.Time `json:"executeTime"`
+}
+
+// Row 返回事件对应的行数据：新增、更新返回变更后的列，删除返回变更前的列
+func (e *Event) Row() map[string]string {
+	if e.Type == EventDelete {
+		return e.Before
+	}
+	return e.After
+}
+
+// String 获取行数据中指定列的值
+func (e *Event) String(column string) string {
+	return e.Row()[column]
+}
+
+// Int64 获取行数据中指定列的值并转换为int64，列不存在或不是整数时返回0
+func (e *Event) Int64(column string) int64 {
+	value, _ := strconv.ParseInt(e.Row()[column], 10, 64)
+	return value
+}
+
+// Int64s 获取变更前后指定列的值(去重、忽略0)，用于外键发生变化时同时处理新旧两个值
+func (e *Event) Int64s(column string) []int64 {
+	values := make([]int64, 0, 2)
+	for _, row := range []map[string]string{e.Before, e.After} {
+		value, err := strconv.ParseInt(row[column], 10, 64)
+		if err != nil || value == 0 {
+			continue
+		}
+		if len(values) == 0 || values[0] != value {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Changed 更新时指定列的值是否发生了变化，新增、删除时总是返回true
+func (e *Event) Changed(column string) bool {
+	if e.Type != EventUpdate {
+		return true
+	}
+	for _, name := range e.Updated {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// RoutingKey 事件发送到MQ时使用的路由：cdc.<表名>.<变更类型>
+func (e *Event) RoutingKey() string {
+	return RoutingKey(e.Table, e.Type)
+}
+
+// RoutingKey 构建表、变更类型对应的路由，eventType为空时匹配该表的所有变更类型
+func RoutingKey(table string, eventType EventType) string {
+	if eventType == "" {
+		return "cdc." + table + ".*"
+	}
+	return "cdc." + table + "." + string(eventType)
+}
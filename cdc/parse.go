@@ -0,0 +1,66 @@
+package cdc
+
+import (
+	"github.com/golang/protobuf/proto"
+	pbe "github.com/withlin/canal-go/protocol/entry"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ParseEntries 将canal获取到的binlog条目解析为变更事件，跳过事务开始、结束以及非增删改的条目
+func ParseEntries(entries []pbe.Entry) []*Event {
+	events := make([]*Event, 0, len(entries))
+	for _, entry := range entries {
+		if entry.GetEntryType() != pbe.EntryType_ROWDATA {
+			continue
+		}
+		rowChange := new(pbe.RowChange)
+		if err := proto.Unmarshal(entry.GetStoreValue(), rowChange); err != nil {
+			zap.L().Error("反序列化canal消息失败", zap.Error(err))
+			continue
+		}
+		var eventType EventType
+		switch rowChange.GetEventType() {
+		case pbe.EventType_INSERT:
+			eventType = EventInsert
+		case pbe.EventType_UPDATE:
+			eventType = EventUpdate
+		case pbe.EventType_DELETE:
+			eventType = EventDelete
+		default:
+			continue
+		}
+		header := entry.GetHeader()
+		for _, rowData := range rowChange.GetRowDatas() {
+			event := &Event{
+				Schema:      header.GetSchemaName(),
+				Table:       header.GetTableName(),
+				Type:        eventType,
+				ExecuteTime: time.UnixMilli(header.GetExecuteTime()),
+			}
+			if eventType != EventInsert {
+				event.Before = columnMap(rowData.GetBeforeColumns(), nil)
+			}
+			if eventType != EventDelete {
+				event.After = columnMap(rowData.GetAfterColumns(), &event.Updated)
+			}
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// columnMap 将列集合转换为 列名->列值 的映射，值为NULL的列不会出现在映射中。updated不为空时记录值发生变化的列名
+func columnMap(columns []*pbe.Column, updated *[]string) map[string]string {
+	row := make(map[string]string, len(columns))
+	for _, col := range columns {
+		if updated != nil && col.GetUpdated() {
+			*updated = append(*updated, col.GetName())
+		}
+		if col.GetIsNull() {
+			continue
+		}
+		row[col.GetName()] = col.GetValue()
+	}
+	return row
+}
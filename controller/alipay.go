@@ -3,7 +3,9 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/logic"
+	"shop-backend/metrics"
 	"shop-backend/models/dto"
 	"strconv"
 )
@@ -15,6 +17,7 @@ import (
 // @Produce json
 // @Router /oms/order/pay/notify [get]
 func AlipayNotifyHandler(c *gin.Context) {
+	metrics.OrderPaid()
 	ResponseSuccessWithMsg(c, "支付成功，请在我的订单查看详情🙉", nil)
 }
 
@@ -27,24 +30,30 @@ func AlipayNotifyHandler(c *gin.Context) {
 // @Param Alipay body dto.AliPay true "支付宝支付结构体"
 // @Router /oms/order/pay [post]
 func AlipayHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	aliPay := new(dto.AliPay)
 	err := c.ShouldBindJSON(aliPay)
 	if err != nil {
-		zap.L().Error("支付宝支付接口，传递参数错误")
-		ResponseError(c, CodeServeBusy)
+		logger.Ctx(ctx).Error("支付宝支付接口，传递参数错误")
+		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
 	orderNum, err := strconv.ParseInt(aliPay.OrderNum, 10, 64)
 	if err != nil {
-		zap.L().Error("支付宝支付接口，订单号转为int64错误")
-		ResponseError(c, CodeServeBusy)
+		logger.Ctx(ctx).Error("支付宝支付接口，订单号转为int64错误")
+		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
-	payUrl, err := logic.CreateAlipayOrder(c.GetInt64("uid"), orderNum)
-	if err != nil || payUrl == "" {
-		zap.L().Error("支付宝支付接口，拉起支付宝支付失败")
+	payUrl, err := logic.CreateAlipayOrder(ctx, c.GetInt64("uid"), orderNum)
+	if err != nil {
+		logger.Ctx(ctx).Error("支付宝支付接口，查询订单失败", zap.Int64("orderNum", orderNum), zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	if payUrl == "" {
+		logger.Ctx(ctx).Error("支付宝支付接口，拉起支付宝支付失败")
 		ResponseError(c, CodeServeBusy)
 		return
 	}
@@ -0,0 +1,17 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"shop-backend/canal"
+)
+
+// AdminCanalPositionHandler 获取canal最后处理的binlog位置
+// @Summary 后台获取canal最后处理的binlog位置
+// @Description 管理员获取canal最后一次成功处理的binlog位置，以及距离变更发生的延迟，用于判断缓存同步是否落后
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /admin/canal/position [get]
+func AdminCanalPositionHandler(c *gin.Context) {
+	ResponseSuccess(c, canal.LastPosition())
+}
@@ -1,5 +1,10 @@
 package controller
 
+import (
+	"go.uber.org/zap"
+	"net/http"
+)
+
 // ResCode 错误码
 type ResCode int64
 
@@ -43,6 +48,32 @@ const (
 	CodeCreateSubmitOrderSuccess
 	CodeToManyRequest
 	CodeSecKillFinished
+	CodeNoPermission
+	CodeBrandNotExist
+	CodeBrandHasSpu
+	CodeUploadBrandLogoFailed
+	CodeCategoryNotExist
+	CodeCategoryHasSpu
+	CodeCategoryHasChildren
+	CodeCategoryInvalidParent
+	CodeAttributeNotExist
+	CodeAttributeHasValues
+	CodeAttributeInUse
+	CodeAttributeInvalidParent
+	CodeAttributeNotInCategory
+	CodeSpuNotExist
+	CodeOrderItemNotCompleted
+	CodeCommentExist
+	CodeCommentNotExist
+	CodeCommentAlreadyVoted
+	CodeCommentPicIllegal
+	CodeUploadCommentPicFailed
+	CodeQueueNotExist
+	CodeServiceNotReady
+	CodeNotFound
+	CodeConflict
+	CodeInsufficientStock
+	CodeRouteNotFound
 )
 
 // map字典 K: 错误码	V: 错误信息
@@ -87,6 +118,101 @@ var codeMsgMap = map[ResCode]string{
 	CodeCreateSubmitOrderSuccess:      "订单提交成功🐔",
 	CodeToManyRequest:                 "当前活动太火爆啦，等会再试试吧🍻",
 	CodeSecKillFinished:               "秒杀活动已结束，谢谢参与😮",
+	CodeNoPermission:                  "无权限访问🚫",
+	CodeBrandNotExist:                 "品牌不存在",
+	CodeBrandHasSpu:                   "品牌下仍有商品，不能删除",
+	CodeUploadBrandLogoFailed:         "上传品牌Logo失败🫥",
+	CodeCategoryNotExist:              "商品分类不存在",
+	CodeCategoryHasSpu:                "商品分类下仍有商品，不能删除",
+	CodeCategoryHasChildren:           "商品分类下仍有子分类",
+	CodeCategoryInvalidParent:         "父分类不合法",
+	CodeAttributeNotExist:             "商品属性不存在",
+	CodeAttributeHasValues:            "属性名下仍有属性值，不能删除",
+	CodeAttributeInUse:                "属性值仍被商品使用，不能删除",
+	CodeAttributeInvalidParent:        "父属性不合法",
+	CodeAttributeNotInCategory:        "属性值不属于商品所在的分类",
+	CodeSpuNotExist:                   "商品不存在",
+	CodeOrderItemNotCompleted:         "只能评价已完成订单中的商品",
+	CodeCommentExist:                  "该商品已经评价过了",
+	CodeCommentNotExist:               "评价不存在",
+	CodeCommentAlreadyVoted:           "已经投过票了",
+	CodeCommentPicIllegal:             "评价图片不合法",
+	CodeUploadCommentPicFailed:        "上传评价图片失败🫥",
+	CodeQueueNotExist:                 "队列不存在",
+	CodeServiceNotReady:               "服务未就绪",
+	CodeNotFound:                      "资源不存在",
+	CodeConflict:                      "资源状态冲突，请刷新后重试",
+	CodeInsufficientStock:             "商品已下架或库存不足",
+	CodeRouteNotFound:                 "接口不存在",
+}
+
+// map字典 K: 错误码	V: HTTP状态码
+var codeStatusMap = map[ResCode]int{
+	CodeSuccess:                       http.StatusOK,
+	CodeServeBusy:                     http.StatusInternalServerError,
+	CodePhoneIsNotEmpty:               http.StatusBadRequest,
+	CodePhoneFormatError:              http.StatusBadRequest,
+	CodeEmailFormatError:              http.StatusBadRequest,
+	CodeInvalidParams:                 http.StatusBadRequest,
+	CodeWrongVerifyCode:               http.StatusBadRequest,
+	CodePassIsWeak:                    http.StatusBadRequest,
+	CodeRequestCodeFrequently:         http.StatusTooManyRequests,
+	CodeUserIsRegistered:              http.StatusConflict,
+	CodeTokenIsEmpty:                  http.StatusUnauthorized,
+	CodeTokenIsWrongFormat:            http.StatusUnauthorized,
+	CodeTokenIsInvalid:                http.StatusUnauthorized,
+	CodeUpdateInfosFailed:             http.StatusInternalServerError,
+	CodeUsernameToLongOrToShort:       http.StatusBadRequest,
+	CodeExceedMaxTerminalNum:          http.StatusUnauthorized,
+	CodeSignOutFailed:                 http.StatusInternalServerError,
+	CodeTokenRefreshFailed:            http.StatusInternalServerError,
+	CodeAccessTokenIsLiving:           http.StatusBadRequest,
+	CodeTokenExpire:                   http.StatusUnauthorized,
+	CodeUsernameOrPassError:           http.StatusUnauthorized,
+	CodeUserNotExist:                  http.StatusNotFound,
+	CodeUploadAvatarFailed:            http.StatusInternalServerError,
+	CodeUploadAvatarToBigOrExtError:   http.StatusBadRequest,
+	CodeMustRequestCode:               http.StatusBadRequest,
+	CodeNeedReLogin:                   http.StatusUnauthorized,
+	CodeFrontEndNeedUseNewToken:       http.StatusOK, // 刷新成功，响应体中带有新的AccessToken
+	CodeRequestAllCategoryFailed:      http.StatusInternalServerError,
+	CodeRequestAllAttributeFailed:     http.StatusInternalServerError,
+	CodeSearchConditionIsNil:          http.StatusBadRequest,
+	CodeDeleteCartProductFailed:       http.StatusInternalServerError,
+	CodeUpdateCartProductStatusFailed: http.StatusInternalServerError,
+	CodeCreatePreSubmitOrderSuccess:   http.StatusOK,
+	CodeAddReceiverAddressFailed:      http.StatusInternalServerError,
+	CodeUpdateReceiverAddressFailed:   http.StatusInternalServerError,
+	CodeOrderNumISNotExistOrExpired:   http.StatusConflict,
+	CodeCreateSubmitOrderSuccess:      http.StatusOK,
+	CodeToManyRequest:                 http.StatusTooManyRequests,
+	CodeSecKillFinished:               http.StatusConflict,
+	CodeNoPermission:                  http.StatusForbidden,
+	CodeBrandNotExist:                 http.StatusNotFound,
+	CodeBrandHasSpu:                   http.StatusConflict,
+	CodeUploadBrandLogoFailed:         http.StatusInternalServerError,
+	CodeCategoryNotExist:              http.StatusNotFound,
+	CodeCategoryHasSpu:                http.StatusConflict,
+	CodeCategoryHasChildren:           http.StatusConflict,
+	CodeCategoryInvalidParent:         http.StatusBadRequest,
+	CodeAttributeNotExist:             http.StatusNotFound,
+	CodeAttributeHasValues:            http.StatusConflict,
+	CodeAttributeInUse:                http.StatusConflict,
+	CodeAttributeInvalidParent:        http.StatusBadRequest,
+	CodeAttributeNotInCategory:        http.StatusBadRequest,
+	CodeSpuNotExist:                   http.StatusNotFound,
+	CodeOrderItemNotCompleted:         http.StatusConflict,
+	CodeCommentExist:                  http.StatusConflict,
+	CodeCommentNotExist:               http.StatusNotFound,
+	CodeCommentAlreadyVoted:           http.StatusConflict,
+	CodeCommentPicIllegal:             http.StatusBadRequest,
+	CodeUploadCommentPicFailed:        http.StatusInternalServerError,
+	CodeQueueNotExist:                 http.StatusNotFound,
+	CodeServiceNotReady:               http.StatusServiceUnavailable,
+	CodeNotFound:                      http.StatusNotFound,
+	CodeConflict:                      http.StatusConflict,
+	CodeInsufficientStock:             http.StatusConflict,
+	CodeRouteNotFound:                 http.StatusNotFound,
 }
 
 // Msg 为ResCode注册一个Msg方法，负责返回错误码对应的错误信息
@@ -97,3 +223,13 @@ func (c ResCode) Msg() string {
 	}
 	return msg
 }
+
+// Status 为ResCode注册一个Status方法，负责返回错误码对应的HTTP状态码
+func (c ResCode) Status() int {
+	status, ok := codeStatusMap[c]
+	if !ok {
+		zap.L().Error("错误码没有对应的HTTP状态码", zap.Int64("code", int64(c)))
+		status = http.StatusInternalServerError
+	}
+	return status
+}
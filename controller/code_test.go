@@ -0,0 +1,18 @@
+package controller
+
+import "testing"
+
+// TestCodeStatus 每个错误码都需要有对应的HTTP状态码，否则会返回500
+func TestCodeStatus(t *testing.T) {
+	for code := range codeMsgMap {
+		if _, ok := codeStatusMap[code]; !ok {
+			t.Errorf("错误码 %d(%s) 没有对应的HTTP状态码", code, code.Msg())
+		}
+	}
+	if status := CodeRouteNotFound.Status(); status != 404 {
+		t.Errorf("接口不存在返回 %d，期望 404", status)
+	}
+	if status := CodeFrontEndNeedUseNewToken.Status(); status != 200 {
+		t.Errorf("刷新AccessToken成功返回 %d，期望 200", status)
+	}
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/logic"
+	"shop-backend/models/dto"
+	"strconv"
+)
+
+// AdminDeadLetterQueueListHandler 获取所有死信队列
+// @Summary 后台获取所有死信队列
+// @Description 管理员获取所有死信队列以及其中的消息数量。消息处理失败次数达到上限后会进入对应队列的死信队列
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /admin/mq/deadletter/list [get]
+func AdminDeadLetterQueueListHandler(c *gin.Context) {
+	queues, err := logic.GetDeadLetterQueues()
+	if err != nil {
+		logger.Ctx(c.Request.Context()).Error("后台获取所有死信队列接口，获取失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, queues)
+}
+
+// AdminDeadLetterPeekHandler 查看死信队列中的消息
+// @Summary 后台查看死信队列中的消息
+// @Description 管理员查看死信队列中的前count条消息，消息仍然保留在死信队列中
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param queue query string true "原队列名称"
+// @Param count query int false "查看的消息数量，默认为10，最大为100"
+// @Router /admin/mq/deadletter/peek [get]
+func AdminDeadLetterPeekHandler(c *gin.Context) {
+	queue := c.Query("queue")
+	count, _ := strconv.Atoi(c.Query("count"))
+	if queue == "" {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	deadLetters, err := logic.PeekDeadLetters(queue, count)
+	if err != nil {
+		logger.Ctx(c.Request.Context()).Error("后台查看死信队列中的消息接口，查看失败", zap.String("queue", queue), zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccess(c, deadLetters)
+}
+
+// AdminDeadLetterReplayHandler 重新发送死信队列中的消息
+// @Summary 后台重新发送死信队列中的消息
+// @Description 管理员将死信队列中的消息重新发送到原队列，失败次数清零
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param replay body dto.DeadLetterReplay true "原队列名称以及重新发送的消息数量"
+// @Router /admin/mq/deadletter/replay [post]
+func AdminDeadLetterReplayHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	replay := new(dto.DeadLetterReplay)
+	if err := c.ShouldBindJSON(replay); err != nil {
+		logger.Ctx(ctx).Error("后台重新发送死信队列中的消息接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	count, err := logic.ReplayDeadLetters(replay)
+	if err != nil {
+		logger.Ctx(ctx).Error("后台重新发送死信队列中的消息接口，重新发送失败", zap.String("queue", replay.Queue), zap.Int("count", count), zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccess(c, count)
+}
+
+// AdminDeadLetterPurgeHandler 清空死信队列
+// @Summary 后台清空死信队列
+// @Description 管理员清空死信队列，死信队列中的消息会被永久删除
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param queue query string true "原队列名称"
+// @Router /admin/mq/deadletter/purge [delete]
+func AdminDeadLetterPurgeHandler(c *gin.Context) {
+	queue := c.Query("queue")
+	if queue == "" {
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	count, err := logic.PurgeDeadLetters(queue)
+	if err != nil {
+		logger.Ctx(c.Request.Context()).Error("后台清空死信队列接口，清空失败", zap.String("queue", queue), zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccess(c, count)
+}
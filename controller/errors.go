@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"shop-backend/dao/mysql"
+	"shop-backend/errs"
+	"shop-backend/logic"
+	"shop-backend/rabbitmq"
+)
+
+// errorCodes 有专属错误码的错误，按顺序匹配。没有专属错误码的领域错误按照类型转换为通用错误码
+var errorCodes = []struct {
+	err  error
+	code ResCode
+}{
+	{mysql.ErrorBrandNotExist, CodeBrandNotExist},
+	{mysql.ErrorBrandHasSpu, CodeBrandHasSpu},
+	{mysql.ErrorCategoryNotExist, CodeCategoryNotExist},
+	{mysql.ErrorCategoryHasSpu, CodeCategoryHasSpu},
+	{mysql.ErrorCategoryHasChildren, CodeCategoryHasChildren},
+	{mysql.ErrorCategoryInvalidParent, CodeCategoryInvalidParent},
+	{mysql.ErrorAttributeNotExist, CodeAttributeNotExist},
+	{mysql.ErrorAttributeHasValues, CodeAttributeHasValues},
+	{mysql.ErrorAttributeInUse, CodeAttributeInUse},
+	{mysql.ErrorAttributeInvalidParent, CodeAttributeInvalidParent},
+	{logic.ErrorAttributeNotInCategory, CodeAttributeNotInCategory},
+	{mysql.ErrorSpuNotExist, CodeSpuNotExist},
+	{mysql.ErrorInsufficientStock, CodeInsufficientStock},
+	{mysql.ErrorOrderItemNotCompleted, CodeOrderItemNotCompleted},
+	{mysql.ErrorCommentExist, CodeCommentExist},
+	{mysql.ErrorCommentNotExist, CodeCommentNotExist},
+	{mysql.ErrorCommentAlreadyVoted, CodeCommentAlreadyVoted},
+	{logic.ErrorCommentPicIllegal, CodeCommentPicIllegal},
+	{logic.ErrorUserIsRegistered, CodeUserIsRegistered},
+	{logic.ErrorUserNotExist, CodeUserNotExist},
+	{logic.ErrorWrongVerifyCode, CodeWrongVerifyCode},
+	{logic.ErrorMustRequestCode, CodeMustRequestCode},
+	{logic.ErrorRequestCodeFrequent, CodeRequestCodeFrequently},
+	{logic.ErrorWrongPass, CodeUsernameOrPassError},
+	{rabbitmq.ErrorQueueNotExist, CodeQueueNotExist},
+	{rabbitmq.ErrorSecKillFinished, CodeSecKillFinished},
+}
+
+// kindCodes 领域错误类型对应的通用错误码
+var kindCodes = map[errs.Kind]ResCode{
+	errs.KindNotFound:          CodeNotFound,
+	errs.KindConflict:          CodeConflict,
+	errs.KindInsufficientStock: CodeInsufficientStock,
+	errs.KindForbidden:         CodeNoPermission,
+	errs.KindValidation:        CodeInvalidParams,
+	errs.KindUnavailable:       CodeServiceNotReady,
+}
+
+// ErrorCode 将logic、dao返回的错误转换为错误码，错误码决定了HTTP状态码。
+// 优先使用专属错误码，其次按照领域错误类型转换，其余错误视为服务内部错误
+func ErrorCode(err error) ResCode {
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+	if code, ok := kindCodes[errs.KindOf(err)]; ok {
+		return code
+	}
+	return CodeServeBusy
+}
+
+// ResponseFromError 根据logic、dao返回的错误响应前端
+func ResponseFromError(c *gin.Context, err error) {
+	ResponseError(c, ErrorCode(err))
+}
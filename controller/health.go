@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"shop-backend/logger"
+	"shop-backend/logic"
+)
+
+// HealthzHandler 存活检查
+// @Summary 存活检查
+// @Description 进程可以处理请求即返回成功，不检查依赖，用于判断是否需要重启服务
+// @Tags 健康检查接口
+// @Produce  json
+// @Router /healthz [get]
+func HealthzHandler(c *gin.Context) {
+	ResponseSuccess(c, gin.H{"status": logic.StatusUp})
+}
+
+// ReadyzHandler 就绪检查
+// @Summary 就绪检查
+// @Description 检查MySQL、Redis、RabbitMQ、canal、支付模块，返回每个依赖的状态、耗时以及失败原因。有依赖不可用时返回503，用于判断是否可以接收流量
+// @Tags 健康检查接口
+// @Produce  json
+// @Router /readyz [get]
+func ReadyzHandler(c *gin.Context) {
+	readiness := logic.CheckReadiness()
+	if readiness.Status != logic.StatusUp {
+		c.JSON(CodeServiceNotReady.Status(), &ResponseData{
+			Code:      CodeServiceNotReady,
+			Msg:       CodeServiceNotReady.Msg(),
+			Data:      readiness,
+			RequestID: logger.RequestID(c.Request.Context()),
+		})
+		return
+	}
+	ResponseSuccess(c, readiness)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"shop-backend/dao/redis"
+	"shop-backend/logger"
 	"shop-backend/logic"
 	"shop-backend/models/dto"
 	"shop-backend/utils/check"
@@ -19,16 +20,17 @@ import (
 // @Param cartProductList body dto.PreSubmitOrder true "预提交订单结构体"
 // @Router /oms/order/presubmit [post]
 func OrderPreSubmitHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	preSubmitOrder := new(dto.PreSubmitOrder)
 	if err := c.ShouldBindJSON(preSubmitOrder); err != nil {
-		zap.L().Error("生成预提交订单，前端传递购物车已勾选商品有误", zap.Error(err))
+		logger.Ctx(ctx).Error("生成预提交订单，前端传递购物车已勾选商品有误", zap.Error(err))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
-	orderVO, err := logic.CreatePreSubmitOrder(preSubmitOrder, c.GetInt64("uid"))
+	orderVO, err := logic.CreatePreSubmitOrder(ctx, preSubmitOrder, c.GetInt64("uid"))
 	if err != nil {
-		zap.L().Error("生成预提交订单失败", zap.Error(err))
-		ResponseError(c, CodeServeBusy)
+		logger.Ctx(ctx).Error("生成预提交订单失败", zap.Error(err))
+		ResponseFromError(c, err)
 		return
 	}
 	ResponseSuccessWithMsg(c, CodeCreatePreSubmitOrderSuccess.Msg(), orderVO)
@@ -43,9 +45,10 @@ func OrderPreSubmitHandler(c *gin.Context) {
 // @Param order body dto.Order true "提交订单结构体"
 // @Router /oms/order/submit [post]
 func OrderSubmitHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	order := new(dto.Order)
 	if err := c.ShouldBindJSON(order); err != nil {
-		zap.L().Error("提交订单接口，用户传递参数错误")
+		logger.Ctx(ctx).Error("提交订单接口，用户传递参数错误")
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
@@ -58,7 +61,7 @@ func OrderSubmitHandler(c *gin.Context) {
 		return
 	}
 	// 判断用户传递的订单号是否是后端生成的
-	exist := redis.GetOrderNumber(orderNum)
+	exist := redis.GetOrderNumber(ctx, orderNum)
 	if !exist {
 		// 用户传递的订单号不存在或缓存在Redis中的订单号已过期
 		ResponseError(c, CodeOrderNumISNotExistOrExpired)
@@ -66,9 +69,9 @@ func OrderSubmitHandler(c *gin.Context) {
 	}
 
 	// 否则，用户传递的订单号存在；提交订单幂等性由数据库主键的唯一性保证
-	if err = logic.CreateSubmitOrder(order, c.GetInt64("uid"), orderNum); err != nil {
-		zap.L().Error("提交订单失败", zap.Error(err))
-		ResponseError(c, CodeServeBusy)
+	if err = logic.CreateSubmitOrder(ctx, order, c.GetInt64("uid"), orderNum); err != nil {
+		logger.Ctx(ctx).Error("提交订单失败", zap.Error(err))
+		ResponseFromError(c, err)
 		return
 	}
 	ResponseSuccessWithMsg(c, CodeCreateSubmitOrderSuccess.Msg(), nil)
@@ -82,9 +85,10 @@ func OrderSubmitHandler(c *gin.Context) {
 // @param Authorization header string true "Bearer AToken&RToken"
 // @Router /oms/order/all [get]
 func OrderGetAllHandler(c *gin.Context) {
-	data, err := logic.GetAllOrder(c.GetInt64("uid"))
+	ctx := c.Request.Context()
+	data, err := logic.GetAllOrder(ctx, c.GetInt64("uid"))
 	if err != nil {
-		zap.L().Error("获取用户所有的订单失败", zap.Error(err))
+		logger.Ctx(ctx).Error("获取用户所有的订单失败", zap.Error(err))
 		ResponseError(c, CodeServeBusy)
 		return
 	}
@@ -101,18 +105,19 @@ func OrderGetAllHandler(c *gin.Context) {
 // @Param num path string true "订单号"
 // @Router /oms/order/one/{num} [get]
 func OrderGetOneOrderItemHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	idStr := c.Param("num")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		zap.L().Error("获取一条订单记录的明细接口，idStr不能转换为int64类型", zap.String("idStr", idStr))
+		logger.Ctx(ctx).Error("获取一条订单记录的明细接口，idStr不能转换为int64类型", zap.String("idStr", idStr))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
-	data, err := logic.GetOneOrderItem(id)
+	data, err := logic.GetOneOrderItem(ctx, id)
 	if err != nil {
-		zap.L().Error("获取一条订单记录的明细失败", zap.Error(err))
-		ResponseError(c, CodeServeBusy)
+		logger.Ctx(ctx).Error("获取一条订单记录的明细失败", zap.Error(err))
+		ResponseFromError(c, err)
 		return
 	}
 
@@ -128,18 +133,19 @@ func OrderGetOneOrderItemHandler(c *gin.Context) {
 // @Param num path string true "订单号"
 // @Router /oms/order/del/{num} [delete]
 func OrderDelOrderHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	idStr := c.Param("num")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		zap.L().Error("删除一条订单记录接口，idStr不能转换为int64类型", zap.String("idStr", idStr))
+		logger.Ctx(ctx).Error("删除一条订单记录接口，idStr不能转换为int64类型", zap.String("idStr", idStr))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
-	err = logic.DelOrder(id)
+	err = logic.DelOrder(ctx, id)
 	if err != nil {
-		zap.L().Error("删除一条订单记录失败", zap.Error(err))
-		ResponseError(c, CodeServeBusy)
+		logger.Ctx(ctx).Error("删除一条订单记录失败", zap.Error(err))
+		ResponseFromError(c, err)
 		return
 	}
 
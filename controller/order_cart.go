@@ -3,6 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/logic"
 	"shop-backend/models/dto"
 	"strconv"
@@ -16,7 +17,7 @@ import (
 // @param Authorization header string true "Bearer AToken&RToken"
 // @Router /oms/cart/list [get]
 func OrderCartListHandler(c *gin.Context) {
-	data, err := logic.GetCarProductList(c.GetInt64("uid"))
+	data, err := logic.GetCarProductList(c.Request.Context(), c.GetInt64("uid"))
 	if err != nil {
 		ResponseError(c, CodeServeBusy)
 		return
@@ -32,7 +33,7 @@ func OrderCartListHandler(c *gin.Context) {
 // @param Authorization header string true "Bearer AToken&RToken"
 // @Router /oms/cart/list/count [get]
 func OrderCartListCountHandler(c *gin.Context) {
-	count, err := logic.GetCarProductListCount(c.GetInt64("uid"))
+	count, err := logic.GetCarProductListCount(c.Request.Context(), c.GetInt64("uid"))
 	if err != nil {
 		ResponseError(c, CodeServeBusy)
 		return
@@ -49,23 +50,24 @@ func OrderCartListCountHandler(c *gin.Context) {
 // @Param CartProduct body dto.CartProduct true "购物车商品结构体"
 // @Router /oms/cart/add [post]
 func OrderCartAddHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	cartProduct := new(dto.CartProduct)
 	if err := c.ShouldBindJSON(cartProduct); err != nil {
-		zap.L().Error("添加商品到购物车接口，传递参数有误", zap.Error(err))
+		logger.Ctx(ctx).Error("添加商品到购物车接口，传递参数有误", zap.Error(err))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
 	skuID, err := strconv.ParseInt(cartProduct.SkuID, 10, 64)
 	if err != nil {
-		zap.L().Error("添加商品到购物车接口，转换skuID为int64失败", zap.Error(err), zap.String("skuID", cartProduct.SkuID))
+		logger.Ctx(ctx).Error("添加商品到购物车接口，转换skuID为int64失败", zap.Error(err), zap.String("skuID", cartProduct.SkuID))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
-	if err = logic.AddCartProduct(c.GetInt64("uid"), skuID, cartProduct.Count, cartProduct.Specification); err != nil {
-		// zap.L().Error("添加商品到购物车接口，添加商品到购物车失败", zap.Error(err))
-		ResponseError(c, CodeServeBusy)
+	if err = logic.AddCartProduct(ctx, c.GetInt64("uid"), skuID, cartProduct.Count, cartProduct.Specification); err != nil {
+		logger.Ctx(ctx).Error("添加商品到购物车接口，添加商品到购物车失败", zap.Error(err))
+		ResponseFromError(c, err)
 		return
 	}
 
@@ -81,22 +83,23 @@ func OrderCartAddHandler(c *gin.Context) {
 // @Param CartProductDel body dto.CartProductDel true "删除时购物车商品结构体"
 // @Router /oms/cart/remove [delete]
 func OrderCartRemoveHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	cartDel := new(dto.CartProductDel)
 	if err := c.ShouldBindJSON(cartDel); err != nil {
-		zap.L().Error("删除购物车商品接口，传递参数有误", zap.Error(err))
+		logger.Ctx(ctx).Error("删除购物车商品接口，传递参数有误", zap.Error(err))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
 	skuID, err := strconv.ParseInt(cartDel.SkuID, 10, 64)
 	if err != nil {
-		zap.L().Error("删除购物车商品接口，skuIDStr不能转换为int64类型", zap.String("skuIDStr", cartDel.Specification))
+		logger.Ctx(ctx).Error("删除购物车商品接口，skuIDStr不能转换为int64类型", zap.String("skuIDStr", cartDel.Specification))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
-	if err = logic.DelCartProduct(c.GetInt64("uid"), skuID, cartDel.Specification); err != nil {
-		zap.L().Error("删除购物车商品接口，删除商品失败", zap.Int64("skuID", skuID), zap.Error(err))
+	if err = logic.DelCartProduct(ctx, c.GetInt64("uid"), skuID, cartDel.Specification); err != nil {
+		logger.Ctx(ctx).Error("删除购物车商品接口，删除商品失败", zap.Int64("skuID", skuID), zap.Error(err))
 		ResponseError(c, CodeDeleteCartProductFailed)
 		return
 	}
@@ -112,28 +115,29 @@ func OrderCartRemoveHandler(c *gin.Context) {
 // @Param CartProduct body dto.CartProductSelected true "购物车商品状态"
 // @Router /oms/cart/product/status [put]
 func OrderCartUpdateSelectedHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	status := new(dto.CartProductSelected)
 	if err := c.ShouldBindJSON(status); err != nil {
-		zap.L().Error("修改购物车中商品勾选状态接口，传递参数有误", zap.Error(err))
+		logger.Ctx(ctx).Error("修改购物车中商品勾选状态接口，传递参数有误", zap.Error(err))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
 	skuID, err := strconv.ParseInt(status.SkuID, 10, 64)
 	if err != nil {
-		zap.L().Error("修改购物车中商品勾选状态接口，转换skuID为int64失败", zap.Error(err), zap.String("skuID", status.SkuID))
+		logger.Ctx(ctx).Error("修改购物车中商品勾选状态接口，转换skuID为int64失败", zap.Error(err), zap.String("skuID", status.SkuID))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
 	selected, err := strconv.Atoi(status.Selected)
 	if err != nil || !(selected == 1 || selected == 2) {
-		zap.L().Error("修改购物车中商品勾选状态接口，转换selected为int8失败或勾选状态值不正确", zap.Error(err), zap.String("count", status.Selected))
+		logger.Ctx(ctx).Error("修改购物车中商品勾选状态接口，转换selected为int8失败或勾选状态值不正确", zap.Error(err), zap.String("count", status.Selected))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
-	if err = logic.UpdateCartProductSelected(c.GetInt64("uid"), skuID, selected, status.Specification); err != nil {
-		zap.L().Error("修改购物车商品状态，修改状态", zap.Int64("skuID", skuID), zap.Int("selected", selected), zap.Error(err))
+	if err = logic.UpdateCartProductSelected(ctx, c.GetInt64("uid"), skuID, selected, status.Specification); err != nil {
+		logger.Ctx(ctx).Error("修改购物车商品状态，修改状态", zap.Int64("skuID", skuID), zap.Int("selected", selected), zap.Error(err))
 		ResponseError(c, CodeUpdateCartProductStatusFailed)
 		return
 	}
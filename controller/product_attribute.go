@@ -3,30 +3,157 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/logic"
+	"shop-backend/models/dto"
 	"strconv"
+	"strings"
 )
 
-// ProductAttributeByCategoryIDHandler 通过二级分类ID获取商品属性
-// @Summary 使用商品二级分类ID获取商品属性
-// @Description 前端以path的形式传递商品二级分类ID，后端返回该商品的所有属性
+// ProductAttributeByCategoryIDHandler 通过分类ID获取商品属性
+// @Summary 使用商品分类ID获取商品属性
+// @Description 前端以path的形式传递商品分类ID，后端返回该分类(包括祖先分类)的所有属性，以及每个属性值下符合当前搜索条件的商品数量。<br> 当前搜索条件以query的形式传递，例如 ?keyword=犬&brandId=1&productAttributeIds=14&productAttributeIds=15
 // @Tags 商品相关接口
 // @Produce  json
-// @Param categoryID path string true "商品二级分类ID"
+// @Param categoryID path string true "商品分类ID"
+// @Param keyword query string false "搜索关键字"
+// @Param brandId query string false "品牌ID"
+// @Param productAttributeIds query []int false "已选中的属性值ID"
 // @Router /pms/product/attribute/bycategory/{categoryID} [get]
 func ProductAttributeByCategoryIDHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	categoryIDStr := c.Param("categoryID")
 	categoryID, err := strconv.ParseInt(categoryIDStr, 10, 64)
 	if err != nil {
-		zap.L().Error("通过二级分类ID获取商品属性接口, 请求参数有误", zap.Error(err))
+		logger.Ctx(ctx).Error("通过分类ID获取商品属性接口, 请求参数有误", zap.Error(err))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
-	attributes, err := logic.GetAllAttribute(categoryID)
+	condition := dto.NewCondition()
+	if err = c.ShouldBindQuery(condition); err != nil {
+		logger.Ctx(ctx).Error("通过分类ID获取商品属性接口, 搜索条件有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	attributes, err := logic.GetAttributeWithCount(ctx, categoryID, condition)
+	if err != nil {
+		logger.Ctx(ctx).Error("通过分类ID获取商品属性失败", zap.Error(err))
+		ResponseError(c, CodeRequestAllAttributeFailed)
+		return
+	}
+	ResponseSuccess(c, attributes)
+}
+
+// AdminAttributeListHandler 获取所有商品属性
+// @Summary 后台获取所有商品属性
+// @Description 管理员获取所有属性名以及属性名下的属性值
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /admin/attribute/list [get]
+func AdminAttributeListHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	attributes, err := logic.GetAdminAttributeTree(ctx)
 	if err != nil {
-		zap.L().Error("通过二级分类ID获取商品属性失败", zap.Error(err))
+		logger.Ctx(ctx).Error("后台获取所有商品属性接口，获取失败", zap.Error(err))
 		ResponseError(c, CodeRequestAllAttributeFailed)
 		return
 	}
 	ResponseSuccess(c, attributes)
 }
+
+// AdminAttributeAddHandler 新增商品属性
+// @Summary 后台新增商品属性名或属性值
+// @Description 管理员新增商品属性，不需要传递主键ID。parentId为0时新增属性名，否则新增该属性名下的属性值
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param attribute body dto.Attribute true "商品属性信息"
+// @Router /admin/attribute/add [post]
+func AdminAttributeAddHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	attribute := new(dto.Attribute)
+	if err := c.ShouldBindJSON(attribute); err != nil || strings.TrimSpace(attribute.Name) == "" {
+		logger.Ctx(ctx).Error("后台新增商品属性接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.AddAttribute(ctx, attribute); err != nil {
+		logger.Ctx(ctx).Error("后台新增商品属性接口，新增失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "添加成功🎴", nil)
+}
+
+// AdminAttributeUpdateHandler 修改商品属性
+// @Summary 后台修改商品属性名或属性值
+// @Description 管理员修改商品属性的类型、名称和排序，需要传递主键ID
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param attribute body dto.Attribute true "商品属性信息"
+// @Router /admin/attribute/update [put]
+func AdminAttributeUpdateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	attribute := new(dto.Attribute)
+	if err := c.ShouldBindJSON(attribute); err != nil || attribute.ID == "" || strings.TrimSpace(attribute.Name) == "" {
+		logger.Ctx(ctx).Error("后台修改商品属性接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.UpdateAttribute(ctx, attribute); err != nil {
+		logger.Ctx(ctx).Error("后台修改商品属性接口，修改失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "修改成功", nil)
+}
+
+// AdminAttributeDelHandler 删除商品属性
+// @Summary 后台删除商品属性名或属性值
+// @Description 管理员删除商品属性。属性名下仍有属性值、属性值仍被商品使用时拒绝删除
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param id path string true "商品属性ID"
+// @Router /admin/attribute/del/{id} [delete]
+func AdminAttributeDelHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Ctx(ctx).Error("后台删除商品属性接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err = logic.DelAttribute(ctx, id); err != nil {
+		logger.Ctx(ctx).Error("后台删除商品属性接口，删除失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "删除成功", nil)
+}
+
+// AdminSpuAttributeAssignHandler 为商品spu分配属性值
+// @Summary 后台为商品spu分配属性值
+// @Description 管理员为商品spu分配属性值，会覆盖spu原有的属性值。属性值所属的属性名必须关联在spu所在分类或其祖先分类上
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param spuAttribute body dto.SpuAttribute true "spu属性值"
+// @Router /admin/spu/attribute [put]
+func AdminSpuAttributeAssignHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	spuAttribute := new(dto.SpuAttribute)
+	if err := c.ShouldBindJSON(spuAttribute); err != nil {
+		logger.Ctx(ctx).Error("后台为商品spu分配属性值接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.AssignSpuAttribute(ctx, spuAttribute.SpuID, spuAttribute.ProductAttributeIds); err != nil {
+		logger.Ctx(ctx).Error("后台为商品spu分配属性值接口，分配失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "分配成功", nil)
+}
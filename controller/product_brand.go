@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/logic"
+	"shop-backend/models/dto"
+	"shop-backend/utils/check"
+	"shop-backend/utils/oss"
+	"strconv"
+	"strings"
+)
+
+// ProductBrandByCategoryIDHandler 通过商品分类ID获取品牌列表
+// @Summary 使用商品分类ID获取品牌列表
+// @Description 前端以path的形式传递商品分类ID，后端返回该分类下所有显示状态的品牌
+// @Tags 商品相关接口
+// @Produce  json
+// @Param categoryID path string true "商品分类ID"
+// @Router /pms/product/brand/bycategory/{categoryID} [get]
+func ProductBrandByCategoryIDHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	categoryID, err := strconv.ParseInt(c.Param("categoryID"), 10, 64)
+	if err != nil {
+		logger.Ctx(ctx).Error("通过分类ID获取品牌列表接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	brands, err := logic.GetBrandByCategoryID(ctx, categoryID)
+	if err != nil {
+		logger.Ctx(ctx).Error("通过分类ID获取品牌列表失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, brands)
+}
+
+// AdminBrandListHandler 获取所有品牌
+// @Summary 后台获取所有品牌
+// @Description 管理员获取所有品牌以及品牌所属的分类ID
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /admin/brand/list [get]
+func AdminBrandListHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	brands, err := logic.GetAllBrand(ctx)
+	if err != nil {
+		logger.Ctx(ctx).Error("后台获取所有品牌接口，获取失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, brands)
+}
+
+// AdminBrandAddHandler 新增品牌
+// @Summary 后台新增品牌
+// @Description 管理员新增品牌，不需要传递主键ID。Logo需要先调用上传品牌Logo接口获取URL
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param brand body dto.Brand true "品牌信息"
+// @Router /admin/brand/add [post]
+func AdminBrandAddHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	brand := new(dto.Brand)
+	if err := c.ShouldBindJSON(brand); err != nil || strings.TrimSpace(brand.Name) == "" {
+		logger.Ctx(ctx).Error("后台新增品牌接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.AddBrand(ctx, brand); err != nil {
+		logger.Ctx(ctx).Error("后台新增品牌接口，新增失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccessWithMsg(c, "添加成功🎴", nil)
+}
+
+// AdminBrandUpdateHandler 修改品牌
+// @Summary 后台修改品牌
+// @Description 管理员修改品牌信息以及品牌所属的分类，需要传递主键ID
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param brand body dto.Brand true "品牌信息"
+// @Router /admin/brand/update [put]
+func AdminBrandUpdateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	brand := new(dto.Brand)
+	if err := c.ShouldBindJSON(brand); err != nil || brand.ID == "" || strings.TrimSpace(brand.Name) == "" {
+		logger.Ctx(ctx).Error("后台修改品牌接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.UpdateBrand(ctx, brand); err != nil {
+		logger.Ctx(ctx).Error("后台修改品牌接口，修改失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "修改成功", nil)
+}
+
+// AdminBrandDelHandler 删除品牌
+// @Summary 后台删除品牌
+// @Description 管理员删除品牌，如果品牌下仍有商品则拒绝删除
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param id path string true "品牌ID"
+// @Router /admin/brand/del/{id} [delete]
+func AdminBrandDelHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Ctx(ctx).Error("后台删除品牌接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err = logic.DelBrand(ctx, id); err != nil {
+		logger.Ctx(ctx).Error("后台删除品牌接口，删除失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "删除成功", nil)
+}
+
+// AdminBrandLogoUploadHandler 上传品牌Logo
+// @Summary 后台上传品牌Logo
+// @Description 管理员上传品牌Logo到阿里云OSS，返回Logo的URL，用于新增/修改品牌
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /admin/brand/logo [post]
+func AdminBrandLogoUploadHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		logger.Ctx(ctx).Error("上传品牌Logo接口，读取上传图片失败", zap.Error(err))
+		ResponseError(c, CodeUploadBrandLogoFailed)
+		return
+	}
+	// 检查图片格式
+	if err = check.CheckPic(fileHeader); err != nil {
+		logger.Ctx(ctx).Error("上传品牌Logo接口，上传图片格式、大小有误", zap.Error(err))
+		ResponseError(c, CodeUploadAvatarToBigOrExtError)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Ctx(ctx).Error("上传品牌Logo接口，打开图片失败", zap.Error(err))
+		ResponseError(c, CodeUploadBrandLogoFailed)
+		return
+	}
+	defer file.Close()
+
+	// 上传Logo到阿里云OSS
+	path, err := oss.UploadBrandLogo(file)
+	if err != nil || path == "" {
+		logger.Ctx(ctx).Error("上传品牌Logo接口，上传图片到阿里云OSS失败", zap.Error(err))
+		ResponseError(c, CodeUploadBrandLogoFailed)
+		return
+	}
+	ResponseSuccess(c, gin.H{"logo": path})
+}
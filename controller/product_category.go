@@ -3,20 +3,170 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/logic"
+	"shop-backend/models/dto"
+	"strconv"
+	"strings"
 )
 
 // ProductCategoryListHandler 获取商品分类
 // @Summary 获取所有商品分类信息
-// @Description 返回商品一级分类、二级分类列表
+// @Description 返回任意层级的商品分类树，子分类位于children中
 // @Tags 商品相关接口
 // @Produce  json
 // @Router /pms/product/category/list [get]
 func ProductCategoryListHandler(c *gin.Context) {
-	categories, err := logic.GetAllCategory()
+	ctx := c.Request.Context()
+	categories, err := logic.GetAllCategory(ctx)
 	if err != nil {
-		zap.L().Error("获取商品分类信息失败", zap.Error(err))
+		logger.Ctx(ctx).Error("获取商品分类信息失败", zap.Error(err))
 		ResponseError(c, CodeRequestAllCategoryFailed)
+		return
 	}
 	ResponseSuccess(c, categories)
 }
+
+// AdminCategoryAddHandler 新增商品分类
+// @Summary 后台新增商品分类
+// @Description 管理员新增商品分类以及分类对应的属性，不需要传递主键ID。新增成功后会重建商品分类缓存
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param category body dto.Category true "商品分类信息"
+// @Router /admin/category/add [post]
+func AdminCategoryAddHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	category := new(dto.Category)
+	if err := c.ShouldBindJSON(category); err != nil || strings.TrimSpace(category.Name) == "" || category.ShowStatus > 1 {
+		logger.Ctx(ctx).Error("后台新增商品分类接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.AddCategory(ctx, category); err != nil {
+		logger.Ctx(ctx).Error("后台新增商品分类接口，新增失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "添加成功🎴", nil)
+}
+
+// AdminCategoryUpdateHandler 修改商品分类
+// @Summary 后台修改商品分类
+// @Description 管理员修改商品分类的名称、简称、图标以及分类对应的属性，需要传递主键ID
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param category body dto.Category true "商品分类信息"
+// @Router /admin/category/update [put]
+func AdminCategoryUpdateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	category := new(dto.Category)
+	if err := c.ShouldBindJSON(category); err != nil || category.ID == "" || strings.TrimSpace(category.Name) == "" {
+		logger.Ctx(ctx).Error("后台修改商品分类接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.UpdateCategory(ctx, category); err != nil {
+		logger.Ctx(ctx).Error("后台修改商品分类接口，修改失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "修改成功", nil)
+}
+
+// AdminCategoryMoveHandler 移动商品分类
+// @Summary 后台移动商品分类
+// @Description 管理员将商品分类移动到新的父分类下。父分类ID为0时移动为一级分类
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param move body dto.CategoryMove true "移动商品分类参数"
+// @Router /admin/category/move [put]
+func AdminCategoryMoveHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	move := new(dto.CategoryMove)
+	if err := c.ShouldBindJSON(move); err != nil {
+		logger.Ctx(ctx).Error("后台移动商品分类接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.MoveCategory(ctx, move.ID, move.ParentID); err != nil {
+		logger.Ctx(ctx).Error("后台移动商品分类接口，移动失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "移动成功", nil)
+}
+
+// AdminCategorySortHandler 修改商品分类排序
+// @Summary 后台修改商品分类排序
+// @Description 管理员修改商品分类的排序，越小越靠前
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param sort body dto.CategorySort true "商品分类排序参数"
+// @Router /admin/category/sort [put]
+func AdminCategorySortHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	sort := new(dto.CategorySort)
+	if err := c.ShouldBindJSON(sort); err != nil {
+		logger.Ctx(ctx).Error("后台修改商品分类排序接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.SortCategory(ctx, sort.ID, sort.Sort); err != nil {
+		logger.Ctx(ctx).Error("后台修改商品分类排序接口，修改失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "修改成功", nil)
+}
+
+// AdminCategoryShowStatusHandler 显示/隐藏商品分类
+// @Summary 后台显示/隐藏商品分类
+// @Description 管理员修改商品分类的显示状态：0->不显示；1->显示
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param status body dto.CategoryShowStatus true "商品分类显示状态参数"
+// @Router /admin/category/status [put]
+func AdminCategoryShowStatusHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	status := new(dto.CategoryShowStatus)
+	if err := c.ShouldBindJSON(status); err != nil || status.ShowStatus > 1 {
+		logger.Ctx(ctx).Error("后台修改商品分类显示状态接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.UpdateCategoryShowStatus(ctx, status.ID, status.ShowStatus); err != nil {
+		logger.Ctx(ctx).Error("后台修改商品分类显示状态接口，修改失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "修改成功", nil)
+}
+
+// AdminCategoryDelHandler 删除商品分类
+// @Summary 后台删除商品分类
+// @Description 管理员删除商品分类，如果分类下仍有子分类或商品则拒绝删除
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param id path string true "商品分类ID"
+// @Router /admin/category/del/{id} [delete]
+func AdminCategoryDelHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Ctx(ctx).Error("后台删除商品分类接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err = logic.DelCategory(ctx, id); err != nil {
+		logger.Ctx(ctx).Error("后台删除商品分类接口，删除失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "删除成功", nil)
+}
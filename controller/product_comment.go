@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/logic"
+	"shop-backend/models/dto"
+	"shop-backend/utils/check"
+	"shop-backend/utils/oss"
+	"strconv"
+)
+
+// ProductCommentListHandler 分页获取商品评价列表
+// @Summary 商品评价列表接口
+// @Description 前端以path的形式传递spuID，以query的形式传递筛选条件。withPic为true时只返回有图评价；sort: 1->最新(默认)；2->最有用。如未指定分页字段，则默认返回第1页的前10条数据
+// @Tags 商品相关接口
+// @Produce  json
+// @Param spuID path string true "商品spuID"
+// @Param withPic query bool false "是否只看有图评价"
+// @Param sort query string false "排序：1->最新；2->最有用"
+// @Param pageNo query string false "页码"
+// @Param pageSize query string false "页长"
+// @Router /pms/comment/list/{spuID} [get]
+func ProductCommentListHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	spuID, err := strconv.ParseInt(c.Param("spuID"), 10, 64)
+	if err != nil {
+		logger.Ctx(ctx).Error("商品评价列表接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	condition := new(dto.CommentCondition)
+	if err = c.ShouldBindQuery(condition); err != nil {
+		logger.Ctx(ctx).Error("商品评价列表接口，筛选条件有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	data, err := logic.GetCommentList(ctx, spuID, condition)
+	if err != nil {
+		logger.Ctx(ctx).Error("商品评价列表接口，获取评价列表失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// ProductCommentAddHandler 发表商品评价
+// @Summary 发表商品评价接口
+// @Description 用户评价已完成订单中的商品，每个订单明细只能评价一次。评分为1~5星，图片最多9张，需要先调用上传评价图片接口获取URL
+// @Tags 商品相关接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param comment body dto.Comment true "评价信息"
+// @Router /pms/comment/add [post]
+func ProductCommentAddHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	comment := new(dto.Comment)
+	if err := c.ShouldBindJSON(comment); err != nil {
+		logger.Ctx(ctx).Error("发表商品评价接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.AddComment(ctx, c.GetInt64("uid"), comment); err != nil {
+		logger.Ctx(ctx).Error("发表商品评价接口，发表失败", zap.Int64("uid", c.GetInt64("uid")), zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "评价成功🎴", nil)
+}
+
+// ProductCommentAppendHandler 追加商品评价
+// @Summary 追加商品评价接口
+// @Description 用户追加自己的评价
+// @Tags 商品相关接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param append body dto.CommentAppend true "追评信息"
+// @Router /pms/comment/append [post]
+func ProductCommentAppendHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	commentAppend := new(dto.CommentAppend)
+	if err := c.ShouldBindJSON(commentAppend); err != nil {
+		logger.Ctx(ctx).Error("追加商品评价接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.AppendComment(ctx, c.GetInt64("uid"), commentAppend); err != nil {
+		logger.Ctx(ctx).Error("追加商品评价接口，追加失败", zap.Int64("uid", c.GetInt64("uid")), zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "追评成功🎴", nil)
+}
+
+// ProductCommentHelpfulHandler 评价有用投票
+// @Summary 评价有用投票接口
+// @Description 用户认为评价有用，每个用户对同一条评价只能投一次
+// @Tags 商品相关接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param id path string true "评价ID"
+// @Router /pms/comment/helpful/{id} [post]
+func ProductCommentHelpfulHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	commentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Ctx(ctx).Error("评价有用投票接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err = logic.VoteCommentHelpful(ctx, c.GetInt64("uid"), commentID); err != nil {
+		logger.Ctx(ctx).Error("评价有用投票接口，投票失败", zap.Int64("uid", c.GetInt64("uid")), zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ProductCommentPicUploadHandler 上传评价图片
+// @Summary 上传评价图片接口
+// @Description 用户上传评价图片，返回图片URL，用于发表评价、追加评价
+// @Tags 商品相关接口
+// @Accept multipart/form-data
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param file formData file true "评价图片"
+// @Router /pms/comment/pic [post]
+func ProductCommentPicUploadHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		logger.Ctx(ctx).Error("上传评价图片接口，读取上传图片失败", zap.Error(err))
+		ResponseError(c, CodeUploadCommentPicFailed)
+		return
+	}
+	// 检查图片格式
+	if err = check.CheckPic(fileHeader); err != nil {
+		logger.Ctx(ctx).Error("上传评价图片接口，上传图片格式、大小有误", zap.Error(err))
+		ResponseError(c, CodeUploadAvatarToBigOrExtError)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Ctx(ctx).Error("上传评价图片接口，打开图片失败", zap.Error(err))
+		ResponseError(c, CodeUploadCommentPicFailed)
+		return
+	}
+	defer file.Close()
+
+	// 上传图片到阿里云OSS
+	path, err := oss.UploadCommentPic(file)
+	if err != nil || path == "" {
+		logger.Ctx(ctx).Error("上传评价图片接口，上传图片到阿里云OSS失败", zap.Error(err))
+		ResponseError(c, CodeUploadCommentPicFailed)
+		return
+	}
+	ResponseSuccess(c, gin.H{"pic": path})
+}
+
+// AdminCommentReplyHandler 商家回复评价
+// @Summary 后台回复商品评价
+// @Description 商家回复商品评价，重复回复会覆盖之前的回复
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param reply body dto.CommentReply true "回复信息"
+// @Router /admin/comment/reply [put]
+func AdminCommentReplyHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	reply := new(dto.CommentReply)
+	if err := c.ShouldBindJSON(reply); err != nil {
+		logger.Ctx(ctx).Error("后台回复商品评价接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.ReplyComment(ctx, reply); err != nil {
+		logger.Ctx(ctx).Error("后台回复商品评价接口，回复失败", zap.Error(err))
+		ResponseFromError(c, err)
+		return
+	}
+	ResponseSuccessWithMsg(c, "回复成功🎴", nil)
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/logic"
 	"strconv"
 )
@@ -12,22 +13,26 @@ import (
 // @Description 前端以path的形式传递spuID，后端返回该商品详情信息
 // @Tags 商品相关接口
 // @Produce  json
+// @param Authorization header string false "Bearer AToken&RToken，携带时记录浏览历史"
 // @Param skuID path string true "skuID:1000002"
 // @Router /pms/product/detail/{skuID} [get]
 func ProductDetailHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	skuIDStr := c.Param("skuID")
 	skuID, err := strconv.ParseInt(skuIDStr, 10, 64)
 	if err != nil {
-		zap.L().Error("商品详情接口，skuIDStr不能转换为int64类型", zap.String("skuIDStr", skuIDStr))
+		logger.Ctx(ctx).Error("商品详情接口，skuIDStr不能转换为int64类型", zap.String("skuIDStr", skuIDStr))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
-	// 多协程获取商品详情信息
-	data, err := logic.GetProductDetailWithConcurrent(skuID)
+	// 优先从缓存获取商品详情信息，未命中时多协程查询数据库
+	data, err := logic.GetProductDetailWithCache(ctx, skuID)
 	if err != nil {
-		zap.L().Error("商品详情接口，获取商品详情信息失败", zap.Error(err))
-		ResponseError(c, CodeServeBusy)
+		logger.Ctx(ctx).Error("商品详情接口，获取商品详情信息失败", zap.Error(err))
+		ResponseFromError(c, err)
 		return
 	}
+	// 登录用户记录浏览历史
+	logic.RecordBrowseHistory(ctx, c.GetInt64("uid"), skuID)
 	ResponseSuccess(c, data)
 }
@@ -4,37 +4,45 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"reflect"
+	"shop-backend/logger"
 	"shop-backend/logic"
 	"shop-backend/models/dto"
 )
 
 // ProductSearchHandler 支持多条件的商品搜索接口
 // @Summary 商品搜索接口
-// @Description 支持多条件的商品搜索接口。如未指定分页字段，则默认返回第1页的前20条数据 <br> case1: 主页搜索框搜索功能 <br> {"keyword": "犬", "pageNo": "1", "pageSize": "10"} <br> <br> case2: 使用二级分类ID搜索商品 <br> {"productCategoryId": "14", "pageNo": "1", "pageSize": "10"} <br> <br> case3: 使用商品属性列表搜索商品 <br> {"productAttributeIds": [14,15,16], "pageNo": "1", "pageSize": "10"} <br> <br>
+// @Description 支持多条件的商品搜索接口。如未指定分页字段，则默认返回第1页的前20条数据 <br> case1: 主页搜索框搜索功能 <br> {"keyword": "犬", "pageNo": "1", "pageSize": "10"} <br> <br> case2: 使用二级分类ID搜索商品 <br> {"productCategoryId": "14", "pageNo": "1", "pageSize": "10"} <br> <br> case3: 使用商品属性列表搜索商品 <br> {"productAttributeIds": [14,15,16], "pageNo": "1", "pageSize": "10"} <br> <br> case4: 多品牌、价格区间、只看有货并按价格升序 <br> {"keyword": "猫粮", "brandIds": [1,2], "minPrice": "10", "maxPrice": "200", "inStock": true, "sort": "3"} <br> <br> 返回结果的facets字段包含品牌、二级分类以及属性值的聚合数量 <br> <br> case5: 游标分页(适用于移动端无限滚动)，传入上一页返回的nextCursor <br> {"keyword": "猫粮", "pageSize": "10", "cursor": "MjAyMy0wMS0wMSAxMjowMDowMHwxMjM"} <br>
 // @Tags 商品相关接口
 // @Produce  json
+// @param Authorization header string false "Bearer AToken&RToken，携带时记录搜索历史"
 // @Param searchCondition body dto.SearchCondition true "搜索条件"
 // @Router /pms/product/search [post]
 func ProductSearchHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	condition := dto.NewCondition()
 	nilCondition := dto.NewCondition()
 	err := c.ShouldBindJSON(condition)
 	if err != nil {
-		zap.L().Error("商品搜索接口，前端传递的条件有误", zap.Error(err))
+		logger.Ctx(ctx).Error("商品搜索接口，前端传递的条件有误", zap.Error(err))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
 	if reflect.DeepEqual(condition, nilCondition) {
 		// 如果condition全部条件为空，不应返回所有数据(防止用户获取sku表所有数据)
-		zap.L().Error("商品搜索接口，前端传递的条件全部为空", zap.Error(err))
+		logger.Ctx(ctx).Error("商品搜索接口，前端传递的条件全部为空", zap.Error(err))
 		ResponseError(c, CodeSearchConditionIsNil)
 		return
 	}
 	// 调用logic层根据条件查询商品
-	data, err := logic.Search(condition)
+	data, err := logic.Search(ctx, condition)
+	if err == nil && condition.Cursor == "" && condition.PageNo == "1" {
+		// 只在搜索第一页时记录关键字热度和用户搜索历史，翻页不重复计算
+		logic.RecordSearchKeyword(ctx, condition.Keyword)
+		logic.RecordSearchHistory(ctx, c.GetInt64("uid"), condition.Keyword)
+	}
 	if err != nil {
-		zap.L().Error("商品搜索logic层错误", zap.Error(err))
-		ResponseError(c, CodeServeBusy)
+		logger.Ctx(ctx).Error("商品搜索logic层错误", zap.Error(err))
+		ResponseFromError(c, err)
 		return
 	}
 	ResponseSuccess(c, data)
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/logic"
+	"shop-backend/models/dto"
+	"strings"
+)
+
+// ProductSearchSuggestHandler 搜索补全接口
+// @Summary 搜索补全接口
+// @Description 根据用户在搜索框中输入的前缀返回补全建议，来源为商品标题、品牌名称、分类名称。支持中文、全拼以及拼音首字母前缀，例如 ?q=猫、?q=mao、?q=ml
+// @Tags 商品相关接口
+// @Produce  json
+// @Param q query string true "用户输入的前缀"
+// @Router /pms/product/search/suggest [get]
+func ProductSearchSuggestHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		ResponseSuccess(c, make([]string, 0))
+		return
+	}
+	suggestions, err := logic.GetSearchSuggestions(ctx, q)
+	if err != nil {
+		logger.Ctx(ctx).Error("搜索补全接口，获取补全建议失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, suggestions)
+}
+
+// ProductSearchHotHandler 热搜词接口
+// @Summary 热搜词接口
+// @Description 返回按照热度排序的热搜词，热度随时间衰减，近期搜索的关键字排名更靠前
+// @Tags 商品相关接口
+// @Produce  json
+// @Router /pms/product/search/hot [get]
+func ProductSearchHotHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	keywords, err := logic.GetHotKeywords(ctx)
+	if err != nil {
+		logger.Ctx(ctx).Error("热搜词接口，获取热搜词失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, keywords)
+}
+
+// AdminSearchBlockedListHandler 获取所有被屏蔽的搜索关键字
+// @Summary 后台获取被屏蔽的搜索关键字
+// @Description 管理员获取所有被屏蔽的搜索关键字
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /admin/search/blocked/list [get]
+func AdminSearchBlockedListHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	keywords, err := logic.GetBlockedKeywords(ctx)
+	if err != nil {
+		logger.Ctx(ctx).Error("后台获取被屏蔽的搜索关键字接口，获取失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, keywords)
+}
+
+// AdminSearchBlockedAddHandler 屏蔽搜索关键字
+// @Summary 后台屏蔽搜索关键字
+// @Description 管理员屏蔽搜索关键字，包含该关键字的热搜词、搜索补全都不会再展示
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param keyword body dto.SearchKeyword true "搜索关键字"
+// @Router /admin/search/blocked/add [post]
+func AdminSearchBlockedAddHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	keyword := new(dto.SearchKeyword)
+	if err := c.ShouldBindJSON(keyword); err != nil || strings.TrimSpace(keyword.Keyword) == "" {
+		logger.Ctx(ctx).Error("后台屏蔽搜索关键字接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.BlockKeyword(ctx, keyword.Keyword); err != nil {
+		logger.Ctx(ctx).Error("后台屏蔽搜索关键字接口，屏蔽失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccessWithMsg(c, "屏蔽成功🎴", nil)
+}
+
+// AdminSearchBlockedDelHandler 取消屏蔽搜索关键字
+// @Summary 后台取消屏蔽搜索关键字
+// @Description 管理员取消屏蔽搜索关键字，以query的形式传递关键字
+// @Tags 后台管理接口
+// @Produce  json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Param keyword query string true "搜索关键字"
+// @Router /admin/search/blocked/del [delete]
+func AdminSearchBlockedDelHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	keyword := new(dto.SearchKeyword)
+	if err := c.ShouldBindQuery(keyword); err != nil || strings.TrimSpace(keyword.Keyword) == "" {
+		logger.Ctx(ctx).Error("后台取消屏蔽搜索关键字接口，请求参数有误", zap.Error(err))
+		ResponseError(c, CodeInvalidParams)
+		return
+	}
+	if err := logic.UnblockKeyword(ctx, keyword.Keyword); err != nil {
+		logger.Ctx(ctx).Error("后台取消屏蔽搜索关键字接口，取消屏蔽失败", zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccessWithMsg(c, "取消屏蔽成功🎴", nil)
+}
@@ -3,28 +3,24 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"shop-backend/logger"
 )
 
-// ResponseData 返回前端的响应体
+// ResponseData 返回前端的响应体，请求失败时带有请求ID，用于根据请求ID查找日志
 type ResponseData struct {
-	Code ResCode     `json:"code"`
-	Msg  interface{} `json:"msg"`
-	Data interface{} `json:"data"`
+	Code      ResCode     `json:"code"`
+	Msg       interface{} `json:"msg"`
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"requestId,omitempty"`
 }
 
+// ResponseError 返回错误码，HTTP状态码由错误码决定
 func ResponseError(c *gin.Context, code ResCode) {
-	c.JSON(http.StatusOK, &ResponseData{
-		Code: code,
-		Msg:  code.Msg(),
-		Data: nil,
-	})
-}
-
-func ResponseBadError(c *gin.Context, code ResCode) {
-	c.JSON(http.StatusInternalServerError, &ResponseData{
-		Code: code,
-		Msg:  code.Msg(),
-		Data: nil,
+	c.JSON(code.Status(), &ResponseData{
+		Code:      code,
+		Msg:       code.Msg(),
+		Data:      nil,
+		RequestID: logger.RequestID(c.Request.Context()),
 	})
 }
 
@@ -37,10 +33,11 @@ func ResponseSuccess(c *gin.Context, data interface{}) {
 }
 
 func ResponseErrorWithMsg(c *gin.Context, code ResCode, msg interface{}) {
-	c.JSON(http.StatusOK, &ResponseData{
-		Code: code,
-		Msg:  msg,
-		Data: nil,
+	c.JSON(code.Status(), &ResponseData{
+		Code:      code,
+		Msg:       msg,
+		Data:      nil,
+		RequestID: logger.RequestID(c.Request.Context()),
 	})
 }
 
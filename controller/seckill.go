@@ -2,7 +2,7 @@ package controller
 
 import (
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/logic"
 	"shop-backend/models/dto"
 	"shop-backend/rabbitmq"
@@ -16,9 +16,9 @@ import (
 // @Produce json
 // @Router /seckill/sku/list [get]
 func SecKillAllSkuHandler(c *gin.Context) {
-	data, err := logic.GetAllSecKillSku()
+	data, err := logic.GetAllSecKillSku(c.Request.Context())
 	if err != nil {
-		ResponseBadError(c, CodeServeBusy)
+		ResponseError(c, CodeServeBusy)
 		return
 	}
 	ResponseSuccess(c, data)
@@ -26,28 +26,29 @@ func SecKillAllSkuHandler(c *gin.Context) {
 
 // SecKillBuyHandler 秒杀商品接口
 func SecKillBuyHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	product := new(dto.SecKillProduct)
 	if err := c.ShouldBindJSON(product); err != nil {
-		zap.L().Error("秒杀商品接口，传递参数错误")
-		ResponseBadError(c, CodeServeBusy)
+		logger.Ctx(ctx).Error("秒杀商品接口，传递参数错误")
+		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
 	skuID, err := strconv.ParseInt(product.SkuID, 10, 64)
 	if err != nil {
-		zap.L().Error("秒杀商品接口，商品skuID转为int64错误")
-		ResponseError(c, CodeServeBusy)
+		logger.Ctx(ctx).Error("秒杀商品接口，商品skuID转为int64错误")
+		ResponseError(c, CodeInvalidParams)
 		return
 	}
 
 	// 发布到MQ中
-	err = rabbitmq.SendSecKillReqMess2MQ(&dto.SecKillMQ{
+	err = rabbitmq.SendSecKillReqMess2MQ(ctx, &dto.SecKillMQ{
 		SkuID: skuID,
 		UID:   c.GetInt64("uid"),
 	})
 	if err != nil {
-		// 发布到MQ失败
-		ResponseBadError(c, CodeSecKillFinished)
+		// 秒杀队列已满时返回秒杀已结束，RabbitMQ不可用时返回服务未就绪
+		ResponseFromError(c, err)
 		return
 	}
 
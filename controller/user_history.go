@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/logic"
+)
+
+// UserSearchHistoryListHandler 获取用户搜索历史
+// @Summary 获取用户搜索历史
+// @Description 后端返回用户最近的搜索关键字，已去重，最近搜索的在前
+// @Tags 用户相关接口
+// @Produce json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /user/history/search/list [get]
+func UserSearchHistoryListHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	keywords, err := logic.GetSearchHistory(ctx, c.GetInt64("uid"))
+	if err != nil {
+		logger.Ctx(ctx).Error("获取用户搜索历史失败", zap.Int64("uid", c.GetInt64("uid")), zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, keywords)
+}
+
+// UserSearchHistoryClearHandler 清空用户搜索历史
+// @Summary 清空用户搜索历史
+// @Description 清空用户所有的搜索历史
+// @Tags 用户相关接口
+// @Produce json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /user/history/search/clear [delete]
+func UserSearchHistoryClearHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := logic.ClearSearchHistory(ctx, c.GetInt64("uid")); err != nil {
+		logger.Ctx(ctx).Error("清空用户搜索历史失败", zap.Int64("uid", c.GetInt64("uid")), zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UserBrowseHistoryListHandler 获取用户浏览历史
+// @Summary 获取用户浏览历史
+// @Description 后端返回用户最近浏览过的商品，已去重，最近浏览的在前
+// @Tags 用户相关接口
+// @Produce json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /user/history/browse/list [get]
+func UserBrowseHistoryListHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	products, err := logic.GetBrowseHistory(ctx, c.GetInt64("uid"))
+	if err != nil {
+		logger.Ctx(ctx).Error("获取用户浏览历史失败", zap.Int64("uid", c.GetInt64("uid")), zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, products)
+}
+
+// UserBrowseHistoryClearHandler 清空用户浏览历史
+// @Summary 清空用户浏览历史
+// @Description 清空用户所有的浏览历史
+// @Tags 用户相关接口
+// @Produce json
+// @param Authorization header string true "Bearer AToken&RToken"
+// @Router /user/history/browse/clear [delete]
+func UserBrowseHistoryClearHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := logic.ClearBrowseHistory(ctx, c.GetInt64("uid")); err != nil {
+		logger.Ctx(ctx).Error("清空用户浏览历史失败", zap.Int64("uid", c.GetInt64("uid")), zap.Error(err))
+		ResponseError(c, CodeServeBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
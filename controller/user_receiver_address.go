@@ -3,6 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/logic"
 	"shop-backend/models/dto"
 	"shop-backend/utils/check"
@@ -17,9 +18,10 @@ import (
 // @param Authorization header string true "Bearer AToken&RToken"
 // @Router /user/receiveraddress/list [get]
 func UserReceiverAddressListHandler(c *gin.Context) {
-	pcdList, err := logic.GetAllAddress()
+	ctx := c.Request.Context()
+	pcdList, err := logic.GetAllAddress(ctx)
 	if err != nil {
-		zap.L().Error("获取所有收货地址失败", zap.Error(err))
+		logger.Ctx(ctx).Error("获取所有收货地址失败", zap.Error(err))
 		ResponseError(c, CodeServeBusy)
 		return
 	}
@@ -35,9 +37,10 @@ func UserReceiverAddressListHandler(c *gin.Context) {
 // @Param cartProductList body dto.ReceiverAddress true "用户收货地址信息结构体"
 // @Router /user/receiveraddress/add [post]
 func UserReceiverAddressAddHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	address := new(dto.ReceiverAddress)
 	if err := c.ShouldBindJSON(address); err != nil {
-		zap.L().Error("新增用户收货地址接口，传递参数有误", zap.Error(err))
+		logger.Ctx(ctx).Error("新增用户收货地址接口，传递参数有误", zap.Error(err))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
@@ -49,9 +52,9 @@ func UserReceiverAddressAddHandler(c *gin.Context) {
 		return
 	}
 
-	err := logic.AddReceiverAddress(address, c.GetInt64("uid"))
+	err := logic.AddReceiverAddress(ctx, address, c.GetInt64("uid"))
 	if err != nil {
-		zap.L().Error("新增用户收货地址接口，添加失败", zap.Error(err))
+		logger.Ctx(ctx).Error("新增用户收货地址接口，添加失败", zap.Error(err))
 		ResponseError(c, CodeAddReceiverAddressFailed)
 		return
 	}
@@ -67,9 +70,10 @@ func UserReceiverAddressAddHandler(c *gin.Context) {
 // @Param cartProductList body dto.ReceiverAddress true "用户收货地址信息结构体"
 // @Router /user/receiveraddress/update [put]
 func UserReceiverAddressUpdateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	address := new(dto.ReceiverAddress)
 	if err := c.ShouldBindJSON(address); err != nil {
-		zap.L().Error("修改用户收货地址接口，传递参数有误", zap.Error(err))
+		logger.Ctx(ctx).Error("修改用户收货地址接口，传递参数有误", zap.Error(err))
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
@@ -81,9 +85,9 @@ func UserReceiverAddressUpdateHandler(c *gin.Context) {
 		return
 	}
 
-	err := logic.UpdateReceiverAddress(address, c.GetInt64("uid"))
+	err := logic.UpdateReceiverAddress(ctx, address, c.GetInt64("uid"))
 	if err != nil {
-		zap.L().Error("修改用户收货地址接口，修改失败", zap.Error(err))
+		logger.Ctx(ctx).Error("修改用户收货地址接口，修改失败", zap.Error(err))
 		ResponseError(c, CodeUpdateReceiverAddressFailed)
 		return
 	}
@@ -99,15 +103,16 @@ func UserReceiverAddressUpdateHandler(c *gin.Context) {
 // @Param id path string true "收货地址信息主键ID"
 // @Router /user/receiveraddress/delete/{id} [delete]
 func UserReceiverAddressDeleteHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if idStr == "" || err != nil {
-		zap.L().Error("删除用户收货地址接口，传递参数有误")
+		logger.Ctx(ctx).Error("删除用户收货地址接口，传递参数有误")
 		ResponseError(c, CodeInvalidParams)
 		return
 	}
-	if err = logic.DelReceiverAddress(id, c.GetInt64("uid")); err != nil {
-		zap.L().Error("删除用户收货地址接口，删除失败", zap.Error(err))
+	if err = logic.DelReceiverAddress(ctx, id, c.GetInt64("uid")); err != nil {
+		logger.Ctx(ctx).Error("删除用户收货地址接口，删除失败", zap.Error(err))
 		ResponseError(c, CodeServeBusy)
 		return
 	}
@@ -122,9 +127,10 @@ func UserReceiverAddressDeleteHandler(c *gin.Context) {
 // @param Authorization header string true "Bearer AToken&RToken"
 // @Router /user/receiveraddress/my [get]
 func UserReceiverAddressPersonHandler(c *gin.Context) {
-	data, err := logic.GetPersonAllAddress(c.GetInt64("uid"))
+	ctx := c.Request.Context()
+	data, err := logic.GetPersonAllAddress(ctx, c.GetInt64("uid"))
 	if err != nil {
-		zap.L().Error("获取用户所有的收货地址失败", zap.Error(err))
+		logger.Ctx(ctx).Error("获取用户所有的收货地址失败", zap.Error(err))
 		ResponseError(c, CodeServeBusy)
 		return
 	}
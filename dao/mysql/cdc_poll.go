@@ -0,0 +1,235 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"shop-backend/logger"
+	"shop-backend/models/pojo"
+	"strconv"
+	"time"
+)
+
+// CDCTimeLayout 与canal解析binlog得到的时间格式保持一致
+const CDCTimeLayout = "2006-01-02 15:04:05"
+
+// 删除前查询到的被删除行，保存在gorm.Statement中
+const tombstoneRowsKey = "cdc:tombstone:rows"
+
+// SelectMaxUpdatedTime 查询表中最大的updated_time，表为空时返回零值
+func SelectMaxUpdatedTime(ctx context.Context, table string) (time.Time, error) {
+	var maxTime sql.NullTime
+	if err := db.WithContext(ctx).Table(table).Select("MAX(updated_time)").Scan(&maxTime).Error; err != nil {
+		logger.Ctx(ctx).Error("查询表中最大的updated_time失败", zap.String("table", table), zap.Error(err))
+		return time.Time{}, err
+	}
+	return maxTime.Time, nil
+}
+
+// SelectChangedRows 按照(updated_time, id)升序查询位于(afterTime, afterID)之后的行，每行为 列名->列值 的映射。
+// 使用复合游标分页，同一时间更新的行超过limit时也可以继续向后查询
+func SelectChangedRows(ctx context.Context, table string, afterTime time.Time, afterID int64, limit int) ([]map[string]string, error) {
+	rows, err := db.WithContext(ctx).Table(table).
+		Where("(updated_time, id) > (?, ?)", afterTime, afterID).
+		Order("updated_time asc, id asc").
+		Limit(limit).
+		Rows()
+	if err != nil {
+		logger.Ctx(ctx).Error("轮询表中发生变更的行失败", zap.String("table", table), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRowMaps(rows)
+}
+
+// SelectMaxTombstoneID 查询最大的墓碑记录ID，没有墓碑记录时返回0
+func SelectMaxTombstoneID(ctx context.Context) (int64, error) {
+	var maxID sql.NullInt64
+	if err := db.WithContext(ctx).Model(&pojo.CDCTombstone{}).Select("MAX(id)").Scan(&maxID).Error; err != nil {
+		logger.Ctx(ctx).Error("查询最大的墓碑记录ID失败", zap.Error(err))
+		return 0, err
+	}
+	return maxID.Int64, nil
+}
+
+// SelectTombstones 按照ID升序查询ID大于afterID的墓碑记录
+func SelectTombstones(ctx context.Context, afterID int64, limit int) ([]*pojo.CDCTombstone, error) {
+	tombstones := make([]*pojo.CDCTombstone, 0)
+	if err := db.WithContext(ctx).Where("id > ?", afterID).Order("id asc").Limit(limit).Find(&tombstones).Error; err != nil {
+		logger.Ctx(ctx).Error("查询墓碑记录失败", zap.Int64("afterID", afterID), zap.Error(err))
+		return nil, err
+	}
+	return tombstones, nil
+}
+
+// DeleteTombstonesBefore 删除创建时间早于before的墓碑记录
+func DeleteTombstonesBefore(ctx context.Context, before time.Time) error {
+	if err := db.WithContext(ctx).Where("created_time < ?", before).Delete(&pojo.CDCTombstone{}).Error; err != nil {
+		logger.Ctx(ctx).Error("删除过期的墓碑记录失败", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// EnableTombstone 为指定的表开启墓碑记录：通过gorm删除这些表中的行时，在同一个事务中记录被删除行的列。
+// 直接执行的DELETE语句(db.Exec)不会被记录
+func EnableTombstone(ctx context.Context, tables []string) error {
+	enabled := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		enabled[table] = true
+	}
+	err := db.WithContext(ctx).Callback().Delete().Before("gorm:delete").Register("cdc:tombstone_before_delete", func(tx *gorm.DB) {
+		if tx.Error != nil || !enabled[tx.Statement.Table] {
+			return
+		}
+		rows, err := selectDeletingRows(tx)
+		if err != nil {
+			_ = tx.AddError(err)
+			return
+		}
+		tx.InstanceSet(tombstoneRowsKey, rows)
+	})
+	if err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Callback().Delete().After("gorm:delete").Register("cdc:tombstone_after_delete", func(tx *gorm.DB) {
+		if tx.Error != nil || tx.RowsAffected == 0 || !enabled[tx.Statement.Table] {
+			return
+		}
+		value, ok := tx.InstanceGet(tombstoneRowsKey)
+		if !ok {
+			return
+		}
+		if err := insertTombstones(tx, value.([]map[string]string)); err != nil {
+			_ = tx.AddError(err)
+		}
+	})
+}
+
+// selectDeletingRows 使用删除语句的条件查询将要被删除的行
+func selectDeletingRows(tx *gorm.DB) ([]map[string]string, error) {
+	query, ok := deletingQuery(tx)
+	if !ok {
+		return nil, nil
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		logger.Ctx(tx.Statement.Context).Error("查询将要被删除的行失败", zap.String("table", tx.Statement.Table), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRowMaps(rows)
+}
+
+// deletingQuery 构建与删除语句条件相同的查询，条件包括Where以及模型中的主键。没有任何条件时返回false
+func deletingQuery(tx *gorm.DB) (*gorm.DB, bool) {
+	stmt := tx.Statement
+	// 使用同一个连接(事务)查询
+	query := tx.Session(&gorm.Session{NewDB: true}).Table(stmt.Table)
+	hasCondition := false
+	if where, ok := stmt.Clauses["WHERE"]; ok {
+		if expression, ok := where.Expression.(clause.Where); ok && len(expression.Exprs) != 0 {
+			query = query.Clauses(expression)
+			hasCondition = true
+		}
+	}
+	if stmt.Schema != nil && len(stmt.Schema.PrimaryFields) != 0 && stmt.ReflectValue.IsValid() {
+		_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+		if len(values) != 0 {
+			query = query.Where(clause.IN{Column: column, Values: values})
+			hasCondition = true
+		}
+	}
+	return query, hasCondition
+}
+
+// insertTombstones 删除成功后记录墓碑。删除语句可能带有查询时没有的条件(例如乐观锁版本号)，
+// 所以再次查询，只为已经不存在的行记录墓碑
+func insertTombstones(tx *gorm.DB, deleting []map[string]string) error {
+	if len(deleting) == 0 {
+		return nil
+	}
+	remaining, err := selectDeletingRows(tx)
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(remaining))
+	for _, row := range remaining {
+		exists[RowKey(row)] = true
+	}
+	tombstones := make([]*pojo.CDCTombstone, 0, len(deleting))
+	for _, row := range deleting {
+		if exists[RowKey(row)] {
+			continue
+		}
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		tombstones = append(tombstones, &pojo.CDCTombstone{Table: tx.Statement.Table, RowData: string(data)})
+	}
+	if len(tombstones) == 0 {
+		return nil
+	}
+	if err = tx.Session(&gorm.Session{NewDB: true}).Create(&tombstones).Error; err != nil {
+		logger.Ctx(tx.Statement.Context).Error("记录墓碑失败", zap.String("table", tx.Statement.Table), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RowKey 使用行中所有列的值生成唯一标识，用于判断两行是否相同
+func RowKey(row map[string]string) string {
+	data, _ := json.Marshal(row)
+	return string(data)
+}
+
+// scanRowMaps 将查询结果转换为 列名->列值 的映射，值为NULL的列不会出现在映射中
+func scanRowMaps(rows *sql.Rows) ([]map[string]string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]string, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			if values[i] == nil {
+				continue
+			}
+			row[column] = formatColumnValue(values[i])
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// formatColumnValue 将列值转换为字符串，格式与canal解析binlog得到的列值一致
+func formatColumnValue(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(CDCTimeLayout)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
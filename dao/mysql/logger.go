@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+	"shop-backend/logger"
+	"time"
+)
+
+// 执行时间超过slowThreshold的SQL记录为慢SQL
+const slowThreshold = 200 * time.Millisecond
+
+// zapLogger 使用ctx中的日志记录gorm的日志，SQL日志带有请求ID、用户ID
+type zapLogger struct {
+	level gormlogger.LogLevel
+}
+
+func (l zapLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return zapLogger{level: level}
+}
+
+func (l zapLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		logger.Ctx(ctx).Info(fmt.Sprintf(msg, data...), zap.String("file", utils.FileWithLineNum()))
+	}
+}
+
+func (l zapLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		logger.Ctx(ctx).Warn(fmt.Sprintf(msg, data...), zap.String("file", utils.FileWithLineNum()))
+	}
+}
+
+func (l zapLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		logger.Ctx(ctx).Error(fmt.Sprintf(msg, data...), zap.String("file", utils.FileWithLineNum()))
+	}
+}
+
+// Trace 记录执行失败的SQL以及慢SQL，记录不存在不视为失败
+func (l zapLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		sql, rows := fc()
+		logger.Ctx(ctx).Error("SQL执行失败", zap.String("sql", sql), zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed), zap.String("file", utils.FileWithLineNum()), zap.Error(err))
+	case elapsed > slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		logger.Ctx(ctx).Warn("慢SQL", zap.String("sql", sql), zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed), zap.String("file", utils.FileWithLineNum()))
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		logger.Ctx(ctx).Debug("SQL", zap.String("sql", sql), zap.Int64("rows", rows),
+			zap.Duration("elapsed", elapsed), zap.String("file", utils.FileWithLineNum()))
+	}
+}
@@ -0,0 +1,75 @@
+package mysql
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"shop-backend/logger"
+	"shop-backend/models/pojo"
+	"time"
+)
+
+// SelectPendingOutbox 按照ID升序查询到达发送时间的待发送消息
+func SelectPendingOutbox(ctx context.Context, limit int) ([]*pojo.OutboxMessage, error) {
+	messages := make([]*pojo.OutboxMessage, 0)
+	err := db.WithContext(ctx).Where("status = ? AND next_retry_time <= ?", pojo.OutboxStatusPending, time.Now()).
+		Order("id asc").Limit(limit).Find(&messages).Error
+	if err != nil {
+		logger.Ctx(ctx).Error("查询待发送的发件箱消息失败", zap.Error(err))
+		return nil, err
+	}
+	return messages, nil
+}
+
+// ClaimOutbox 领取一条待发送消息，将下一次发送时间推迟lease，避免多个服务实例同时发送。
+// 以查询到的下一次发送时间作为条件，领取成功返回true
+func ClaimOutbox(ctx context.Context, message *pojo.OutboxMessage, lease time.Duration) (bool, error) {
+	next := time.Now().Add(lease)
+	result := db.WithContext(ctx).Model(&pojo.OutboxMessage{}).
+		Where("id = ? AND status = ? AND next_retry_time = ?", message.ID, pojo.OutboxStatusPending, message.NextRetryTime).
+		Update("next_retry_time", next)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("领取发件箱消息失败", zap.Int64("id", message.ID), zap.Error(result.Error))
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	message.NextRetryTime = next
+	return true, nil
+}
+
+// UpdateOutboxSent 将消息标记为已发送
+func UpdateOutboxSent(ctx context.Context, id int64) error {
+	err := db.WithContext(ctx).Model(&pojo.OutboxMessage{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": pojo.OutboxStatusSent, "sent_time": time.Now()}).Error
+	if err != nil {
+		logger.Ctx(ctx).Error("标记发件箱消息为已发送失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// UpdateOutboxRetry 发送失败后增加失败次数，并在delay后重新发送
+func UpdateOutboxRetry(ctx context.Context, id int64, delay time.Duration) error {
+	err := db.WithContext(ctx).Model(&pojo.OutboxMessage{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + ?", 1),
+			"next_retry_time": time.Now().Add(delay),
+		}).Error
+	if err != nil {
+		logger.Ctx(ctx).Error("修改发件箱消息的重试时间失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteSentOutboxBefore 删除在before之前发送成功的消息
+func DeleteSentOutboxBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := db.WithContext(ctx).Where("status = ? AND sent_time < ?", pojo.OutboxStatusSent, before).Delete(&pojo.OutboxMessage{})
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("清理已发送的发件箱消息失败", zap.Error(result.Error))
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
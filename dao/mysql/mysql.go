@@ -2,11 +2,13 @@ package mysql
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 	"shop-backend/settings"
 )
 
@@ -21,13 +23,18 @@ func Init(cfg *settings.MySQLConfig) (err error) {
 		cfg.Host,
 		cfg.Port,
 		cfg.Dbname)
-	// 初始化gorm
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// 初始化gorm，SQL日志写入zap
+	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: zapLogger{level: gormlogger.Warn}})
 	if err != nil {
 		// 初始化失败
 		zap.L().Error("gorm.Open(mysql.Open(dsn), &gorm.Config{}) failed", zap.Error(err))
 		return
 	}
+	// 为每条SQL语句创建span
+	if err = db.Use(tracingPlugin{}); err != nil {
+		zap.L().Error("db.Use(tracingPlugin{}) failed", zap.Error(err))
+		return
+	}
 	// 获取sql.DB
 	sqlDB, _ = db.DB()
 	if err = sqlDB.Ping(); err != nil {
@@ -42,6 +49,14 @@ func Init(cfg *settings.MySQLConfig) (err error) {
 	return
 }
 
+// Ping 检查数据库连接是否可用
+func Ping() error {
+	if sqlDB == nil {
+		return errors.New("MySQL未初始化")
+	}
+	return sqlDB.Ping()
+}
+
 // Close 关键数据库连接
 func Close() {
 	_ = sqlDB.Close()
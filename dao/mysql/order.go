@@ -1,20 +1,30 @@
 package mysql
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"shop-backend/errs"
+	"shop-backend/logger"
 	"shop-backend/models/dto"
 	"shop-backend/models/pojo"
 	"strconv"
 	"time"
 )
 
+var (
+	ErrorInsufficientStock   = errs.InsufficientStock("商品已下架或购买数量超过库存")
+	ErrorCartProductNotExist = errs.NotFound("购物车中没有该商品")
+	ErrorOrderNotExist       = errs.NotFound("订单不存在")
+	ErrorOrderItemNotExist   = errs.NotFound("订单明细不存在")
+)
+
 // CheckOrderProduct 检查预提交订单中的商品是否还在上架，购买数量是否超过库存
-func CheckOrderProduct(cartProduct *dto.CartProduct, uid int64) (*pojo.Cart, *pojo.Sku, error) {
-	tx := db.Begin()
+func CheckOrderProduct(ctx context.Context, cartProduct *dto.CartProduct, uid int64) (*pojo.Cart, *pojo.Sku, error) {
+	tx := db.WithContext(ctx).Begin()
 
 	// 查询出sku，并校验上架状态和库存
 	// 类型转换
@@ -22,28 +32,34 @@ func CheckOrderProduct(cartProduct *dto.CartProduct, uid int64) (*pojo.Cart, *po
 	sku := new(pojo.Sku)
 	if err := tx.Where("id = ?", skuID).First(&sku).Error; err != nil {
 		tx.Rollback()
-		zap.L().Error("使用skuID查询sku信息失败", zap.Int64("skuID", skuID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrorSpuNotExist
+		}
+		logger.Ctx(ctx).Error("使用skuID查询sku信息失败", zap.Int64("skuID", skuID), zap.Error(err))
 		return nil, nil, err
 	}
 	if sku.Valid == 0 || sku.Stock < cartProduct.Count {
 		tx.Rollback()
-		zap.L().Error("商品已下架或者购买数量大于库存")
-		return nil, nil, errors.New("商品已下架或者购买数量大于库存")
+		logger.Ctx(ctx).Warn("商品已下架或者购买数量大于库存", zap.Int64("skuID", skuID), zap.Int("count", cartProduct.Count), zap.Int("stock", sku.Stock))
+		return nil, nil, ErrorInsufficientStock
 	}
 
 	// 返回购物车对象，用于构建购物车展示对象
 	cartPojo := new(pojo.Cart)
 	if err := tx.Where("user_id = ? and sku_id = ? and specification = ?", uid, skuID, cartProduct.Specification).First(cartPojo).Error; err != nil {
 		tx.Rollback()
-		zap.L().Error("根据用户ID、skuID、商品规格获取一条购物车数据失败")
-		return nil, nil, errors.New("根据用户ID、skuID、商品规格获取一条购物车数据失败")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrorCartProductNotExist
+		}
+		logger.Ctx(ctx).Error("根据用户ID、skuID、商品规格获取一条购物车数据失败", zap.Error(err))
+		return nil, nil, err
 	}
 	tx.Commit()
 	return cartPojo, sku, nil
 }
 
-// CreateOrderAndOrderItem 生成订单 && 校验库存和商品状态 && 生成订单明细
-func CreateOrderAndOrderItem(orderDTO *dto.Order, uid, orderNum int64) error {
+// CreateOrderAndOrderItem 生成订单 && 校验库存和商品状态 && 生成订单明细 && 将需要发送的消息写入发件箱
+func CreateOrderAndOrderItem(ctx context.Context, orderDTO *dto.Order, uid, orderNum int64, messages []*pojo.OutboxMessage) error {
 	// 生产订单对象
 	order := new(pojo.Order)
 	// 订单表记录主键自己生成，不需要数据库自增自动生成。目的是使用主键的唯一性来保证提交订单服务幂等性
@@ -64,7 +80,7 @@ func CreateOrderAndOrderItem(orderDTO *dto.Order, uid, orderNum int64) error {
 	totalMoney := decimal.NewFromFloat(0)
 	totalNum := 0
 
-	tx := db.Begin()
+	tx := db.WithContext(ctx).Begin()
 
 	// 校验库存 && 生成订单明细
 	for _, product := range orderDTO.CartProductList {
@@ -73,23 +89,27 @@ func CreateOrderAndOrderItem(orderDTO *dto.Order, uid, orderNum int64) error {
 		skuID, _ := strconv.ParseInt(product.SkuID, 10, 64)
 		sku := new(pojo.Sku)
 		result := tx.Where("id = ?", skuID).First(&sku)
-		if result.Error != nil || result.RowsAffected <= 0 {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			tx.Rollback()
+			return ErrorSpuNotExist
+		}
+		if result.Error != nil {
 			tx.Rollback()
-			zap.L().Error("使用skuID查询sku信息失败", zap.Int64("skuID", skuID))
+			logger.Ctx(ctx).Error("使用skuID查询sku信息失败", zap.Int64("skuID", skuID), zap.Error(result.Error))
 			return errors.New("使用skuID查询sku信息失败")
 		}
 
 		if sku.Valid == 0 || sku.Stock < product.Count {
 			tx.Rollback()
-			zap.L().Error("商品已下架或者购买数量大于库存")
-			return errors.New("商品已下架或者购买数量大于库存")
+			logger.Ctx(ctx).Warn("商品已下架或者购买数量大于库存", zap.Int64("skuID", skuID), zap.Int("count", product.Count), zap.Int("stock", sku.Stock))
+			return ErrorInsufficientStock
 		}
 
 		// 扣减库存
 		result = tx.Model(&pojo.Sku{}).Where("id = ?", skuID).Update("stock", gorm.Expr("stock - ?", product.Count))
 		if result.Error != nil || result.RowsAffected <= 0 {
 			tx.Rollback()
-			zap.L().Error("扣减库存失败", zap.Int64("skuID", skuID))
+			logger.Ctx(ctx).Error("扣减库存失败", zap.Int64("skuID", skuID))
 			return errors.New(fmt.Sprintf("扣减ID为%s商品库存失败", product.SkuID))
 		}
 
@@ -117,7 +137,7 @@ func CreateOrderAndOrderItem(orderDTO *dto.Order, uid, orderNum int64) error {
 		result = tx.Model(skuPic).Where("sku_id = ? and is_default = 1", sku.ID).First(skuPic)
 		if result.Error != nil || result.RowsAffected <= 0 {
 			tx.Rollback()
-			zap.L().Error("获取商品默认图片失败", zap.Int64("skuID", skuID))
+			logger.Ctx(ctx).Error("获取商品默认图片失败", zap.Int64("skuID", skuID))
 			return errors.New(fmt.Sprintf("获取ID为%s商品图片失败", product.SkuID))
 		}
 		orderItem.ProductPic = skuPic.PicUrl
@@ -125,7 +145,7 @@ func CreateOrderAndOrderItem(orderDTO *dto.Order, uid, orderNum int64) error {
 		result = tx.Create(orderItem)
 		if result.Error != nil || result.RowsAffected <= 0 {
 			tx.Rollback()
-			zap.L().Error("订单明细入库失败", zap.Int64("skuID", skuID))
+			logger.Ctx(ctx).Error("订单明细入库失败", zap.Int64("skuID", skuID))
 			return errors.New("订单明细入库")
 		}
 	}
@@ -144,75 +164,115 @@ func CreateOrderAndOrderItem(orderDTO *dto.Order, uid, orderNum int64) error {
 	result := tx.Create(order)
 	if result.Error != nil || result.RowsAffected <= 0 {
 		tx.Rollback()
-		zap.L().Error("订单入库失败", zap.Int64("skuID", orderNum), zap.Error(result.Error))
+		logger.Ctx(ctx).Error("订单入库失败", zap.Int64("skuID", orderNum), zap.Error(result.Error))
 		return errors.New("订单入库失败")
 	}
-	tx.Commit()
+	// 需要发送的消息与订单在同一个事务中写入发件箱，提交后由中继发送，服务崩溃也不会丢失
+	if len(messages) != 0 {
+		if err := tx.Create(&messages).Error; err != nil {
+			tx.Rollback()
+			logger.Ctx(ctx).Error("写入发件箱消息失败", zap.Int64("orderNum", orderNum), zap.Error(err))
+			return errors.New("写入发件箱消息失败")
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		logger.Ctx(ctx).Error("提交订单事务失败", zap.Int64("orderNum", orderNum), zap.Error(err))
+		return err
+	}
 	return nil
 }
 
 // SelectAllOrder 返回用户所有订单主表信息
-func SelectAllOrder(uid int64) ([]*pojo.Order, error) {
+func SelectAllOrder(ctx context.Context, uid int64) ([]*pojo.Order, error) {
 	data := make([]*pojo.Order, 0)
-	if err := db.Model(&pojo.Order{}).Where("user_id = ?", uid).Find(&data).Error; err != nil {
+	if err := db.WithContext(ctx).Model(&pojo.Order{}).Where("user_id = ?", uid).Find(&data).Error; err != nil {
 		return nil, err
 	}
 	return data, nil
 }
 
 // SelectOneOrderByUIDAndOrderNum 根据用户ID和订单号查询用户订单信息
-func SelectOneOrderByUIDAndOrderNum(uid, orderNum int64) (*pojo.Order, error) {
+func SelectOneOrderByUIDAndOrderNum(ctx context.Context, uid, orderNum int64) (*pojo.Order, error) {
 	order := new(pojo.Order)
-	result := db.Model(&pojo.Order{}).Where("id = ? and user_id = ?", orderNum, uid).First(order)
-	if result.Error != nil || result.RowsAffected <= 0 {
-		zap.L().Error("根据用户ID和订单号查询用户订单信息失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
-		return nil, errors.New("根据用户ID和订单号查询用户订单信息失败")
+	result := db.WithContext(ctx).Model(&pojo.Order{}).Where("id = ? and user_id = ?", orderNum, uid).First(order)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, ErrorOrderNotExist
+	}
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("根据用户ID和订单号查询用户订单信息失败", zap.Error(result.Error))
+		return nil, result.Error
 	}
 	return order, nil
 }
 
 // SelectOneOrderItem 返回一条订单的明细信息
-func SelectOneOrderItem(id int64) ([]*pojo.OrderItem, error) {
+func SelectOneOrderItem(ctx context.Context, id int64) ([]*pojo.OrderItem, error) {
 	data := make([]*pojo.OrderItem, 0)
-	result := db.Model(&pojo.OrderItem{}).Where("order_id = ?", id).Find(&data)
-	if result.Error != nil || result.RowsAffected <= 0 {
-		// 查询过程中出现异常或者查询到的行数为0
-		zap.L().Error("获取订单明细失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
-		return nil, errors.New("订单明细不存在")
+	result := db.WithContext(ctx).Model(&pojo.OrderItem{}).Where("order_id = ?", id).Find(&data)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("获取订单明细失败", zap.Error(result.Error))
+		return nil, result.Error
+	}
+	if result.RowsAffected <= 0 {
+		// 查询到的行数为0
+		return nil, ErrorOrderItemNotExist
 	}
 	return data, nil
 }
 
 // SelectOrderOrderStatus 返回订单状态
-func SelectOrderOrderStatus(id int64) (uint8, error) {
+func SelectOrderOrderStatus(ctx context.Context, id int64) (uint8, error) {
 	order := &pojo.Order{ID: id}
-	result := db.Model(order).First(order)
+	result := db.WithContext(ctx).Model(order).First(order)
 	if result.Error != nil || result.RowsAffected <= 0 {
-		zap.L().Error("获取订单状态失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
+		logger.Ctx(ctx).Error("获取订单状态失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
 		return 0, errors.New("获取订单状态失败")
 	}
 	return order.OrderStatus, nil
 }
 
 // UpdateOrderOrderStatus 修改订单支付状态
-func UpdateOrderOrderStatus(id int64, orderStatus uint8) error {
-	result := db.Model(&pojo.Order{ID: id}).Update("order_status", orderStatus)
+func UpdateOrderOrderStatus(ctx context.Context, id int64, orderStatus uint8) error {
+	result := db.WithContext(ctx).Model(&pojo.Order{ID: id}).Update("order_status", orderStatus)
 	if result.Error != nil || result.RowsAffected <= 0 {
-		zap.L().Error("修改订单状态失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
+		logger.Ctx(ctx).Error("修改订单状态失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
 		return errors.New("修改订单状态失败")
 	}
 	return nil
 }
 
-// RollbackOrderStock 回滚订单超时未支付的商品库存
-func RollbackOrderStock(id int64) error {
+// TimeoutOrder 在同一个事务中将超时未支付(6)的订单修改为超时(5)并回滚商品库存。
+// 订单已经不是未支付状态时(已支付、已经超时)不做任何修改，返回false，重复消费超时消息不会重复回滚库存
+func TimeoutOrder(ctx context.Context, id int64) (bool, error) {
+	tx := db.WithContext(ctx).Begin()
+	result := tx.Model(&pojo.Order{ID: id}).Where("order_status = ?", 6).Update("order_status", 5)
+	if result.Error != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("修改订单状态为超时失败", zap.Int64("orderID", id), zap.Error(result.Error))
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return false, nil
+	}
+	if err := rollbackOrderStock(tx, id); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		logger.Ctx(ctx).Error("提交订单超时事务失败", zap.Int64("orderID", id), zap.Error(err))
+		return false, err
+	}
+	return true, nil
+}
+
+// rollbackOrderStock 在事务中回滚订单的商品库存
+func rollbackOrderStock(tx *gorm.DB, id int64) error {
 	// 查询订单所包含的所有商品明细
-	tx := db.Begin()
 	items := make([]*pojo.OrderItem, 0)
 	result := tx.Model(&pojo.OrderItem{}).Where("order_id = ?", id).Find(&items)
 	if result.Error != nil || result.RowsAffected <= 0 {
-		tx.Rollback()
-		zap.L().Error("查询订单所包含的所有商品明细失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
+		logger.Ctx(tx.Statement.Context).Error("查询订单所包含的所有商品明细失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
 		return errors.New("查询订单所包含的所有商品明细失败")
 	}
 
@@ -226,7 +286,6 @@ func RollbackOrderStock(id int64) error {
 				// 没有找到商品记录，可能是商品已经下架，并删除了数据库中的记录
 				continue
 			}
-			tx.Rollback()
 			return err
 		} else {
 			// 异常为空，但是商品已经下架，数据库记录未删除
@@ -236,28 +295,25 @@ func RollbackOrderStock(id int64) error {
 		}
 
 		// 回滚库存(使用Version字段解决并发下的更新问题)
-		result = tx.Debug().Model(&pojo.Sku{ID: item.SkuID}).Update("stock", gorm.Expr("stock + ?", item.ProductQuantity))
+		result = tx.Model(&pojo.Sku{ID: item.SkuID}).Update("stock", gorm.Expr("stock + ?", item.ProductQuantity))
 		if result.Error != nil || result.RowsAffected <= 0 {
-			tx.Rollback()
-			zap.L().Error("回滚商品库存失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
+			logger.Ctx(tx.Statement.Context).Error("回滚商品库存失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
 			return errors.New("回滚商品库存失败")
 		}
 	}
-	// 库存全部都回滚成功
-	tx.Commit()
 	return nil
 }
 
 // DelOrderAndItems 删除订单主表信息和对应的订单明细信息
-func DelOrderAndItems(id int64) error {
-	tx := db.Begin()
+func DelOrderAndItems(ctx context.Context, id int64) error {
+	tx := db.WithContext(ctx).Begin()
 
 	// 删除订单主表信息
 	order := &pojo.Order{ID: id}
 	result := tx.Delete(order)
 	if result.Error != nil || result.RowsAffected <= 0 {
 		tx.Rollback()
-		zap.L().Error("删除订单主表信息失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
+		logger.Ctx(ctx).Error("删除订单主表信息失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
 		return errors.New("删除订单主表信息失败")
 	}
 
@@ -265,7 +321,7 @@ func DelOrderAndItems(id int64) error {
 	result = tx.Where("order_id = ?", id).Delete(&pojo.OrderItem{})
 	if result.Error != nil || result.RowsAffected <= 0 {
 		tx.Rollback()
-		zap.L().Error("删除订单明细失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
+		logger.Ctx(ctx).Error("删除订单明细失败", zap.Error(result.Error), zap.Int64("rowAffected", result.RowsAffected))
 		return errors.New("删除订单明细失败")
 	}
 
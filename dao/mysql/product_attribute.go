@@ -1,28 +1,153 @@
 package mysql
 
 import (
+	"context"
+	"errors"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"shop-backend/errs"
+	"shop-backend/logger"
 	"shop-backend/models/pojo"
 )
 
+var ErrorAttributeNotExist = errs.NotFound("商品属性不存在")
+var ErrorAttributeHasValues = errs.Conflict("属性名下仍有属性值，不能删除")
+var ErrorAttributeInUse = errs.Conflict("属性值仍被商品使用，不能删除")
+var ErrorAttributeInvalidParent = errs.Validation("商品属性的父属性不合法")
+
 // SelectAllAttribute 返回所有商品属性
-func SelectAllAttribute() ([]*pojo.ProductAttribute, error) {
+func SelectAllAttribute(ctx context.Context) ([]*pojo.ProductAttribute, error) {
 	attrs := make([]*pojo.ProductAttribute, 0)
-	result := db.Find(&attrs)
+	result := db.WithContext(ctx).Order("sort asc, id asc").Find(&attrs)
 	if result.Error != nil {
-		zap.L().Error("查询所有商品属性", zap.Error(result.Error))
+		logger.Ctx(ctx).Error("查询所有商品属性", zap.Error(result.Error))
 		return nil, result.Error
 	}
 	return attrs, nil
 }
 
-// SelectAttrIDeByCategoryID 查询商品二级分类下对应的所有商品属性ID
-func SelectAttrIDeByCategoryID(categoryID int64) ([]*pojo.ProductCategoryAttributeRel, error) {
+// SelectAttrIDeByCategoryIDs 查询多个商品分类下对应的所有商品属性ID
+func SelectAttrIDeByCategoryIDs(ctx context.Context, categoryIDs []int64) ([]*pojo.ProductCategoryAttributeRel, error) {
 	caRels := make([]*pojo.ProductCategoryAttributeRel, 0)
-	result := db.Where("product_category_id = ?", categoryID).Find(&caRels)
+	if len(categoryIDs) == 0 {
+		return caRels, nil
+	}
+	result := db.WithContext(ctx).Where("product_category_id IN ?", categoryIDs).Find(&caRels)
 	if result.Error != nil {
-		zap.L().Error("查询商品二级分类下对应的所有商品属性ID出错了", zap.Error(result.Error))
+		logger.Ctx(ctx).Error("查询多个商品分类下对应的所有商品属性ID出错了", zap.Error(result.Error))
 		return nil, result.Error
 	}
 	return caRels, nil
 }
+
+// SelectAttributeByID 根据主键ID查询商品属性
+func SelectAttributeByID(ctx context.Context, id int64) (*pojo.ProductAttribute, error) {
+	attr := new(pojo.ProductAttribute)
+	err := db.WithContext(ctx).Where("id = ?", id).First(attr).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrorAttributeNotExist
+		}
+		logger.Ctx(ctx).Error("根据主键ID查询商品属性失败", zap.Int64("id", id), zap.Error(err))
+		return nil, err
+	}
+	return attr, nil
+}
+
+// SelectAttributeByIDs 根据主键ID集合查询商品属性
+func SelectAttributeByIDs(ctx context.Context, ids []int64) ([]*pojo.ProductAttribute, error) {
+	attrs := make([]*pojo.ProductAttribute, 0)
+	if len(ids) == 0 {
+		return attrs, nil
+	}
+	if err := db.WithContext(ctx).Where("id IN ?", ids).Find(&attrs).Error; err != nil {
+		logger.Ctx(ctx).Error("根据主键ID集合查询商品属性失败", zap.Error(err))
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// InsertAttribute 新增商品属性名或属性值
+func InsertAttribute(ctx context.Context, attr *pojo.ProductAttribute) error {
+	if err := db.WithContext(ctx).Create(attr).Error; err != nil {
+		logger.Ctx(ctx).Error("新增商品属性失败", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// UpdateAttribute 修改商品属性的类型、名称和排序
+func UpdateAttribute(ctx context.Context, attr *pojo.ProductAttribute) error {
+	err := db.WithContext(ctx).Model(&pojo.ProductAttribute{}).Where("id = ?", attr.ID).
+		Select("type", "name", "sort").
+		Updates(attr).Error
+	if err != nil {
+		logger.Ctx(ctx).Error("修改商品属性失败", zap.Int64("id", attr.ID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DelAttribute 删除商品属性。属性名下仍有属性值、属性值仍被商品使用时拒绝删除
+func DelAttribute(ctx context.Context, id int64) error {
+	tx := db.WithContext(ctx).Begin()
+	var count int64
+	if err := tx.Model(&pojo.ProductAttribute{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("查询属性名下的属性值数量失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	if count > 0 {
+		tx.Rollback()
+		return ErrorAttributeHasValues
+	}
+	if err := tx.Model(&pojo.ProductAttributeRel{}).Where("product_attribute_id = ?", id).Count(&count).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("查询使用属性值的商品数量失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	if count > 0 {
+		tx.Rollback()
+		return ErrorAttributeInUse
+	}
+	result := tx.Where("id = ?", id).Delete(&pojo.ProductAttribute{})
+	if result.Error != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("删除商品属性失败", zap.Int64("id", id), zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrorAttributeNotExist
+	}
+	if err := tx.Where("product_attribute_id = ?", id).Delete(&pojo.ProductCategoryAttributeRel{}).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("删除商品分类和属性的对应关系失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// ReplaceSpuAttributeRel 使用attributeIDs覆盖商品spu的属性值
+func ReplaceSpuAttributeRel(ctx context.Context, spuID int64, attributeIDs []int64) error {
+	tx := db.WithContext(ctx).Begin()
+	if err := tx.Where("spu_id = ?", spuID).Delete(&pojo.ProductAttributeRel{}).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("删除spu对应的属性值失败", zap.Int64("spuID", spuID), zap.Error(err))
+		return err
+	}
+	if len(attributeIDs) != 0 {
+		rels := make([]*pojo.ProductAttributeRel, 0, len(attributeIDs))
+		for _, attributeID := range attributeIDs {
+			rels = append(rels, &pojo.ProductAttributeRel{SpuID: spuID, ProductAttributeID: attributeID})
+		}
+		if err := tx.Create(&rels).Error; err != nil {
+			tx.Rollback()
+			logger.Ctx(ctx).Error("新增spu对应的属性值失败", zap.Int64("spuID", spuID), zap.Error(err))
+			return err
+		}
+	}
+	tx.Commit()
+	return nil
+}
@@ -0,0 +1,153 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"shop-backend/errs"
+	"shop-backend/logger"
+	"shop-backend/models/pojo"
+)
+
+var ErrorBrandNotExist = errs.NotFound("品牌不存在")
+var ErrorBrandHasSpu = errs.Conflict("品牌下仍有商品，不能删除")
+
+// SelectAllBrand 查询所有品牌，按照sort升序排列
+func SelectAllBrand(ctx context.Context) ([]*pojo.Brand, error) {
+	brands := make([]*pojo.Brand, 0)
+	if err := db.WithContext(ctx).Order("sort asc, id asc").Find(&brands).Error; err != nil {
+		logger.Ctx(ctx).Error("查询所有品牌失败", zap.Error(err))
+		return nil, err
+	}
+	return brands, nil
+}
+
+// SelectBrandByID 根据主键ID查询品牌
+func SelectBrandByID(ctx context.Context, id int64) (*pojo.Brand, error) {
+	brand := new(pojo.Brand)
+	err := db.WithContext(ctx).Where("id = ?", id).First(brand).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrorBrandNotExist
+		}
+		logger.Ctx(ctx).Error("根据主键ID查询品牌失败", zap.Int64("id", id), zap.Error(err))
+		return nil, err
+	}
+	return brand, nil
+}
+
+// SelectShowBrandByCategoryID 查询商品分类下所有显示状态的品牌
+func SelectShowBrandByCategoryID(ctx context.Context, categoryID int64) ([]*pojo.Brand, error) {
+	brands := make([]*pojo.Brand, 0)
+	err := db.WithContext(ctx).Model(&pojo.Brand{}).
+		Joins("JOIN pms_product_category_brand_rel ON pms_product_category_brand_rel.brand_id = pms_brand.id").
+		Where("pms_product_category_brand_rel.product_category_id = ? AND pms_brand.show_status = ?", categoryID, 1).
+		Order("pms_brand.sort asc, pms_brand.id asc").
+		Find(&brands).Error
+	if err != nil {
+		logger.Ctx(ctx).Error("查询商品分类下的品牌失败", zap.Int64("categoryID", categoryID), zap.Error(err))
+		return nil, err
+	}
+	return brands, nil
+}
+
+// SelectCategoryBrandRelByBrandIDs 查询品牌对应的所有分类关系
+func SelectCategoryBrandRelByBrandIDs(ctx context.Context, brandIDs []int64) ([]*pojo.ProductCategoryBrandRel, error) {
+	rels := make([]*pojo.ProductCategoryBrandRel, 0)
+	if len(brandIDs) == 0 {
+		return rels, nil
+	}
+	if err := db.WithContext(ctx).Where("brand_id IN ?", brandIDs).Find(&rels).Error; err != nil {
+		logger.Ctx(ctx).Error("查询品牌对应的分类关系失败", zap.Error(err))
+		return nil, err
+	}
+	return rels, nil
+}
+
+// InsertBrand 新增品牌，并保存品牌和分类的对应关系
+func InsertBrand(ctx context.Context, brand *pojo.Brand, categoryIDs []int64) error {
+	tx := db.WithContext(ctx).Begin()
+	if err := tx.Create(brand).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("新增品牌失败", zap.Error(err))
+		return err
+	}
+	if err := replaceCategoryBrandRel(tx, brand.ID, categoryIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// UpdateBrand 修改品牌信息，并覆盖品牌和分类的对应关系
+func UpdateBrand(ctx context.Context, brand *pojo.Brand, categoryIDs []int64) error {
+	tx := db.WithContext(ctx).Begin()
+	result := tx.Model(&pojo.Brand{}).Where("id = ?", brand.ID).
+		Select("name", "first_letter", "logo", "description", "sort", "show_status").
+		Updates(brand)
+	if result.Error != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("修改品牌信息失败", zap.Int64("id", brand.ID), zap.Error(result.Error))
+		return result.Error
+	}
+	if err := replaceCategoryBrandRel(tx, brand.ID, categoryIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// DelBrand 删除品牌和品牌对应的分类关系。如果品牌下仍有商品spu，拒绝删除
+func DelBrand(ctx context.Context, id int64) error {
+	tx := db.WithContext(ctx).Begin()
+	var count int64
+	if err := tx.Model(&pojo.Spu{}).Where("brand_id = ?", id).Count(&count).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("查询品牌下的商品数量失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	if count > 0 {
+		tx.Rollback()
+		return ErrorBrandHasSpu
+	}
+	result := tx.Where("id = ?", id).Delete(&pojo.Brand{})
+	if result.Error != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("删除品牌失败", zap.Int64("id", id), zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrorBrandNotExist
+	}
+	if err := tx.Where("brand_id = ?", id).Delete(&pojo.ProductCategoryBrandRel{}).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("删除品牌对应的分类关系失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// replaceCategoryBrandRel 在事务中使用categoryIDs覆盖品牌对应的分类关系
+func replaceCategoryBrandRel(tx *gorm.DB, brandID int64, categoryIDs []int64) error {
+	if err := tx.Where("brand_id = ?", brandID).Delete(&pojo.ProductCategoryBrandRel{}).Error; err != nil {
+		logger.Ctx(tx.Statement.Context).Error("删除品牌对应的分类关系失败", zap.Int64("brandID", brandID), zap.Error(err))
+		return err
+	}
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+	rels := make([]*pojo.ProductCategoryBrandRel, 0, len(categoryIDs))
+	for _, categoryID := range categoryIDs {
+		rels = append(rels, &pojo.ProductCategoryBrandRel{ProductCategoryID: categoryID, BrandID: brandID})
+	}
+	if err := tx.Create(&rels).Error; err != nil {
+		logger.Ctx(tx.Statement.Context).Error("新增品牌对应的分类关系失败", zap.Int64("brandID", brandID), zap.Error(err))
+		return err
+	}
+	return nil
+}
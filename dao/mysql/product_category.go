@@ -1,39 +1,241 @@
 package mysql
 
 import (
+	"context"
+	"errors"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"shop-backend/errs"
+	"shop-backend/logger"
 	"shop-backend/models/pojo"
 )
 
+var ErrorCategoryNotExist = errs.NotFound("商品分类不存在")
+var ErrorCategoryHasSpu = errs.Conflict("商品分类下仍有商品，不能删除")
+var ErrorCategoryHasChildren = errs.Conflict("商品分类下仍有子分类")
+var ErrorCategoryInvalidParent = errs.Validation("商品分类的父分类不合法")
+
 // SelectAllCategory 查询所有分类信息
-func SelectAllCategory() ([]*pojo.ProductCategory, error) {
+func SelectAllCategory(ctx context.Context) ([]*pojo.ProductCategory, error) {
 	categories := make([]*pojo.ProductCategory, 0)
-	result := db.Find(&categories)
+	result := db.WithContext(ctx).Order("level asc, sort asc, id asc").Find(&categories)
 	if result.Error != nil {
-		zap.L().Error("SelectAllCategory 查询所有分类信息失败", zap.Error(result.Error))
+		logger.Ctx(ctx).Error("SelectAllCategory 查询所有分类信息失败", zap.Error(result.Error))
 		return nil, result.Error
 	}
 	return categories, nil
 }
 
-// SelectFirstCategory 查询一级分类信息(level = 0)
-func SelectFirstCategory() ([]*pojo.ProductCategory, error) {
+// SelectCategoryByID 根据主键ID查询商品分类
+func SelectCategoryByID(ctx context.Context, id int64) (*pojo.ProductCategory, error) {
+	category := new(pojo.ProductCategory)
+	err := db.WithContext(ctx).Where("id = ?", id).First(category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrorCategoryNotExist
+		}
+		logger.Ctx(ctx).Error("根据主键ID查询商品分类失败", zap.Int64("id", id), zap.Error(err))
+		return nil, err
+	}
+	return category, nil
+}
+
+// InsertCategory 新增商品分类，并保存分类和属性的对应关系
+func InsertCategory(ctx context.Context, category *pojo.ProductCategory, attributeIDs []int64) error {
+	tx := db.WithContext(ctx).Begin()
+	if err := tx.Create(category).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("新增商品分类失败", zap.Error(err))
+		return err
+	}
+	if err := replaceCategoryAttributeRel(tx, category.ID, attributeIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// UpdateCategory 修改商品分类的基本信息，并覆盖分类和属性的对应关系
+func UpdateCategory(ctx context.Context, category *pojo.ProductCategory, attributeIDs []int64) error {
+	tx := db.WithContext(ctx).Begin()
+	err := tx.Model(&pojo.ProductCategory{}).Where("id = ?", category.ID).
+		Select("name", "abbreviation", "icon").
+		Updates(category).Error
+	if err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("修改商品分类信息失败", zap.Int64("id", category.ID), zap.Error(err))
+		return err
+	}
+	if err = replaceCategoryAttributeRel(tx, category.ID, attributeIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// MoveCategory 将商品分类(连同子孙分类)移动到新的父分类下，并同步修改子孙分类的层级。
+// 不能将分类移动到自身或自身的子孙分类下
+func MoveCategory(ctx context.Context, id, parentID int64) error {
+	tx := db.WithContext(ctx).Begin()
 	categories := make([]*pojo.ProductCategory, 0)
-	result := db.Where("level = 0").Find(&categories)
+	if err := tx.Find(&categories).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("查询所有分类信息失败", zap.Error(err))
+		return err
+	}
+	// K: 分类ID V: 分类
+	categoryMap := make(map[int64]*pojo.ProductCategory, len(categories))
+	// K: 父分类ID V: 子分类ID集合
+	childrenMap := make(map[int64][]int64, len(categories))
+	for _, category := range categories {
+		categoryMap[category.ID] = category
+		childrenMap[category.ParentID] = append(childrenMap[category.ParentID], category.ID)
+	}
+	category, ok := categoryMap[id]
+	if !ok {
+		tx.Rollback()
+		return ErrorCategoryNotExist
+	}
+
+	// 收集分类自身以及所有子孙分类ID
+	subtree := []int64{id}
+	for i := 0; i < len(subtree); i++ {
+		subtree = append(subtree, childrenMap[subtree[i]]...)
+	}
+
+	var level uint8
+	if parentID != 0 {
+		parent, ok := categoryMap[parentID]
+		if !ok {
+			tx.Rollback()
+			return ErrorCategoryInvalidParent
+		}
+		for _, subID := range subtree {
+			if subID == parentID {
+				// 父分类是自身或自身的子孙分类，会形成环
+				tx.Rollback()
+				return ErrorCategoryInvalidParent
+			}
+		}
+		level = parent.Level + 1
+	}
+
+	err := tx.Model(&pojo.ProductCategory{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"parent_id": parentID, "level": level}).Error
+	if err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("移动商品分类失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	if level != category.Level && len(subtree) > 1 {
+		// 同步修改子孙分类的层级
+		err = tx.Model(&pojo.ProductCategory{}).Where("id IN ?", subtree[1:]).
+			Update("level", gorm.Expr("level + ? - ?", level, category.Level)).Error
+		if err != nil {
+			tx.Rollback()
+			logger.Ctx(ctx).Error("同步修改子孙分类层级失败", zap.Int64("id", id), zap.Error(err))
+			return err
+		}
+	}
+	tx.Commit()
+	return nil
+}
+
+// UpdateCategorySort 修改商品分类的排序
+func UpdateCategorySort(ctx context.Context, id int64, sort uint8) error {
+	return updateCategoryColumn(ctx, id, "sort", sort)
+}
+
+// UpdateCategoryShowStatus 修改商品分类的显示状态
+func UpdateCategoryShowStatus(ctx context.Context, id int64, showStatus uint8) error {
+	return updateCategoryColumn(ctx, id, "show_status", showStatus)
+}
+
+// DelCategory 删除商品分类以及分类和属性、品牌的对应关系。分类下仍有子分类或商品时拒绝删除
+func DelCategory(ctx context.Context, id int64) error {
+	tx := db.WithContext(ctx).Begin()
+	if err := checkCategoryEmpty(tx, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	result := tx.Where("id = ?", id).Delete(&pojo.ProductCategory{})
 	if result.Error != nil {
-		zap.L().Error("查询一级分类信息失败", zap.Error(result.Error))
-		return nil, result.Error
+		tx.Rollback()
+		logger.Ctx(ctx).Error("删除商品分类失败", zap.Int64("id", id), zap.Error(result.Error))
+		return result.Error
 	}
-	return categories, nil
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrorCategoryNotExist
+	}
+	if err := tx.Where("product_category_id = ?", id).Delete(&pojo.ProductCategoryAttributeRel{}).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("删除商品分类和属性的对应关系失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	if err := tx.Where("product_category_id = ?", id).Delete(&pojo.ProductCategoryBrandRel{}).Error; err != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("删除商品分类和品牌的对应关系失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	tx.Commit()
+	return nil
 }
 
-// SelectSecondCategory 查询二级分类信息(level = 0)
-func SelectSecondCategory() ([]*pojo.ProductCategory, error) {
-	categories := make([]*pojo.ProductCategory, 0)
-	result := db.Where("level = 1").Find(&categories)
+// updateCategoryColumn 修改商品分类的单个字段
+func updateCategoryColumn(ctx context.Context, id int64, column string, value interface{}) error {
+	result := db.WithContext(ctx).Model(&pojo.ProductCategory{}).Where("id = ?", id).Update(column, value)
 	if result.Error != nil {
-		zap.L().Error("查询二级分类信息失败", zap.Error(result.Error))
-		return nil, result.Error
+		logger.Ctx(ctx).Error("修改商品分类失败", zap.Int64("id", id), zap.String("column", column), zap.Error(result.Error))
+		return result.Error
 	}
-	return categories, nil
+	if result.RowsAffected == 0 {
+		// 值未发生变化时RowsAffected也为0，需要确认分类是否存在
+		if _, err := SelectCategoryByID(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCategoryEmpty 检查商品分类下是否还有子分类或商品spu
+func checkCategoryEmpty(tx *gorm.DB, id int64) error {
+	var count int64
+	if err := tx.Model(&pojo.ProductCategory{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+		logger.Ctx(tx.Statement.Context).Error("查询子分类数量失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	if count > 0 {
+		return ErrorCategoryHasChildren
+	}
+	if err := tx.Model(&pojo.Spu{}).Where("category_id = ? OR cid1 = ? OR cid2 = ?", id, id, id).Count(&count).Error; err != nil {
+		logger.Ctx(tx.Statement.Context).Error("查询分类下的商品数量失败", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	if count > 0 {
+		return ErrorCategoryHasSpu
+	}
+	return nil
+}
+
+// replaceCategoryAttributeRel 在事务中使用attributeIDs覆盖分类对应的属性关系
+func replaceCategoryAttributeRel(tx *gorm.DB, categoryID int64, attributeIDs []int64) error {
+	if err := tx.Where("product_category_id = ?", categoryID).Delete(&pojo.ProductCategoryAttributeRel{}).Error; err != nil {
+		logger.Ctx(tx.Statement.Context).Error("删除分类对应的属性关系失败", zap.Int64("categoryID", categoryID), zap.Error(err))
+		return err
+	}
+	if len(attributeIDs) == 0 {
+		return nil
+	}
+	rels := make([]*pojo.ProductCategoryAttributeRel, 0, len(attributeIDs))
+	for _, attributeID := range attributeIDs {
+		rels = append(rels, &pojo.ProductCategoryAttributeRel{ProductCategoryID: categoryID, ProductAttributeID: attributeID})
+	}
+	if err := tx.Create(&rels).Error; err != nil {
+		logger.Ctx(tx.Statement.Context).Error("新增分类对应的属性关系失败", zap.Int64("categoryID", categoryID), zap.Error(err))
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,206 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"shop-backend/errs"
+	"shop-backend/logger"
+	"shop-backend/models/pojo"
+	"shop-backend/models/vo"
+	"time"
+)
+
+var (
+	ErrorOrderItemNotCompleted = errs.Conflict("订单明细不存在或订单未完成")
+	ErrorCommentExist          = errs.Conflict("该订单明细已经评价过了")
+	ErrorCommentNotExist       = errs.NotFound("评价不存在")
+	ErrorCommentAlreadyVoted   = errs.Conflict("已经投过票了")
+)
+
+// 订单状态：已完成
+const orderStatusCompleted = 3
+
+// 评价列表的排序方式
+const (
+	// 最新
+	CommentSortNewest = 1
+	// 最有用
+	CommentSortHelpful = 2
+)
+
+// SelectCompletedOrderItem 查询用户已完成订单中的订单明细，不存在或订单未完成时返回ErrorOrderItemNotCompleted
+func SelectCompletedOrderItem(ctx context.Context, uid, orderItemID int64) (*pojo.OrderItem, error) {
+	item := new(pojo.OrderItem)
+	result := db.WithContext(ctx).Model(&pojo.OrderItem{}).
+		Select("oms_order_item.*").
+		Joins("JOIN oms_order ON oms_order.id = oms_order_item.order_id").
+		Where("oms_order_item.id = ? AND oms_order.user_id = ? AND oms_order.order_status = ?", orderItemID, uid, orderStatusCompleted).
+		Limit(1).
+		Find(item)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("查询用户已完成订单的订单明细失败", zap.Int64("orderItemID", orderItemID), zap.Error(result.Error))
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrorOrderItemNotCompleted
+	}
+	return item, nil
+}
+
+// InsertComment 新增商品评价，同一个订单明细重复评价时返回ErrorCommentExist
+func InsertComment(ctx context.Context, comment *pojo.Comment) error {
+	if err := db.WithContext(ctx).Create(comment).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrorCommentExist
+		}
+		logger.Ctx(ctx).Error("新增商品评价失败", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SelectCommentByID 根据主键ID查询商品评价
+func SelectCommentByID(ctx context.Context, id int64) (*pojo.Comment, error) {
+	comment := new(pojo.Comment)
+	err := db.WithContext(ctx).Where("id = ?", id).First(comment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrorCommentNotExist
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error("根据主键ID查询商品评价失败", zap.Int64("id", id), zap.Error(err))
+		return nil, err
+	}
+	return comment, nil
+}
+
+// InsertCommentAppend 新增追加评价
+func InsertCommentAppend(ctx context.Context, commentAppend *pojo.CommentAppend) error {
+	if err := db.WithContext(ctx).Create(commentAppend).Error; err != nil {
+		logger.Ctx(ctx).Error("新增追加评价失败", zap.Int64("commentID", commentAppend.CommentID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// UpdateCommentReply 修改商家回复
+func UpdateCommentReply(ctx context.Context, id int64, reply string) error {
+	result := db.WithContext(ctx).Model(&pojo.Comment{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"reply": reply, "reply_time": time.Now()})
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("修改商家回复失败", zap.Int64("id", id), zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrorCommentNotExist
+	}
+	return nil
+}
+
+// InsertCommentHelpful 用户为评价投"有用"票，并增加评价的有用数。重复投票时返回ErrorCommentAlreadyVoted
+func InsertCommentHelpful(ctx context.Context, commentID, uid int64) error {
+	tx := db.WithContext(ctx).Begin()
+	if err := tx.Create(&pojo.CommentHelpful{CommentID: commentID, UserID: uid}).Error; err != nil {
+		tx.Rollback()
+		if isDuplicateKeyError(err) {
+			return ErrorCommentAlreadyVoted
+		}
+		logger.Ctx(ctx).Error("新增评价有用投票失败", zap.Int64("commentID", commentID), zap.Error(err))
+		return err
+	}
+	result := tx.Model(&pojo.Comment{}).Where("id = ?", commentID).
+		UpdateColumn("helpful_count", gorm.Expr("helpful_count + ?", 1))
+	if result.Error != nil {
+		tx.Rollback()
+		logger.Ctx(ctx).Error("增加评价有用数失败", zap.Int64("commentID", commentID), zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrorCommentNotExist
+	}
+	tx.Commit()
+	return nil
+}
+
+// SelectCommentList 分页查询商品的评价列表，返回当前页的评价以及符合条件的评价总数
+func SelectCommentList(ctx context.Context, spuID int64, withPic bool, sort, pageNo, pageSize int) ([]*vo.CommentVO, int64, error) {
+	comments := make([]*vo.CommentVO, 0)
+	var total int64
+	query := db.WithContext(ctx).Model(&pojo.Comment{}).Where("pms_comment.spu_id = ?", spuID)
+	if withPic {
+		query.Where("pms_comment.has_pic = ?", 1)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		logger.Ctx(ctx).Error("查询商品评价总数失败", zap.Int64("spuID", spuID), zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return comments, 0, nil
+	}
+
+	query = db.WithContext(ctx).Model(&pojo.Comment{}).
+		Select("pms_comment.id, pms_comment.sku_id, pms_comment.user_id, ums_user.username, ums_user.avatar, pms_comment.star, "+
+			"pms_comment.content, pms_comment.pics, pms_comment.helpful_count, pms_comment.reply, pms_comment.reply_time, pms_comment.created_time").
+		Joins("LEFT JOIN ums_user ON ums_user.user_id = pms_comment.user_id").
+		Where("pms_comment.spu_id = ?", spuID)
+	if withPic {
+		query.Where("pms_comment.has_pic = ?", 1)
+	}
+	if sort == CommentSortHelpful {
+		query.Order("pms_comment.helpful_count desc")
+	}
+	query.Order("pms_comment.created_time desc").Order("pms_comment.id desc")
+	result := query.Limit(pageSize).Offset((pageNo - 1) * pageSize).Scan(&comments)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("分页查询商品评价失败", zap.Int64("spuID", spuID), zap.Error(result.Error))
+		return nil, 0, result.Error
+	}
+	return comments, total, nil
+}
+
+// SelectCommentAppendByCommentIDs 根据评价ID集合查询追加评价，按照创建时间升序
+func SelectCommentAppendByCommentIDs(ctx context.Context, commentIDs []int64) ([]*pojo.CommentAppend, error) {
+	appends := make([]*pojo.CommentAppend, 0)
+	if len(commentIDs) == 0 {
+		return appends, nil
+	}
+	if err := db.WithContext(ctx).Where("comment_id IN ?", commentIDs).Order("created_time asc").Find(&appends).Error; err != nil {
+		logger.Ctx(ctx).Error("根据评价ID集合查询追加评价失败", zap.Error(err))
+		return nil, err
+	}
+	return appends, nil
+}
+
+// SelectCommentStarCounts 查询商品每个星级的评价数
+func SelectCommentStarCounts(ctx context.Context, spuID int64) ([]*vo.RatingStarVO, error) {
+	stars := make([]*vo.RatingStarVO, 0)
+	result := db.WithContext(ctx).Model(&pojo.Comment{}).
+		Select("star, COUNT(*) AS count").
+		Where("spu_id = ?", spuID).
+		Group("star").
+		Scan(&stars)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("查询商品每个星级的评价数失败", zap.Int64("spuID", spuID), zap.Error(result.Error))
+		return nil, result.Error
+	}
+	return stars, nil
+}
+
+// CountCommentWithPic 查询商品有图评价的数量
+func CountCommentWithPic(ctx context.Context, spuID int64) (int64, error) {
+	var count int64
+	if err := db.WithContext(ctx).Model(&pojo.Comment{}).Where("spu_id = ? AND has_pic = ?", spuID, 1).Count(&count).Error; err != nil {
+		logger.Ctx(ctx).Error("查询商品有图评价的数量失败", zap.Int64("spuID", spuID), zap.Error(err))
+		return 0, err
+	}
+	return count, nil
+}
+
+// isDuplicateKeyError 是否为违反唯一索引的错误
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
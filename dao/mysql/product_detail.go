@@ -1,15 +1,35 @@
 package mysql
 
 import (
+	"context"
+	"errors"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"shop-backend/errs"
+	"shop-backend/logger"
 	"shop-backend/models/pojo"
 )
 
+var ErrorSpuNotExist = errs.NotFound("商品spu不存在")
+
+// SelectSpuByID 使用spuID获取spu信息
+func SelectSpuByID(ctx context.Context, spuID int64) (*pojo.Spu, error) {
+	spu := new(pojo.Spu)
+	if err := db.WithContext(ctx).Where("id = ?", spuID).First(spu).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrorSpuNotExist
+		}
+		logger.Ctx(ctx).Error("使用spuID获取spu信息失败", zap.Error(err), zap.Int64("spuID", spuID))
+		return nil, err
+	}
+	return spu, nil
+}
+
 // SelectSpuBySkuID 使用skuID获取spu信息
-func SelectSpuBySkuID(skuID int64) (*pojo.Spu, error) {
+func SelectSpuBySkuID(ctx context.Context, skuID int64) (*pojo.Spu, error) {
 	spu := new(pojo.Spu)
-	result := db.Model(&pojo.Sku{}).
-		Select("pms_spu.id, pms_spu.brand_id, pms_spu.cid1, pms_spu.cid2, "+
+	result := db.WithContext(ctx).Model(&pojo.Sku{}).
+		Select("pms_spu.id, pms_spu.brand_id, pms_spu.category_id, pms_spu.cid1, pms_spu.cid2, "+
 			"pms_spu.sale, pms_spu.publish_status, pms_spu.verify_status, "+
 			"pms_spu.valid, pms_spu.name, pms_spu.sub_title, "+
 			"pms_spu.product_specification, pms_spu.default_pic_url, pms_spu.default_price, "+
@@ -17,41 +37,63 @@ func SelectSpuBySkuID(skuID int64) (*pojo.Spu, error) {
 		Joins("LEFT JOIN pms_spu ON pms_spu.id = pms_sku.spu_id").
 		Where("pms_sku.id = ?", skuID).
 		First(spu)
-	if result.Error != nil {
-		zap.L().Error("使用skuID获取spu信息失败", zap.Error(result.Error), zap.Int64("skuID", skuID))
-		return nil, result.Error
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, ErrorSpuNotExist
 	}
-	return spu, nil
-}
-
-// SelectSpuCategoryByCID 获取spu所有的分类信息(
-func SelectSpuCategoryByCID(cid1, cid2 int64) ([]*pojo.ProductCategory, error) {
-	categories := make([]*pojo.ProductCategory, 0)
-	result := db.Model(&pojo.ProductCategory{}).Where("id IN ?", []int64{cid1, cid2}).Find(&categories)
 	if result.Error != nil {
-		zap.L().Error("通过cid1和cid2获取spu所有的分类信息失败", zap.Error(result.Error), zap.Int64("cid1", cid1), zap.Int64("cid2", cid2))
+		logger.Ctx(ctx).Error("使用skuID获取spu信息失败", zap.Error(result.Error), zap.Int64("skuID", skuID))
 		return nil, result.Error
 	}
-	return categories, nil
+	if spu.ID == 0 {
+		// sku存在，但是对应的spu已经被删除
+		return nil, ErrorSpuNotExist
+	}
+	return spu, nil
 }
 
 // SelectSkuListBySpuID 根据spuID获取skuList
-func SelectSkuListBySpuID(spuID int64) ([]*pojo.Sku, error) {
+func SelectSkuListBySpuID(ctx context.Context, spuID int64) ([]*pojo.Sku, error) {
 	skuList := make([]*pojo.Sku, 0)
-	result := db.Model(&pojo.Sku{}).Where("spu_id = ?", spuID).Where("is_default = 1").Find(&skuList)
+	result := db.WithContext(ctx).Model(&pojo.Sku{}).Where("spu_id = ?", spuID).Where("is_default = 1").Find(&skuList)
 	if result.Error != nil {
-		zap.L().Error("根据spuID获取skuList", zap.Error(result.Error), zap.Int64("spuID", spuID))
+		logger.Ctx(ctx).Error("根据spuID获取skuList", zap.Error(result.Error), zap.Int64("spuID", spuID))
 		return nil, result.Error
 	}
 	return skuList, nil
 }
 
 // SelectSkuPicBySkuID 使用skuID获取sku商品图片
-func SelectSkuPicBySkuID(skuID int64) ([]*pojo.SkuPic, error) {
+func SelectSkuPicBySkuID(ctx context.Context, skuID int64) ([]*pojo.SkuPic, error) {
 	skuPicList := make([]*pojo.SkuPic, 0)
-	if err := db.Model(&pojo.SkuPic{}).Where("sku_id = ?", skuID).Find(&skuPicList).Error; err != nil {
-		zap.L().Error("使用skuID获取sku商品图片", zap.Error(err), zap.Int64("skuID", skuID))
+	if err := db.WithContext(ctx).Model(&pojo.SkuPic{}).Where("sku_id = ?", skuID).Find(&skuPicList).Error; err != nil {
+		logger.Ctx(ctx).Error("使用skuID获取sku商品图片", zap.Error(err), zap.Int64("skuID", skuID))
 		return nil, err
 	}
 	return skuPicList, nil
 }
+
+// SelectSkuPicBySkuIDs 使用skuID集合一次性获取多个sku的商品图片
+func SelectSkuPicBySkuIDs(ctx context.Context, skuIDs []int64) ([]*pojo.SkuPic, error) {
+	skuPicList := make([]*pojo.SkuPic, 0)
+	if len(skuIDs) == 0 {
+		return skuPicList, nil
+	}
+	if err := db.WithContext(ctx).Model(&pojo.SkuPic{}).Where("sku_id IN ?", skuIDs).Find(&skuPicList).Error; err != nil {
+		logger.Ctx(ctx).Error("使用skuID集合获取sku商品图片失败", zap.Error(err))
+		return nil, err
+	}
+	return skuPicList, nil
+}
+
+// SelectSpuIDBySkuIDs 使用skuID集合查询sku所属的spuID集合
+func SelectSpuIDBySkuIDs(ctx context.Context, skuIDs []int64) ([]int64, error) {
+	spuIDs := make([]int64, 0)
+	if len(skuIDs) == 0 {
+		return spuIDs, nil
+	}
+	if err := db.WithContext(ctx).Model(&pojo.Sku{}).Where("id IN ?", skuIDs).Distinct().Pluck("spu_id", &spuIDs).Error; err != nil {
+		logger.Ctx(ctx).Error("使用skuID集合查询spuID失败", zap.Error(err))
+		return nil, err
+	}
+	return spuIDs, nil
+}
@@ -1,8 +1,12 @@
 package mysql
 
 import (
-	"errors"
+	"context"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"shop-backend/errs"
+	"shop-backend/logger"
 	"shop-backend/models/dto"
 	"shop-backend/models/vo"
 	"shop-backend/utils/concatstr"
@@ -11,91 +15,286 @@ import (
 )
 
 var MAXRecord = 100
-var ErrorExceedMaxRecord = errors.New("超过单次查询最大记录条数")
+var ErrorExceedMaxRecord = errs.Validation("超过单次查询最大记录条数")
 
-// BaseSearchCondition 根据条件查询商品，needLimit：是否要分页查询
-func BaseSearchCondition(condition *dto.SearchCondition, needLimit bool) ([]*vo.ProductVO, int, error) {
+// 构造搜索查询时需要忽略的搜索条件，用于聚合
+const (
+	excludeNone = 0
+	// 忽略品牌条件
+	excludeBrand = 1 << iota
+	// 忽略属性值条件
+	excludeAttribute
+	// 忽略分类条件
+	excludeCategory
+)
+
+// spuCategoryColumn spu所属的末级分类。category_id为0的旧数据使用cid2，与商品详情的面包屑导航保持一致
+const spuCategoryColumn = "IF(pms_spu.category_id = 0, pms_spu.cid2, pms_spu.category_id)"
+
+// searchSort 搜索结果的排序方式，Desc为true表示降序。所有排序都以pms_sku.id作为第二排序字段，保证排序结果稳定，以支持游标分页
+type searchSort struct {
+	Expr clause.Expr
+	Desc bool
+}
+
+// BaseSearchCondition 根据条件分页查询商品。
+// 搜索条件中的游标不为空时使用游标分页(keyset)，从游标位置之后开始查询，忽略页码；否则使用页码分页
+func BaseSearchCondition(ctx context.Context, condition *dto.SearchCondition) ([]*vo.ProductVO, error) {
+	data := make([]*vo.ProductVO, 0)
+	db, err := buildSearchQuery(ctx, condition, excludeNone)
+	if err != nil {
+		return nil, err
+	}
+	order, err := getSearchSort(ctx, condition)
+	if err != nil {
+		return nil, err
+	}
+	db.Select("pms_sku.id, pms_sku.title AS name, pms_sku.sale, pms_sku.price AS defaultPrice, pms_spu.default_pic_url AS defaultPicUrl, CAST(? AS CHAR) AS sortValue", order.Expr)
+
+	pageSize, err := strconv.Atoi(condition.PageSize)
+	if err != nil {
+		logger.Ctx(ctx).Error("PageSize转换为整型失败", zap.Error(err))
+		return nil, err
+	}
+	if pageSize > MAXRecord {
+		logger.Ctx(ctx).Error("超过单次查询最大记录条数", zap.Error(ErrorExceedMaxRecord))
+		return nil, ErrorExceedMaxRecord
+	}
+
+	if condition.CursorID != 0 {
+		// 游标分页，查询排序位置在游标之后的商品
+		operator := ">"
+		if order.Desc {
+			operator = "<"
+		}
+		db.Where(concatstr.ConcatString("(? ", operator, " ? OR (? = ? AND pms_sku.id ", operator, " ?))"),
+			order.Expr, condition.CursorValue, order.Expr, condition.CursorValue, condition.CursorID)
+		db.Limit(pageSize)
+	} else {
+		pageNo, err := strconv.Atoi(condition.PageNo)
+		if err != nil {
+			logger.Ctx(ctx).Error("PageNo转换为整型失败", zap.Error(err))
+			return nil, err
+		}
+		if pageNo < 1 {
+			pageNo = 1
+		}
+		// 分页
+		db.Limit(pageSize).Offset((pageNo - 1) * pageSize)
+	}
+
+	direction := "ASC"
+	if order.Desc {
+		direction = "DESC"
+	}
+	db.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                concatstr.ConcatString("? ", direction, ", pms_sku.id ", direction),
+		Vars:               []interface{}{order.Expr},
+		WithoutParentheses: true,
+	}})
+
+	// 分组去重
+	db.Group("pms_sku.id")
+	result := db.Find(&data)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("使用搜索条件查询数据库失败", zap.Error(result.Error))
+		return nil, result.Error
+	}
+	return data, nil
+}
+
+// SelectProductBySkuIDs 根据skuID集合查询商品信息，不保证返回顺序
+func SelectProductBySkuIDs(ctx context.Context, skuIDs []int64) ([]*vo.ProductVO, error) {
 	data := make([]*vo.ProductVO, 0)
+	if len(skuIDs) == 0 {
+		return data, nil
+	}
+	result := db.WithContext(ctx).Model(&vo.ProductVO{}).
+		Select("pms_sku.id, pms_sku.title AS name, pms_sku.sale, pms_sku.price AS defaultPrice, pms_spu.default_pic_url AS defaultPicUrl").
+		Joins("LEFT JOIN pms_spu ON pms_sku.spu_id = pms_spu.id").
+		Where("pms_sku.id IN ?", skuIDs).
+		Find(&data)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("根据skuID集合查询商品信息失败", zap.Error(result.Error))
+		return nil, result.Error
+	}
+	return data, nil
+}
+
+// CountSearchCondition 根据条件查询符合条件的商品总数
+func CountSearchCondition(ctx context.Context, condition *dto.SearchCondition) (int64, error) {
+	var total int64
+	db, err := buildSearchQuery(ctx, condition, excludeNone)
+	if err != nil {
+		return 0, err
+	}
+	result := db.Select("COUNT(DISTINCT pms_sku.id)").Scan(&total)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("使用搜索条件统计商品总数失败", zap.Error(result.Error))
+		return 0, result.Error
+	}
+	return total, nil
+}
+
+// getSearchSort 根据搜索条件中的排序方式获取排序表达式
+func getSearchSort(ctx context.Context, condition *dto.SearchCondition) (*searchSort, error) {
+	sort, err := strconv.ParseUint(condition.Sort, 10, 8)
+	if err != nil {
+		logger.Ctx(ctx).Error("sort转换为整型失败", zap.Error(err))
+		return nil, err
+	}
+	switch {
+	case sort == 1 && len(condition.SkuIds) != 0:
+		// 使用全文索引时，默认按照相关度排序
+		return &searchSort{Expr: clause.Expr{SQL: "FIELD(pms_sku.id, ?)", Vars: []interface{}{condition.SkuIds}, WithoutParentheses: true}}, nil
+	case sort == 2:
+		// 按照销量排序
+		return &searchSort{Expr: clause.Expr{SQL: "pms_spu.sale"}, Desc: true}, nil
+	case sort == 3:
+		// 按照价格升序排序
+		return &searchSort{Expr: clause.Expr{SQL: "pms_sku.price"}}, nil
+	case sort == 4:
+		// 按照价格降序排序
+		return &searchSort{Expr: clause.Expr{SQL: "pms_sku.price"}, Desc: true}, nil
+	default:
+		// 按照创建时间排序
+		return &searchSort{Expr: clause.Expr{SQL: "pms_sku.created_time"}, Desc: true}, nil
+	}
+}
+
+// SelectBrandFacets 根据搜索条件聚合品牌，返回每个品牌下符合条件的商品数量。
+// 聚合时忽略搜索条件中的品牌ID，这样用户选中某个品牌后，仍然可以看到其他可选品牌
+func SelectBrandFacets(ctx context.Context, condition *dto.SearchCondition) ([]*vo.BrandFacetVO, error) {
+	facets := make([]*vo.BrandFacetVO, 0)
+	db, err := buildSearchQuery(ctx, condition, excludeBrand)
+	if err != nil {
+		return nil, err
+	}
+	result := db.Select("pms_brand.id, pms_brand.name, pms_brand.logo, COUNT(DISTINCT pms_sku.id) AS count").
+		Joins("JOIN pms_brand ON pms_brand.id = pms_spu.brand_id").
+		Where("pms_brand.show_status = ?", 1).
+		Group("pms_brand.id, pms_brand.name, pms_brand.logo").
+		Order("count desc, pms_brand.sort asc").
+		Scan(&facets)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("使用搜索条件聚合品牌失败", zap.Error(result.Error))
+		return nil, result.Error
+	}
+	return facets, nil
+}
+
+// SelectAttributeValueFacets 根据搜索条件聚合属性值，返回每个属性值下符合条件的商品数量。
+// 聚合时忽略搜索条件中的属性值ID，这样用户选中某个属性值后，仍然可以看到其他可选属性值的数量
+func SelectAttributeValueFacets(ctx context.Context, condition *dto.SearchCondition) ([]*vo.AttributeValueFacetVO, error) {
+	facets := make([]*vo.AttributeValueFacetVO, 0)
+	db, err := buildSearchQuery(ctx, condition, excludeAttribute)
+	if err != nil {
+		return nil, err
+	}
+	result := db.Select("pms_product_attribute_rel.product_attribute_id AS value_id, COUNT(DISTINCT pms_sku.id) AS count").
+		Where("pms_product_attribute_rel.product_attribute_id IS NOT NULL").
+		Group("pms_product_attribute_rel.product_attribute_id").
+		Scan(&facets)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("使用搜索条件聚合属性值失败", zap.Error(result.Error))
+		return nil, result.Error
+	}
+	return facets, nil
+}
+
+// SelectCategoryFacets 根据搜索条件按商品所属分类聚合，返回每个分类下符合条件的商品数量。
+// 聚合时忽略搜索条件中的分类ID，由logic层将分类归并到所属的二级分类
+func SelectCategoryFacets(ctx context.Context, condition *dto.SearchCondition) ([]*vo.CategoryFacetVO, error) {
+	facets := make([]*vo.CategoryFacetVO, 0)
+	db, err := buildSearchQuery(ctx, condition, excludeCategory)
+	if err != nil {
+		return nil, err
+	}
+	result := db.Select(spuCategoryColumn + " AS id, COUNT(DISTINCT pms_sku.id) AS count").
+		Group(spuCategoryColumn).
+		Scan(&facets)
+	if result.Error != nil {
+		logger.Ctx(ctx).Error("使用搜索条件聚合分类失败", zap.Error(result.Error))
+		return nil, result.Error
+	}
+	return facets, nil
+}
+
+// buildSearchQuery 根据搜索条件构造查询(不包含select、排序和分页)，exclude：需要忽略的搜索条件
+func buildSearchQuery(ctx context.Context, condition *dto.SearchCondition, exclude int) (*gorm.DB, error) {
 	// 绑定db对应的表为pms_sku
-	db := db.Model(&vo.ProductVO{})
-	db.Select("pms_sku.id, pms_sku.title AS name, pms_sku.sale, pms_sku.price AS defaultPrice, pms_spu.default_pic_url AS defaultPicUrl")
+	db := db.WithContext(ctx).Model(&vo.ProductVO{})
 	db.Joins("LEFT JOIN pms_sku_pic ON pms_sku_pic.sku_id = pms_sku.id")
 	db.Joins("LEFT JOIN pms_spu ON pms_sku.spu_id = pms_spu.id")
 	db.Joins("LEFT JOIN pms_product_attribute_rel ON pms_product_attribute_rel.spu_id = pms_spu.id")
 	db.Joins("LEFT JOIN pms_product_attribute ON pms_product_attribute.id = pms_product_attribute_rel.product_attribute_id")
 	// 每个sku在pms_sku中都有多个规格，is_default取值为0和1，1代表默认规格
 	db.Where("pms_sku.is_default = ?", 1)
-	if strings.TrimSpace(condition.Keyword) != "" {
+	if len(condition.SkuIds) != 0 {
+		// 全文索引召回的sku集合不为空
+		db.Where("pms_sku.id IN ?", condition.SkuIds)
+	} else if strings.TrimSpace(condition.Keyword) != "" {
 		// sku表 搜索关键字不为空
-		db.Where("title like ?", concatstr.ConcatString("%", strings.TrimSpace(condition.Keyword), "%"))
+		db.Where("pms_sku.title like ?", concatstr.ConcatString("%", strings.TrimSpace(condition.Keyword), "%"))
 	}
 
-	if strings.TrimSpace(condition.BrandId) != "" {
-		// spu表 品牌ID不为空
-		brandId, err := strconv.ParseInt(strings.TrimSpace(condition.BrandId), 10, 64)
-		if err != nil {
-			zap.L().Error("BrandId转换为整型失败", zap.Error(err))
-			return nil, 0, err
+	if exclude&excludeBrand == 0 {
+		// spu表 品牌ID集合不为空
+		brandIds := condition.BrandIds
+		if strings.TrimSpace(condition.BrandId) != "" {
+			brandId, err := strconv.ParseInt(strings.TrimSpace(condition.BrandId), 10, 64)
+			if err != nil {
+				logger.Ctx(ctx).Error("BrandId转换为整型失败", zap.Error(err))
+				return nil, err
+			}
+			brandIds = append([]int64{brandId}, brandIds...)
+		}
+		if len(brandIds) != 0 {
+			db.Where("pms_spu.brand_id IN ?", brandIds)
 		}
-		db.Where("brand_id = ?", brandId)
 	}
 
-	if strings.TrimSpace(condition.ProductCategoryId) != "" {
-		// spu表 商品二级分类ID不为空
-		productCategoryId, err := strconv.ParseInt(strings.TrimSpace(condition.ProductCategoryId), 10, 64)
+	if strings.TrimSpace(condition.MinPrice) != "" {
+		// sku表 最低价格不为空
+		minPrice, err := strconv.ParseFloat(strings.TrimSpace(condition.MinPrice), 64)
 		if err != nil {
-			zap.L().Error("ProductCategoryId转换为整型失败", zap.Error(err))
-			return nil, 0, err
+			logger.Ctx(ctx).Error("MinPrice转换为浮点数失败", zap.Error(err))
+			return nil, err
 		}
-		db.Where("cid2 = ?", productCategoryId)
+		db.Where("pms_sku.price >= ?", minPrice)
 	}
 
-	if len(condition.ProductAttributeIds) != 0 {
-		// pms_product_attribute_rel表 商品属性集合不为空
-		db.Where("pms_product_attribute_rel.product_attribute_id IN ? ", condition.ProductAttributeIds)
+	if strings.TrimSpace(condition.MaxPrice) != "" {
+		// sku表 最高价格不为空
+		maxPrice, err := strconv.ParseFloat(strings.TrimSpace(condition.MaxPrice), 64)
+		if err != nil {
+			logger.Ctx(ctx).Error("MaxPrice转换为浮点数失败", zap.Error(err))
+			return nil, err
+		}
+		db.Where("pms_sku.price <= ?", maxPrice)
 	}
 
-	// 排序ID不为空
-	sort, err := strconv.ParseUint(condition.Sort, 10, 8)
-	if err != nil {
-		zap.L().Error("sort转换为整型失败", zap.Error(err))
-		return nil, 0, err
-	}
-	if sort == 1 {
-		// 按照创建时间排序
-		db.Order("pms_product_attribute.created_time desc")
-	} else if sort == 2 {
-		// 按照销量排序
-		db.Order("pms_spu.sale desc")
+	if condition.InStock {
+		// sku表 只显示有货商品
+		db.Where("pms_sku.stock > ?", 0)
 	}
 
-	if needLimit {
-		pageSize, err := strconv.Atoi(condition.PageSize)
-		if err != nil {
-			zap.L().Error("PageSize转换为整型失败", zap.Error(err))
-			return nil, 0, err
-		}
-		if pageSize > MAXRecord {
-			zap.L().Error("超过单次查询最大记录条数", zap.Error(ErrorExceedMaxRecord))
-			return nil, 0, ErrorExceedMaxRecord
-		}
-
-		pageNo, err := strconv.Atoi(condition.PageNo)
+	if exclude&excludeCategory == 0 && len(condition.ProductCategoryTreeIds) != 0 {
+		// spu表 分类ID及其子孙分类ID集合不为空
+		db.Where(spuCategoryColumn+" IN ?", condition.ProductCategoryTreeIds)
+	} else if exclude&excludeCategory == 0 && strings.TrimSpace(condition.ProductCategoryId) != "" {
+		// spu表 分类ID不为空
+		productCategoryId, err := strconv.ParseInt(strings.TrimSpace(condition.ProductCategoryId), 10, 64)
 		if err != nil {
-			zap.L().Error("PageNo转换为整型失败", zap.Error(err))
-			return nil, 0, err
+			logger.Ctx(ctx).Error("ProductCategoryId转换为整型失败", zap.Error(err))
+			return nil, err
 		}
-		// 分页
-		db.Limit(pageSize).Offset((pageNo - 1) * pageSize)
+		db.Where(spuCategoryColumn+" = ?", productCategoryId)
 	}
 
-	// 分组去重
-	db.Group("pms_sku.id")
-	result := db.Debug().Find(&data)
-	if result.Error != nil {
-		zap.L().Error("使用搜索条件查询数据库失败", zap.Error(result.Error))
-		return nil, 0, result.Error
+	if exclude&excludeAttribute == 0 && len(condition.ProductAttributeIds) != 0 {
+		// pms_product_attribute_rel表 商品属性集合不为空
+		db.Where("pms_product_attribute_rel.product_attribute_id IN ? ", condition.ProductAttributeIds)
 	}
-
-	return data, len(data), nil
+	return db, nil
 }
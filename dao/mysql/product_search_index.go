@@ -0,0 +1,32 @@
+package mysql
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/models/pojo"
+	"shop-backend/models/vo"
+)
+
+// SelectSearchDocuments 查询建立全文索引使用的商品文档。skuIDs、spuIDs都为空时查询所有默认规格的sku
+func SelectSearchDocuments(ctx context.Context, skuIDs, spuIDs []int64) ([]*vo.SearchDocumentVO, error) {
+	docs := make([]*vo.SearchDocumentVO, 0)
+	db := db.WithContext(ctx).Model(&pojo.Sku{}).
+		Select("pms_sku.id AS sku_id, pms_sku.spu_id, pms_sku.title, pms_spu.name AS spu_name, pms_spu.sub_title, "+
+			"pms_brand.name AS brand_name, pms_product_category.name AS category_name").
+		Joins("JOIN pms_spu ON pms_spu.id = pms_sku.spu_id").
+		Joins("LEFT JOIN pms_brand ON pms_brand.id = pms_spu.brand_id").
+		Joins("LEFT JOIN pms_product_category ON pms_product_category.id = "+spuCategoryColumn).
+		Where("pms_sku.is_default = ?", 1)
+	if len(skuIDs) != 0 {
+		db.Where("pms_sku.id IN ?", skuIDs)
+	}
+	if len(spuIDs) != 0 {
+		db.Where("pms_sku.spu_id IN ?", spuIDs)
+	}
+	if err := db.Scan(&docs).Error; err != nil {
+		logger.Ctx(ctx).Error("查询建立全文索引使用的商品文档失败", zap.Error(err))
+		return nil, err
+	}
+	return docs, nil
+}
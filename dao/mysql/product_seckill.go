@@ -1,39 +1,79 @@
 package mysql
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
+	"shop-backend/errs"
 	"shop-backend/models/pojo"
 )
 
-// UpdateSecKillProductStock 使用乐观锁修改秒杀商品库存
-func UpdateSecKillProductStock(skuID int64) error {
-	tx := db.Begin()
+var (
+	// ErrorSecKillConflict 多个消费者同时修改同一个秒杀商品，乐观锁冲突
+	ErrorSecKillConflict = errs.Conflict("秒杀商品库存修改冲突")
+	// ErrorSecKillSkuNotExist 秒杀商品不存在
+	ErrorSecKillSkuNotExist = errs.NotFound("秒杀商品不存在")
+	// ErrorSecKillSoldOut 秒杀商品已经售罄
+	ErrorSecKillSoldOut = errs.InsufficientStock("秒杀商品已售罄")
+)
+
+// 乐观锁冲突时立即重试的次数
+const secKillConflictRetries = 3
+
+// UpdateSecKillProductStock 使用乐观锁修改秒杀商品库存，冲突时立即重试，多次冲突后返回ErrorSecKillConflict。
+// 商品不存在、已经售罄时返回ErrorSecKillSkuNotExist、ErrorSecKillSoldOut，重试也不会成功
+func UpdateSecKillProductStock(ctx context.Context, skuID int64) error {
+	for i := 0; i < secKillConflictRetries; i++ {
+		if err := updateSecKillProductStock(ctx, skuID); !errors.Is(err, ErrorSecKillConflict) {
+			return err
+		}
+	}
+	return ErrorSecKillConflict
+}
+
+// updateSecKillProductStock 使用乐观锁修改一次秒杀商品库存
+func updateSecKillProductStock(ctx context.Context, skuID int64) error {
+	tx := db.WithContext(ctx).Begin()
 	// 1. 根据主键ID查询出商品
 	var product pojo.SecKillSku
 	result := tx.First(&product, skuID)
-	if result.Error != nil || result.RowsAffected == 0 {
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		tx.Rollback()
-		return errors.New("查询秒杀商品失败")
+		return ErrorSecKillSkuNotExist
+	}
+	if result.Error != nil {
+		tx.Rollback()
+		return fmt.Errorf("查询秒杀商品失败: %w", result.Error)
+	}
+	if product.Stock <= 0 {
+		tx.Rollback()
+		return ErrorSecKillSoldOut
 	}
 	// 2. 更新
 	result = tx.Model(&product).Updates(map[string]interface{}{
 		"sale":  gorm.Expr("sale + ?", 1),
 		"stock": gorm.Expr("stock - ?", 1),
 	})
-	if result.Error != nil || result.RowsAffected == 0 {
+	if result.Error != nil {
 		tx.Rollback()
-		// 秒杀失败
-		return errors.New("秒杀失败")
+		return fmt.Errorf("扣减秒杀商品库存失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// 查询后版本号被其他消费者修改了
+		tx.Rollback()
+		return ErrorSecKillConflict
+	}
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("提交秒杀事务失败: %w", err)
 	}
-	tx.Commit()
 	return nil
 }
 
 // SelectAllSecKillSku 获取所有正在秒杀的商品
-func SelectAllSecKillSku() ([]*pojo.SecKillSku, error) {
+func SelectAllSecKillSku(ctx context.Context) ([]*pojo.SecKillSku, error) {
 	data := make([]*pojo.SecKillSku, 0)
-	if err := db.Model(&pojo.SecKillSku{}).Find(&data).Error; err != nil {
+	if err := db.WithContext(ctx).Model(&pojo.SecKillSku{}).Find(&data).Error; err != nil {
 		return nil, errors.New("获取所有正在秒杀的商品失败")
 	}
 	return data, nil
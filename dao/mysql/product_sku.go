@@ -1,15 +1,23 @@
 package mysql
 
 import (
+	"context"
+	"errors"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"shop-backend/logger"
 	"shop-backend/models/pojo"
 )
 
-// SelectSkuBySkuID 使用skuID查询sku信息
-func SelectSkuBySkuID(skuID int64) (*pojo.Sku, error) {
+// SelectSkuBySkuID 使用skuID查询sku信息，sku不存在时返回ErrorSpuNotExist
+func SelectSkuBySkuID(ctx context.Context, skuID int64) (*pojo.Sku, error) {
 	sku := new(pojo.Sku)
-	if err := db.Where("id = ?", skuID).First(&sku).Error; err != nil {
-		zap.L().Error("使用skuID查询sku信息失败", zap.Int64("skuID", skuID))
+	err := db.WithContext(ctx).Where("id = ?", skuID).First(&sku).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrorSpuNotExist
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error("使用skuID查询sku信息失败", zap.Int64("skuID", skuID), zap.Error(err))
 		return nil, err
 	}
 	return sku, nil
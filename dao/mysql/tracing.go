@@ -0,0 +1,78 @@
+package mysql
+
+import (
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"shop-backend/tracing"
+)
+
+// 保存当前语句span的key
+const tracingSpanKey = "tracing:span"
+
+// tracingPlugin 为每条SQL语句创建span，语句的ctx中没有链路时不创建。
+// 需要使用db.WithContext(ctx)传入请求的ctx
+type tracingPlugin struct{}
+
+func (tracingPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize 在每种操作的执行前后注册回调
+func (p tracingPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	errs := []error{
+		callback.Create().Before("gorm:create").Register("tracing:before_create", p.before("create")),
+		callback.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", p.before("query")),
+		callback.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", p.before("update")),
+		callback.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", p.before("row")),
+		callback.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// before 创建span，并将span的ctx作为语句的ctx
+func (tracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil || !tracing.Traced(db.Statement.Context) {
+			return
+		}
+		ctx, span := tracing.Tracer().Start(db.Statement.Context, "mysql "+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperationKey.String(operation)))
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+// after 记录SQL语句、影响行数以及错误，结束span。未找到记录不视为错误
+func (tracingPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	span.SetAttributes(
+		semconv.DBStatementKey.String(db.Statement.SQL.String()),
+		semconv.DBSQLTableKey.String(db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected))
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -1,7 +1,9 @@
 package redis
 
 import (
+	"context"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/utils/concatstr"
 	"strconv"
 	"time"
@@ -13,19 +15,19 @@ var (
 )
 
 // SetOrderNumber 将订单编号设置进Redis
-func SetOrderNumber(orderNum int64) error {
+func SetOrderNumber(ctx context.Context, orderNum int64) error {
 	key := concatstr.ConcatString(orderOrderNumPrefix, strconv.FormatInt(orderNum, 10))
-	if err := rdb.Set(key, nil, orderOrderNumLivingTime).Err(); err != nil {
-		zap.L().Error("将订单编号设置进Redis失败", zap.Error(err))
+	if err := withContext(ctx).Set(key, nil, orderOrderNumLivingTime).Err(); err != nil {
+		logger.Ctx(ctx).Error("将订单编号设置进Redis失败", zap.Error(err))
 		return err
 	}
 	return nil
 }
 
 // GetOrderNumber 判断订单号是否存在于Redis中
-func GetOrderNumber(orderNum int64) bool {
+func GetOrderNumber(ctx context.Context, orderNum int64) bool {
 	key := concatstr.ConcatString(orderOrderNumPrefix, strconv.FormatInt(orderNum, 10))
-	if err := rdb.Get(key).Err(); err != nil {
+	if err := withContext(ctx).Get(key).Err(); err != nil {
 		return false
 	}
 	return true
@@ -1,43 +1,98 @@
 package redis
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/go-redis/redis"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/models/vo"
+	"strconv"
 	"time"
 )
 
 var (
-	productCategoryPrefix = "product:category"
-	categoryLivingTime    = time.Hour * 24 * 15
+	productCategoryPrefix        = "product:category:tree"
+	productCategoryVersionPrefix = "product:category:version"
+	categoryLivingTime           = time.Hour * 24 * 15
 )
 
-// SetCategoryList 缓存商品分类信息
-func SetCategoryList(categories []vo.FirstProductCategoryVO) error {
+// setCategoryIfVersionScript 只有当分类版本号没有变化时才写入缓存，防止较旧的分类树覆盖较新的分类树
+// KEYS[1]: 分类缓存key KEYS[2]: 分类版本号key
+// ARGV[1]: 分类树json ARGV[2]: 构建分类树前读取到的版本号 ARGV[3]: 过期时间(秒)
+var setCategoryIfVersionScript = redis.NewScript(`
+local version = redis.call('GET', KEYS[2])
+if version == false then
+	version = '0'
+end
+if version == ARGV[2] then
+	redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[3])
+	return 1
+end
+return 0
+`)
+
+// SetCategoryListIfVersion 当分类版本号仍为version时，原子地覆盖缓存中的商品分类信息。返回是否写入成功
+func SetCategoryListIfVersion(ctx context.Context, categories []*vo.ProductCategoryTreeVO, version int64) (bool, error) {
 	bytes, err := json.Marshal(categories)
 	if err != nil {
-		zap.L().Error("序列化商品分类信息失败")
-		return err
+		logger.Ctx(ctx).Error("序列化商品分类信息失败")
+		return false, err
+	}
+	n, err := setCategoryIfVersionScript.Run(rdb,
+		[]string{productCategoryPrefix, productCategoryVersionPrefix},
+		string(bytes), strconv.FormatInt(version, 10), int64(categoryLivingTime/time.Second)).Int64()
+	if err != nil {
+		logger.Ctx(ctx).Error("将商品分类信息缓存进Redis失败", zap.Error(err))
+		return false, err
 	}
-	if err := rdb.Set(productCategoryPrefix, string(bytes), categoryLivingTime).Err(); err != nil {
-		zap.L().Error("将商品分类信息缓存进Redis失败", zap.Error(err))
+	return n == 1, nil
+}
+
+// GetCategoryVersion 获取商品分类版本号，不存在时返回0
+func GetCategoryVersion(ctx context.Context) (int64, error) {
+	version, err := withContext(ctx).Get(productCategoryVersionPrefix).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error("获取商品分类版本号失败", zap.Error(err))
+		return 0, err
+	}
+	return version, nil
+}
+
+// IncrCategoryVersion 商品分类发生变化，版本号加一
+func IncrCategoryVersion(ctx context.Context) (int64, error) {
+	version, err := withContext(ctx).Incr(productCategoryVersionPrefix).Result()
+	if err != nil {
+		logger.Ctx(ctx).Error("递增商品分类版本号失败", zap.Error(err))
+		return 0, err
+	}
+	return version, nil
+}
+
+// DelCategoryList 删除缓存中的商品分类信息
+func DelCategoryList(ctx context.Context) error {
+	if err := withContext(ctx).Del(productCategoryPrefix).Err(); err != nil {
+		logger.Ctx(ctx).Error("删除缓存中的商品分类信息失败", zap.Error(err))
 		return err
 	}
 	return nil
 }
 
 // GetCategoryList 获取缓存中的商品分类信息
-func GetCategoryList() ([]vo.FirstProductCategoryVO, bool) {
-	result, err := rdb.Get(productCategoryPrefix).Result()
+func GetCategoryList(ctx context.Context) ([]*vo.ProductCategoryTreeVO, bool) {
+	result, err := withContext(ctx).Get(productCategoryPrefix).Result()
 	if err != nil {
-		zap.L().Info("获取缓存中的商品分类信息失败")
+		logger.Ctx(ctx).Info("获取缓存中的商品分类信息失败")
 		return nil, false
 	}
 
 	var data = []byte(result)
-	var categories = make([]vo.FirstProductCategoryVO, 0)
+	var categories = make([]*vo.ProductCategoryTreeVO, 0)
 	if err := json.Unmarshal(data, &categories); err != nil {
-		zap.L().Error("反序列化商品分类信息失败")
+		logger.Ctx(ctx).Error("反序列化商品分类信息失败")
 		return nil, false
 	}
 
@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis"
+	"go.uber.org/zap"
+	"math/rand"
+	"shop-backend/logger"
+	"shop-backend/models/vo"
+	"shop-backend/utils/concatstr"
+	"strconv"
+	"time"
+)
+
+var (
+	// 商品详情缓存，以spu为粒度缓存，同一个spu下的sku共享一份详情
+	productDetailSpuPrefix = "product:detail:spu:"
+	// skuID到spuID的映射，value为"0"时表示该sku不存在(空值缓存，防止缓存穿透)
+	productDetailSkuPrefix = "product:detail:sku:"
+
+	// 商品详情缓存的基础过期时间，实际过期时间会加上随机时长，防止大量缓存同时过期(缓存雪崩)
+	productDetailLivingTime = time.Minute * 30
+	productDetailJitterTime = time.Minute * 10
+	// 空值缓存的过期时间
+	productDetailNullLivingTime = time.Minute * 2
+
+	// 空值缓存的value
+	productDetailNullValue = "0"
+)
+
+// GetProductDetailSpuID 获取缓存中sku所属的spuID。sku不存在(命中空值缓存)时返回0，未命中缓存时返回false
+func GetProductDetailSpuID(ctx context.Context, skuID int64) (int64, bool) {
+	key := concatstr.ConcatString(productDetailSkuPrefix, strconv.FormatInt(skuID, 10))
+	spuID, err := withContext(ctx).Get(key).Int64()
+	if err != nil {
+		if err != redis.Nil {
+			logger.Ctx(ctx).Error("获取缓存中sku所属的spuID失败", zap.Int64("skuID", skuID), zap.Error(err))
+		}
+		return 0, false
+	}
+	return spuID, true
+}
+
+// SetProductDetailSpuID 缓存sku所属的spuID
+func SetProductDetailSpuID(ctx context.Context, skuID, spuID int64) error {
+	key := concatstr.ConcatString(productDetailSkuPrefix, strconv.FormatInt(skuID, 10))
+	if err := withContext(ctx).Set(key, spuID, jitterLivingTime()).Err(); err != nil {
+		logger.Ctx(ctx).Error("缓存sku所属的spuID失败", zap.Int64("skuID", skuID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetProductDetailNull 缓存不存在的sku，防止不存在的skuID反复查询数据库
+func SetProductDetailNull(ctx context.Context, skuID int64) error {
+	key := concatstr.ConcatString(productDetailSkuPrefix, strconv.FormatInt(skuID, 10))
+	if err := withContext(ctx).Set(key, productDetailNullValue, productDetailNullLivingTime).Err(); err != nil {
+		logger.Ctx(ctx).Error("缓存不存在的sku失败", zap.Int64("skuID", skuID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetProductDetail 获取缓存中的商品详情，未命中缓存时返回false
+func GetProductDetail(ctx context.Context, spuID int64) (*vo.ProductDetailVO, bool) {
+	key := concatstr.ConcatString(productDetailSpuPrefix, strconv.FormatInt(spuID, 10))
+	result, err := withContext(ctx).Get(key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			logger.Ctx(ctx).Error("获取缓存中的商品详情失败", zap.Int64("spuID", spuID), zap.Error(err))
+		}
+		return nil, false
+	}
+	detail := new(vo.ProductDetailVO)
+	if err = json.Unmarshal(result, detail); err != nil {
+		logger.Ctx(ctx).Error("反序列化商品详情失败", zap.Int64("spuID", spuID), zap.Error(err))
+		return nil, false
+	}
+	return detail, true
+}
+
+// SetProductDetail 缓存商品详情
+func SetProductDetail(ctx context.Context, spuID int64, detail *vo.ProductDetailVO) error {
+	bytes, err := json.Marshal(detail)
+	if err != nil {
+		logger.Ctx(ctx).Error("序列化商品详情失败", zap.Int64("spuID", spuID), zap.Error(err))
+		return err
+	}
+	key := concatstr.ConcatString(productDetailSpuPrefix, strconv.FormatInt(spuID, 10))
+	if err = withContext(ctx).Set(key, bytes, jitterLivingTime()).Err(); err != nil {
+		logger.Ctx(ctx).Error("缓存商品详情失败", zap.Int64("spuID", spuID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DelProductDetail 删除spu的商品详情缓存以及sku到spu的映射缓存
+func DelProductDetail(ctx context.Context, spuIDs, skuIDs []int64) error {
+	keys := make([]string, 0, len(spuIDs)+len(skuIDs))
+	for _, spuID := range spuIDs {
+		keys = append(keys, concatstr.ConcatString(productDetailSpuPrefix, strconv.FormatInt(spuID, 10)))
+	}
+	for _, skuID := range skuIDs {
+		keys = append(keys, concatstr.ConcatString(productDetailSkuPrefix, strconv.FormatInt(skuID, 10)))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := withContext(ctx).Del(keys...).Err(); err != nil {
+		logger.Ctx(ctx).Error("删除商品详情缓存失败", zap.Strings("keys", keys), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// jitterLivingTime 商品详情缓存的过期时间：基础过期时间加上随机时长
+func jitterLivingTime() time.Duration {
+	return productDetailLivingTime + time.Duration(rand.Int63n(int64(productDetailJitterTime)))
+}
@@ -0,0 +1,176 @@
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"strings"
+	"time"
+)
+
+var (
+	// 热搜词，score为按时间衰减后的搜索热度
+	productSearchHotPrefix = "product:search:hot"
+	// 搜索补全前缀索引，所有成员的score都为0，使用ZRANGEBYLEX按前缀查询
+	productSearchSuggestPrefix = "product:search:suggest"
+	// 重建前缀索引时使用的临时key，写入完成后RENAME为正式key
+	productSearchSuggestTmpPrefix = "product:search:suggest:tmp"
+	// 被屏蔽的搜索关键字
+	productSearchBlockedPrefix = "product:search:blocked"
+	// 热度衰减、重建前缀索引的分布式锁，保证多个服务实例中同一周期只有一个实例执行
+	productSearchHotDecayLockPrefix = "product:search:hot:decay:lock"
+	productSearchSuggestLockPrefix  = "product:search:suggest:lock"
+
+	// 热度衰减后低于该值的关键字会被移除
+	hotKeywordMinScore = "0.1"
+	// 前缀索引成员中索引key与展示文本的分隔符
+	suggestSeparator = "\x00"
+	// 批量写入前缀索引时每批的数量
+	suggestBatchSize = 500
+)
+
+// IncrSearchKeyword 增加搜索关键字的热度
+func IncrSearchKeyword(ctx context.Context, keyword string) error {
+	if err := withContext(ctx).ZIncrBy(productSearchHotPrefix, 1, keyword).Err(); err != nil {
+		logger.Ctx(ctx).Error("增加搜索关键字热度失败", zap.String("keyword", keyword), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetHotKeywords 按照热度降序获取前count个热搜词
+func GetHotKeywords(ctx context.Context, count int64) ([]string, error) {
+	keywords, err := withContext(ctx).ZRevRange(productSearchHotPrefix, 0, count-1).Result()
+	if err != nil {
+		logger.Ctx(ctx).Error("获取热搜词失败", zap.Error(err))
+		return nil, err
+	}
+	return keywords, nil
+}
+
+// DecayHotKeywords 将所有热搜词的热度乘以factor，并移除热度过低的关键字。
+// 同一个周期内只有获取到锁的服务实例会执行，返回是否执行了衰减
+func DecayHotKeywords(ctx context.Context, factor float64, period time.Duration) (bool, error) {
+	ok, err := tryLock(ctx, productSearchHotDecayLockPrefix, period)
+	if err != nil || !ok {
+		return false, err
+	}
+	pipeline := withContext(ctx).TxPipeline()
+	pipeline.ZUnionStore(productSearchHotPrefix, redis.ZStore{Weights: []float64{factor}}, productSearchHotPrefix)
+	pipeline.ZRemRangeByScore(productSearchHotPrefix, "-inf", "("+hotKeywordMinScore)
+	if _, err = pipeline.Exec(); err != nil {
+		logger.Ctx(ctx).Error("衰减热搜词热度失败", zap.Error(err))
+		return false, err
+	}
+	return true, nil
+}
+
+// TryLockSuggestRebuild 获取重建前缀索引的锁，同一个周期内只有一个服务实例可以获取成功
+func TryLockSuggestRebuild(ctx context.Context, period time.Duration) (bool, error) {
+	return tryLock(ctx, productSearchSuggestLockPrefix, period)
+}
+
+// ReplaceSuggestIndex 使用新的前缀索引整体替换旧的前缀索引。entries的K为索引key，V为该key对应的展示文本
+func ReplaceSuggestIndex(ctx context.Context, entries map[string][]string) error {
+	client := withContext(ctx)
+	members := make([]redis.Z, 0, len(entries))
+	for key, displays := range entries {
+		for _, display := range displays {
+			members = append(members, redis.Z{Member: key + suggestSeparator + display})
+		}
+	}
+	if len(members) == 0 {
+		if err := client.Del(productSearchSuggestPrefix).Err(); err != nil {
+			logger.Ctx(ctx).Error("删除搜索补全前缀索引失败", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+
+	if err := client.Del(productSearchSuggestTmpPrefix).Err(); err != nil {
+		logger.Ctx(ctx).Error("删除搜索补全临时前缀索引失败", zap.Error(err))
+		return err
+	}
+	for start := 0; start < len(members); start += suggestBatchSize {
+		end := start + suggestBatchSize
+		if end > len(members) {
+			end = len(members)
+		}
+		if err := client.ZAdd(productSearchSuggestTmpPrefix, members[start:end]...).Err(); err != nil {
+			logger.Ctx(ctx).Error("写入搜索补全临时前缀索引失败", zap.Error(err))
+			return err
+		}
+	}
+	if err := client.Rename(productSearchSuggestTmpPrefix, productSearchSuggestPrefix).Err(); err != nil {
+		logger.Ctx(ctx).Error("替换搜索补全前缀索引失败", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetSuggestions 获取索引key以prefix开头的展示文本，最多返回count条(可能重复)
+func GetSuggestions(ctx context.Context, prefix string, count int64) ([]string, error) {
+	members, err := withContext(ctx).ZRangeByLex(productSearchSuggestPrefix, redis.ZRangeBy{
+		Min:   "[" + prefix,
+		Max:   "[" + prefix + "\xff",
+		Count: count,
+	}).Result()
+	if err != nil {
+		logger.Ctx(ctx).Error("查询搜索补全前缀索引失败", zap.String("prefix", prefix), zap.Error(err))
+		return nil, err
+	}
+	displays := make([]string, 0, len(members))
+	for _, member := range members {
+		if index := strings.Index(member, suggestSeparator); index != -1 {
+			displays = append(displays, member[index+len(suggestSeparator):])
+		}
+	}
+	return displays, nil
+}
+
+// AddBlockedKeyword 屏蔽搜索关键字，并将其从热搜词中移除
+func AddBlockedKeyword(ctx context.Context, keyword string) error {
+	pipeline := withContext(ctx).TxPipeline()
+	pipeline.SAdd(productSearchBlockedPrefix, keyword)
+	pipeline.ZRem(productSearchHotPrefix, keyword)
+	if _, err := pipeline.Exec(); err != nil {
+		logger.Ctx(ctx).Error("屏蔽搜索关键字失败", zap.String("keyword", keyword), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DelBlockedKeyword 取消屏蔽搜索关键字
+func DelBlockedKeyword(ctx context.Context, keyword string) error {
+	if err := withContext(ctx).SRem(productSearchBlockedPrefix, keyword).Err(); err != nil {
+		logger.Ctx(ctx).Error("取消屏蔽搜索关键字失败", zap.String("keyword", keyword), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetBlockedKeywords 获取所有被屏蔽的搜索关键字
+func GetBlockedKeywords(ctx context.Context) ([]string, error) {
+	keywords, err := withContext(ctx).SMembers(productSearchBlockedPrefix).Result()
+	if err != nil {
+		logger.Ctx(ctx).Error("获取被屏蔽的搜索关键字失败", zap.Error(err))
+		return nil, err
+	}
+	return keywords, nil
+}
+
+// tryLock 获取一个在period后自动过期的锁，获取成功返回true
+func tryLock(ctx context.Context, key string, period time.Duration) (bool, error) {
+	// 提前一秒过期，避免因为定时器的误差错过下一个周期
+	ttl := period - time.Second
+	if ttl <= 0 {
+		ttl = period
+	}
+	ok, err := withContext(ctx).SetNX(key, time.Now().Unix(), ttl).Result()
+	if err != nil {
+		logger.Ctx(ctx).Error("获取分布式锁失败", zap.String("key", key), zap.Error(err))
+		return false, err
+	}
+	return ok, nil
+}
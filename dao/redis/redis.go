@@ -22,6 +22,11 @@ func Init(cfg *settings.RedisConfig) (err error) {
 	return
 }
 
+// Ping 检查Redis连接是否可用
+func Ping() error {
+	return rdb.Ping().Err()
+}
+
 func Close() {
 	_ = rdb.Close()
 }
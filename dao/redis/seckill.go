@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"shop-backend/utils/concatstr"
 	"strconv"
 	"time"
@@ -12,7 +13,7 @@ var (
 )
 
 // SetNXSecKillUID 使用Redis SETNX命名将用户ID设置进Redis
-func SetNXSecKillUID(uid int64) bool {
+func SetNXSecKillUID(ctx context.Context, uid int64) bool {
 	key := concatstr.ConcatString(SecKillUIDPrefix, strconv.FormatInt(uid, 10))
-	return rdb.SetNX(key, nil, SecKillUIDivingTime).Val()
+	return withContext(ctx).SetNX(key, nil, SecKillUIDivingTime).Val()
 }
@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"shop-backend/tracing"
+	"strings"
+)
+
+// withContext 返回为每条命令创建span的客户端，ctx中没有链路时直接返回rdb
+func withContext(ctx context.Context) *redis.Client {
+	if !tracing.Traced(ctx) {
+		return rdb
+	}
+	// WithContext返回的是rdb的副本，包装副本的process不影响rdb
+	client := rdb.WithContext(ctx)
+	client.WrapProcess(func(oldProcess func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			_, span := startSpan(ctx, "redis "+cmd.Name(), cmd.Name())
+			err := oldProcess(cmd)
+			endSpan(span, err)
+			return err
+		}
+	})
+	client.WrapProcessPipeline(func(oldProcess func(cmds []redis.Cmder) error) func(cmds []redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			names := make([]string, 0, len(cmds))
+			for _, cmd := range cmds {
+				names = append(names, cmd.Name())
+			}
+			_, span := startSpan(ctx, "redis pipeline", strings.Join(names, " "))
+			span.SetAttributes(attribute.Int("db.redis.num_cmd", len(cmds)))
+			err := oldProcess(cmds)
+			endSpan(span, err)
+			return err
+		}
+	})
+	return client
+}
+
+// startSpan 创建命令的span，只记录命令名称，不记录参数
+func startSpan(ctx context.Context, name, statement string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis, semconv.DBStatementKey.String(statement)))
+}
+
+// endSpan 结束span，key不存在(redis.Nil)不视为错误
+func endSpan(span trace.Span, err error) {
+	if err == redis.Nil {
+		err = nil
+	}
+	tracing.End(span, err)
+}
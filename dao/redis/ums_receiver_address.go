@@ -1,8 +1,10 @@
 package redis
 
 import (
+	"context"
 	"encoding/json"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/models/vo"
 	"time"
 )
@@ -13,17 +15,17 @@ var (
 )
 
 // GetAllAddress 从Redis中获取所有地址
-func GetAllAddress() ([]*vo.PCDDicVO, error) {
-	str, err := rdb.Get(usersReceiverAddressPrefix).Result()
+func GetAllAddress(ctx context.Context) ([]*vo.PCDDicVO, error) {
+	str, err := withContext(ctx).Get(usersReceiverAddressPrefix).Result()
 	if err != nil {
-		zap.L().Error("从Redis中获取所有地址缓存失败", zap.Error(err))
+		logger.Ctx(ctx).Error("从Redis中获取所有地址缓存失败", zap.Error(err))
 		return nil, err
 	}
 
 	data := make([]*vo.PCDDicVO, 0)
 	err = json.Unmarshal([]byte(str), &data)
 	if err != nil {
-		zap.L().Error("反序列化地址信息失败", zap.Error(err))
+		logger.Ctx(ctx).Error("反序列化地址信息失败", zap.Error(err))
 		return nil, err
 	}
 
@@ -31,10 +33,10 @@ func GetAllAddress() ([]*vo.PCDDicVO, error) {
 }
 
 // SetAllAddress 将所有的地址添加到Redis缓存中
-func SetAllAddress(data []*vo.PCDDicVO) error {
+func SetAllAddress(ctx context.Context, data []*vo.PCDDicVO) error {
 	dataJson, _ := json.Marshal(data)
-	if err := rdb.Set(usersReceiverAddressPrefix, dataJson, usersReceiverAddressLivingTime).Err(); err != nil {
-		zap.L().Error("将所有的地址添加到Redis缓存失败", zap.Error(err))
+	if err := withContext(ctx).Set(usersReceiverAddressPrefix, dataJson, usersReceiverAddressLivingTime).Err(); err != nil {
+		logger.Ctx(ctx).Error("将所有的地址添加到Redis缓存失败", zap.Error(err))
 		return err
 	}
 	return nil
@@ -1,8 +1,10 @@
 package redis
 
 import (
+	"context"
 	"github.com/go-redis/redis"
 	"go.uber.org/zap"
+	"shop-backend/logger"
 	"shop-backend/utils/concatstr"
 	"shop-backend/utils/gen"
 	"strconv"
@@ -17,19 +19,19 @@ var (
 )
 
 // SetVerifyCode 将手机验证码存入Redis中，有效期五分钟
-func SetVerifyCode(phone, code string) (err error) {
+func SetVerifyCode(ctx context.Context, phone, code string) (err error) {
 	key := concatstr.ConcatString(userPrefix, verifyStr, phone)
-	if err = rdb.Set(key, code, codeLivingTime).Err(); err != nil {
-		zap.L().Error("SetAccessToken failed", zap.Error(err))
+	if err = withContext(ctx).Set(key, code, codeLivingTime).Err(); err != nil {
+		logger.Ctx(ctx).Error("SetAccessToken failed", zap.Error(err))
 		return
 	}
 	return
 }
 
 // GetVerifyCode 通过手机号获取验证码
-func GetVerifyCode(phone string) (code string, err error) {
+func GetVerifyCode(ctx context.Context, phone string) (code string, err error) {
 	key := concatstr.ConcatString(userPrefix, verifyStr, phone)
-	if code, err = rdb.Get(key).Result(); err != nil {
+	if code, err = withContext(ctx).Get(key).Result(); err != nil {
 		// 验证码已过期或不存在
 		if err == redis.Nil {
 			err = nil
@@ -40,19 +42,19 @@ func GetVerifyCode(phone string) (code string, err error) {
 }
 
 // SetAccessToken 将AccessToken存入Redis中 K: userID V: aToken
-func SetAccessToken(userID int64, aToken string) (err error) {
+func SetAccessToken(ctx context.Context, userID int64, aToken string) (err error) {
 	key := concatstr.ConcatString(userPrefix, tokenStr, strconv.FormatInt(userID, 10))
-	if err = rdb.Set(key, aToken, gen.ATokenExpireDuration).Err(); err != nil {
-		zap.L().Error("SetAccessToken failed", zap.Error(err))
+	if err = withContext(ctx).Set(key, aToken, gen.ATokenExpireDuration).Err(); err != nil {
+		logger.Ctx(ctx).Error("SetAccessToken failed", zap.Error(err))
 		return
 	}
 	return
 }
 
 // GetAccessTokenByUID 通过userID从Redis中获取对应的Access Token
-func GetAccessTokenByUID(uid string) (token string, err error) {
+func GetAccessTokenByUID(ctx context.Context, uid string) (token string, err error) {
 	key := concatstr.ConcatString(userPrefix, tokenStr, uid)
-	token, err = rdb.Get(key).Result()
+	token, err = withContext(ctx).Get(key).Result()
 	if err != nil {
 		return
 	}
@@ -60,9 +62,9 @@ func GetAccessTokenByUID(uid string) (token string, err error) {
 }
 
 // DelAccessTokenByUID 根据uid删除AccessToken
-func DelAccessTokenByUID(idStr string) (err error) {
+func DelAccessTokenByUID(ctx context.Context, idStr string) (err error) {
 	key := concatstr.ConcatString(userPrefix, tokenStr, idStr)
-	_, err = rdb.Del(key).Result()
+	_, err = withContext(ctx).Del(key).Result()
 	if err != nil {
 		if err == redis.Nil {
 			err = nil
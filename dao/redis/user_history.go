@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/utils/concatstr"
+	"strconv"
+	"time"
+)
+
+var (
+	historyStr       = "history:"
+	searchHistoryStr = "search:"
+	browseHistoryStr = "browse:"
+	// 搜索历史、浏览历史最多保存的条数
+	searchHistoryLimit int64 = 20
+	browseHistoryLimit int64 = 50
+	// 用户长时间未产生新的历史记录时自动过期
+	historyLivingTime = time.Hour * 24 * 90
+)
+
+// AddSearchHistory 记录用户的搜索关键字
+func AddSearchHistory(ctx context.Context, uid int64, keyword string) error {
+	return addHistory(ctx, searchHistoryKey(uid), keyword, searchHistoryLimit)
+}
+
+// GetSearchHistory 获取用户的搜索历史，按照时间降序
+func GetSearchHistory(ctx context.Context, uid int64) ([]string, error) {
+	return getHistory(ctx, searchHistoryKey(uid))
+}
+
+// DelSearchHistory 清空用户的搜索历史
+func DelSearchHistory(ctx context.Context, uid int64) error {
+	return delHistory(ctx, searchHistoryKey(uid))
+}
+
+// AddBrowseHistory 记录用户浏览的商品skuID
+func AddBrowseHistory(ctx context.Context, uid, skuID int64) error {
+	return addHistory(ctx, browseHistoryKey(uid), strconv.FormatInt(skuID, 10), browseHistoryLimit)
+}
+
+// GetBrowseHistory 获取用户浏览过的商品skuID，按照时间降序
+func GetBrowseHistory(ctx context.Context, uid int64) ([]int64, error) {
+	values, err := getHistory(ctx, browseHistoryKey(uid))
+	if err != nil {
+		return nil, err
+	}
+	skuIDs := make([]int64, 0, len(values))
+	for _, value := range values {
+		skuID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		skuIDs = append(skuIDs, skuID)
+	}
+	return skuIDs, nil
+}
+
+// DelBrowseHistory 清空用户的浏览历史
+func DelBrowseHistory(ctx context.Context, uid int64) error {
+	return delHistory(ctx, browseHistoryKey(uid))
+}
+
+// addHistory 将value插入到历史记录列表头部。列表中已存在的相同记录会先被移除，保证去重；超出limit的旧记录会被裁剪
+func addHistory(ctx context.Context, key, value string, limit int64) error {
+	pipeline := withContext(ctx).TxPipeline()
+	pipeline.LRem(key, 0, value)
+	pipeline.LPush(key, value)
+	pipeline.LTrim(key, 0, limit-1)
+	pipeline.Expire(key, historyLivingTime)
+	if _, err := pipeline.Exec(); err != nil {
+		logger.Ctx(ctx).Error("记录用户历史失败", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// getHistory 获取历史记录列表
+func getHistory(ctx context.Context, key string) ([]string, error) {
+	values, err := withContext(ctx).LRange(key, 0, -1).Result()
+	if err != nil {
+		logger.Ctx(ctx).Error("获取用户历史失败", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+	return values, nil
+}
+
+// delHistory 删除历史记录列表
+func delHistory(ctx context.Context, key string) error {
+	if err := withContext(ctx).Del(key).Err(); err != nil {
+		logger.Ctx(ctx).Error("清空用户历史失败", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func searchHistoryKey(uid int64) string {
+	return concatstr.ConcatString(userPrefix, historyStr, searchHistoryStr, strconv.FormatInt(uid, 10))
+}
+
+func browseHistoryKey(uid int64) string {
+	return concatstr.ConcatString(userPrefix, historyStr, browseHistoryStr, strconv.FormatInt(uid, 10))
+}
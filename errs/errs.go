@@ -0,0 +1,77 @@
+package errs
+
+import "errors"
+
+// Kind 领域错误的类型，controller根据类型返回对应的HTTP状态码和错误码
+type Kind int
+
+const (
+	// KindInternal 服务内部错误，例如数据库、缓存不可用。没有类型的错误都视为内部错误
+	KindInternal Kind = iota
+	// KindNotFound 请求的资源不存在
+	KindNotFound
+	// KindConflict 资源当前的状态不允许这次操作，例如重复评价、删除仍被使用的分类
+	KindConflict
+	// KindInsufficientStock 商品已下架或者购买数量超过库存
+	KindInsufficientStock
+	// KindForbidden 没有权限操作这个资源
+	KindForbidden
+	// KindValidation 请求参数不合法
+	KindValidation
+	// KindUnavailable 依赖的服务暂时不可用，例如RabbitMQ未连接，稍后重试可能成功
+	KindUnavailable
+)
+
+// Error 带有类型的领域错误。一般定义为包级变量，调用方使用errors.Is判断具体的错误
+type Error struct {
+	Kind Kind
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// New 创建kind类型的领域错误
+func New(kind Kind, msg string) error {
+	return &Error{Kind: kind, Msg: msg}
+}
+
+// NotFound 资源不存在
+func NotFound(msg string) error {
+	return New(KindNotFound, msg)
+}
+
+// Conflict 资源状态冲突
+func Conflict(msg string) error {
+	return New(KindConflict, msg)
+}
+
+// InsufficientStock 商品已下架或者库存不足
+func InsufficientStock(msg string) error {
+	return New(KindInsufficientStock, msg)
+}
+
+// Forbidden 没有权限
+func Forbidden(msg string) error {
+	return New(KindForbidden, msg)
+}
+
+// Validation 参数不合法
+func Validation(msg string) error {
+	return New(KindValidation, msg)
+}
+
+// Unavailable 依赖的服务暂时不可用
+func Unavailable(msg string) error {
+	return New(KindUnavailable, msg)
+}
+
+// KindOf 获取err的类型，err被fmt.Errorf("%w")包装过也可以获取。不是领域错误时返回KindInternal
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
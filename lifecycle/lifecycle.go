@@ -0,0 +1,124 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Phase 关闭阶段，服务退出时按照阶段从小到大依次关闭，同一阶段与defer一样按照注册的相反顺序关闭
+type Phase int
+
+const (
+	// PhaseIntake 停止接收新的请求和数据：HTTP服务、canal、后台定时任务
+	PhaseIntake Phase = iota
+	// PhaseDrain 等待正在处理的消息处理完成：消息消费者
+	PhaseDrain
+	// PhaseFlush 发送尚未发送的消息：发件箱中继
+	PhaseFlush
+	// PhaseClose 关闭外部连接：RabbitMQ
+	PhaseClose
+	// PhaseStorage 关闭存储：Redis、MySQL
+	PhaseStorage
+)
+
+var ErrorShutdownTimeout = errors.New("关闭超时")
+
+// hook 一个需要在服务退出时关闭的子系统
+type hook struct {
+	name  string
+	phase Phase
+	stop  func(ctx context.Context) error
+}
+
+var (
+	mu       sync.Mutex
+	hooks    []*hook
+	shutdown sync.Once
+)
+
+// Register 注册子系统的关闭函数，stop需要在ctx结束前返回
+func Register(name string, phase Phase, stop func(ctx context.Context) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, &hook{name: name, phase: phase, stop: stop})
+}
+
+// Go 启动一个后台任务，run需要在ctx取消后尽快返回。
+// 关闭时取消ctx并等待run返回
+func Go(name string, phase Phase, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+	Register(name, phase, func(stopCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return ErrorShutdownTimeout
+		}
+	})
+}
+
+// Shutdown 按照阶段顺序关闭所有子系统，所有子系统共享timeout。
+// 某个子系统关闭失败或超时不影响后续子系统的关闭，只会执行一次
+func Shutdown(timeout time.Duration) (err error) {
+	shutdown.Do(func() {
+		mu.Lock()
+		ordered := make([]*hook, 0, len(hooks))
+		for i := len(hooks) - 1; i >= 0; i-- {
+			ordered = append(ordered, hooks[i])
+		}
+		mu.Unlock()
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].phase < ordered[j].phase
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		failed := 0
+		for _, h := range ordered {
+			start := time.Now()
+			if e := stopHook(ctx, h); e != nil {
+				failed++
+				zap.L().Error("关闭失败", zap.String("name", h.name), zap.Error(e))
+				continue
+			}
+			zap.L().Info("已关闭", zap.String("name", h.name), zap.Duration("elapsed", time.Since(start)))
+		}
+		if failed > 0 {
+			err = fmt.Errorf("%d个子系统关闭失败", failed)
+		}
+	})
+	return
+}
+
+// stopHook 执行关闭函数。超时后ctx已经结束，后续的子系统不再等待，直接释放资源
+func stopHook(ctx context.Context, h *hook) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.stop(ctx)
+}
+
+// Sleep 等待d，ctx结束时提前返回false，用于后台任务的循环
+func Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
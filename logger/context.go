@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/baggage"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader 请求ID的请求头、响应头
+const RequestIDHeader = "X-Request-ID"
+
+// 请求ID在baggage中的key，链路信息写入消息头时请求ID随baggage一起传递给消费者
+const requestIDKey = "request_id"
+
+type loggerKey struct{}
+
+// WithRequestID 将请求ID放入ctx，之后通过Ctx(ctx)获取的日志都会带有请求ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if member, err := baggage.NewMember(requestIDKey, requestID); err == nil {
+		if bag, err := baggage.FromContext(ctx).SetMember(member); err == nil {
+			ctx = baggage.ContextWithBaggage(ctx, bag)
+		}
+	}
+	return With(ctx, zap.String(requestIDKey, requestID))
+}
+
+// With 为ctx中的日志增加字段，例如鉴权之后的用户ID
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	return context.WithValue(ctx, loggerKey{}, Ctx(ctx).With(fields...))
+}
+
+// Ctx 获取ctx中带有请求ID、用户ID的日志，ctx中没有日志时返回全局日志。
+// 从消息头中恢复的ctx只有baggage中的请求ID，第一次获取时创建带有请求ID的日志
+func Ctx(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return zap.L()
+	}
+	if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return l
+	}
+	if requestID := RequestID(ctx); requestID != "" {
+		return zap.L().With(zap.String(requestIDKey, requestID))
+	}
+	return zap.L()
+}
+
+// RequestID 获取ctx中的请求ID，没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	return baggage.FromContext(ctx).Member(requestIDKey).Value()
+}
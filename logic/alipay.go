@@ -1,6 +1,7 @@
 package logic
 
 import (
+	"context"
 	"github.com/shopspring/decimal"
 	"shop-backend/dao/mysql"
 	"shop-backend/utils/pay"
@@ -8,9 +9,9 @@ import (
 )
 
 // CreateAlipayOrder 根据订单号和用户ID查询用户订单金额，并调用支付宝进行支付
-func CreateAlipayOrder(uid, orderNum int64) (string, error) {
+func CreateAlipayOrder(ctx context.Context, uid, orderNum int64) (string, error) {
 	// 获取订单信息
-	order, err := mysql.SelectOneOrderByUIDAndOrderNum(uid, orderNum)
+	order, err := mysql.SelectOneOrderByUIDAndOrderNum(ctx, uid, orderNum)
 	if err != nil {
 		return "", err
 	}
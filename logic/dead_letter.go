@@ -0,0 +1,41 @@
+package logic
+
+import (
+	"math"
+	"shop-backend/models/dto"
+	"shop-backend/models/vo"
+	"shop-backend/rabbitmq"
+)
+
+// 单次查看死信消息的最大数量
+const maxPeekDeadLetters = 100
+
+// GetDeadLetterQueues 获取所有死信队列以及其中的消息数量
+func GetDeadLetterQueues() ([]*vo.DeadLetterQueueVO, error) {
+	return rabbitmq.ListDeadLetterQueues()
+}
+
+// PeekDeadLetters 查看死信队列中的前count条消息
+func PeekDeadLetters(queue string, count int) ([]*vo.DeadLetterVO, error) {
+	if count <= 0 {
+		count = 10
+	}
+	if count > maxPeekDeadLetters {
+		count = maxPeekDeadLetters
+	}
+	return rabbitmq.PeekDeadLetters(queue, count)
+}
+
+// ReplayDeadLetters 将死信队列中的消息重新发送到原队列，返回重新发送的消息数量
+func ReplayDeadLetters(replay *dto.DeadLetterReplay) (int, error) {
+	count := replay.Count
+	if count == 0 {
+		count = math.MaxInt32
+	}
+	return rabbitmq.ReplayDeadLetters(replay.Queue, count)
+}
+
+// PurgeDeadLetters 清空死信队列，返回删除的消息数量
+func PurgeDeadLetters(queue string) (int, error) {
+	return rabbitmq.PurgeDeadLetters(queue)
+}
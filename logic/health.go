@@ -0,0 +1,88 @@
+package logic
+
+import (
+	"errors"
+	"shop-backend/canal"
+	"shop-backend/dao/mysql"
+	"shop-backend/dao/redis"
+	"shop-backend/models/vo"
+	"shop-backend/rabbitmq"
+	"shop-backend/utils/pay"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// 每个依赖检查的超时时间，超时视为不可用
+const dependencyCheckTimeout = 2 * time.Second
+
+var ErrorCheckTimeout = errors.New("检查超时")
+
+// dependencyChecks 就绪检查的依赖。RabbitMQ、canal、支付模块在后台初始化，初始化完成前检查失败
+var dependencyChecks = []struct {
+	name  string
+	check func() error
+}{
+	{"mysql", mysql.Ping},
+	{"redis", redis.Ping},
+	{"rabbitmq", rabbitmq.Ping},
+	{"canal", canal.Health},
+	{"pay", func() error {
+		if !pay.Ready() {
+			return errors.New("支付模块未初始化")
+		}
+		return nil
+	}},
+}
+
+// CheckReadiness 并发检查所有依赖，返回每个依赖的状态、耗时以及失败原因
+func CheckReadiness() *vo.ReadinessVO {
+	readiness := &vo.ReadinessVO{
+		Status:       StatusUp,
+		Dependencies: make([]*vo.DependencyStatusVO, len(dependencyChecks)),
+	}
+	var wg sync.WaitGroup
+	for i, dependency := range dependencyChecks {
+		wg.Add(1)
+		go func(i int, name string, check func() error) {
+			defer wg.Done()
+			readiness.Dependencies[i] = checkDependency(name, check)
+		}(i, dependency.name, dependency.check)
+	}
+	wg.Wait()
+	for _, status := range readiness.Dependencies {
+		if status.Status != StatusUp {
+			readiness.Status = StatusDown
+		}
+	}
+	return readiness
+}
+
+// checkDependency 检查一个依赖，超过dependencyCheckTimeout未返回时视为不可用
+func checkDependency(name string, check func() error) *vo.DependencyStatusVO {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check()
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(dependencyCheckTimeout):
+		err = ErrorCheckTimeout
+	}
+	status := &vo.DependencyStatusVO{
+		Name:      name,
+		Status:    StatusUp,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
@@ -1,10 +1,13 @@
 package logic
 
 import (
+	"context"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 	"shop-backend/dao/mysql"
 	"shop-backend/dao/redis"
+	"shop-backend/logger"
+	"shop-backend/metrics"
 	"shop-backend/models/dto"
 	"shop-backend/models/pojo"
 	"shop-backend/models/vo"
@@ -18,7 +21,7 @@ import (
 // 2. 判断预提交订单中的商品是否已经下架
 // 3. 判断预提交订单中的商品购买数量是否大于库存
 // 4. 计算出订单应付款 = 总金额 + 运费
-func CreatePreSubmitOrder(preSubmitOrder *dto.PreSubmitOrder, uid int64) (*vo.OrderVO, error) {
+func CreatePreSubmitOrder(ctx context.Context, preSubmitOrder *dto.PreSubmitOrder, uid int64) (*vo.OrderVO, error) {
 	// 要返回的订单展示对象
 	orderVO := new(vo.OrderVO)
 	// 生成全局唯一订单号
@@ -31,14 +34,14 @@ func CreatePreSubmitOrder(preSubmitOrder *dto.PreSubmitOrder, uid int64) (*vo.Or
 	for _, cartProduct := range preSubmitOrder.CartProductList {
 		// 校验商品是否下架
 		// 校验商品购买数量是否大于库存
-		cartPojo, sku, err := mysql.CheckOrderProduct(cartProduct, uid)
+		cartPojo, sku, err := mysql.CheckOrderProduct(ctx, cartProduct, uid)
 		if err != nil {
-			zap.L().Error("商品已下架或购买数量超过库存", zap.Error(err))
+			logger.Ctx(ctx).Error("商品已下架或购买数量超过库存", zap.Error(err))
 			return nil, err
 		}
 
 		// 构建订单(购物车)商品展示对象
-		cartProductVO := build.CreateCartProductVO(cartPojo, channel)
+		cartProductVO := build.CreateCartProductVO(ctx, cartPojo, channel)
 		// 添加到订单展示对象中
 		orderVO.CartProductVOList = append(orderVO.CartProductVOList, cartProductVO)
 
@@ -51,7 +54,7 @@ func CreatePreSubmitOrder(preSubmitOrder *dto.PreSubmitOrder, uid int64) (*vo.Or
 		totalMoney = totalMoney.Add(price.Mul(count))
 	}
 
-	zap.L().Info("totalMoney", zap.String("totalMoney", totalMoney.String()))
+	logger.Ctx(ctx).Info("totalMoney", zap.String("totalMoney", totalMoney.String()))
 	// 运费为18元
 	freight := decimal.NewFromFloat(18)
 
@@ -63,7 +66,7 @@ func CreatePreSubmitOrder(preSubmitOrder *dto.PreSubmitOrder, uid int64) (*vo.Or
 	orderVO.PayMoney = payMoney.String()
 
 	// 将订单编号设置进Redis，并设置5分钟的失效时间。实现提交订单幂等性和限流
-	err := redis.SetOrderNumber(orderVO.OrderNumber)
+	err := redis.SetOrderNumber(ctx, orderVO.OrderNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -77,35 +80,38 @@ func CreatePreSubmitOrder(preSubmitOrder *dto.PreSubmitOrder, uid int64) (*vo.Or
 // 4. 生成订单明细
 // 5. 清空购物车
 // 6. 失败后或者未支付回滚库存
-func CreateSubmitOrder(orderDTO *dto.Order, uid, orderNum int64) error {
-	// 生成订单 && 校验库存和商品状态 && 生成订单明细
-	err := mysql.CreateOrderAndOrderItem(orderDTO, uid, orderNum)
+func CreateSubmitOrder(ctx context.Context, orderDTO *dto.Order, uid, orderNum int64) error {
+	messages := []*pojo.OutboxMessage{
+		// 异步清除购物车
+		rabbitmq.NewCartDelOutboxMessage(ctx, &dto.CartProductListDTO{
+			UserID:          uid,
+			CartProductList: orderDTO.CartProductList,
+		}),
+		// 订单超时未支付后回滚库存并将订单状态改成超时未支付
+		rabbitmq.NewDelayOrderOutboxMessage(ctx, orderNum),
+	}
+	// 生成订单 && 校验库存和商品状态 && 生成订单明细 && 消息写入发件箱
+	err := mysql.CreateOrderAndOrderItem(ctx, orderDTO, uid, orderNum, messages)
 	if err != nil {
 		return err
 	}
-
-	// 异步清除购物车
-	go rabbitmq.SendCartDelMess2MQ(&dto.CartProductListDTO{
-		UserID:          uid,
-		CartProductList: orderDTO.CartProductList,
-	})
-
-	// 订单超时未支付后回滚库存并将订单状态改成超时未支付
-	go rabbitmq.SendDelayOrderMess2MQ(orderNum)
+	// 通知发件箱中继立即发送
+	rabbitmq.NotifyOutbox()
+	metrics.OrderSubmitted()
 	return nil
 }
 
 // GetAllOrder 返回用户所有订单
-func GetAllOrder(uid int64) ([]*pojo.Order, error) {
-	return mysql.SelectAllOrder(uid)
+func GetAllOrder(ctx context.Context, uid int64) ([]*pojo.Order, error) {
+	return mysql.SelectAllOrder(ctx, uid)
 }
 
 // GetOneOrderItem 返回一条订单的明细信息
-func GetOneOrderItem(id int64) ([]*pojo.OrderItem, error) {
-	return mysql.SelectOneOrderItem(id)
+func GetOneOrderItem(ctx context.Context, id int64) ([]*pojo.OrderItem, error) {
+	return mysql.SelectOneOrderItem(ctx, id)
 }
 
 // DelOrder 删除一条订单记录
-func DelOrder(id int64) error {
-	return mysql.DelOrderAndItems(id)
+func DelOrder(ctx context.Context, id int64) error {
+	return mysql.DelOrderAndItems(ctx, id)
 }
@@ -1,26 +1,32 @@
 package logic
 
 import (
+	"context"
+	"errors"
 	"shop-backend/dao/mysql"
+	"shop-backend/errs"
+	"shop-backend/models/dto"
+	"shop-backend/models/pojo"
 	"shop-backend/models/vo"
+	"strconv"
 )
 
-// GetAllAttribute 获取商品二级分类的所有属性
-func GetAllAttribute(categoryID int64) ([]*vo.AttributeVO, error) {
-	// category和attribute对应表集合
-	caRels, err := mysql.SelectAttrIDeByCategoryID(categoryID)
+var ErrorAttributeNotInCategory = errs.Validation("属性值不属于商品所在的分类")
+
+// GetAllAttribute 获取商品分类的所有属性，包括从祖先分类继承的属性
+func GetAllAttribute(ctx context.Context, categoryID int64) ([]*vo.AttributeVO, error) {
+	// 商品属性ID Set集合
+	attrIDSet, err := getCategoryAttrIDSet(ctx, categoryID)
 	if err != nil {
 		return nil, err
 	}
-	// 商品属性ID Set集合
-	attrIDSet := make(map[int64]bool)
-	for _, caRel := range caRels {
-		attrIDSet[caRel.ProductAttributeID] = true
-	}
 
 	result := make([]*vo.AttributeVO, 0)
 	// 获取所有商品属性
-	attrs, err := mysql.SelectAllAttribute()
+	attrs, err := mysql.SelectAllAttribute(ctx)
+	if err != nil {
+		return nil, err
+	}
 	for _, attr := range attrs {
 		if attrIDSet[attr.ID] {
 			// 如果Set集合中有这一条记录
@@ -44,3 +50,176 @@ func GetAllAttribute(categoryID int64) ([]*vo.AttributeVO, error) {
 
 	return result, nil
 }
+
+// GetAttributeWithCount 获取商品分类的所有属性，并统计每个属性值下符合当前搜索条件的商品数量
+func GetAttributeWithCount(ctx context.Context, categoryID int64, condition *dto.SearchCondition) ([]*vo.AttributeVO, error) {
+	attributes, err := GetAllAttribute(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	condition.ProductCategoryId = strconv.FormatInt(categoryID, 10)
+	noMatch, err := prepareCondition(ctx, condition)
+	if err != nil {
+		return nil, err
+	}
+	if noMatch {
+		// 没有符合搜索条件的商品，所有属性值的商品数量都为0
+		return attributes, nil
+	}
+	facets, err := mysql.SelectAttributeValueFacets(ctx, condition)
+	if err != nil {
+		return nil, err
+	}
+	// K: 属性值ID V: 商品数量
+	countMap := make(map[int64]int64, len(facets))
+	for _, facet := range facets {
+		countMap[facet.ValueID] = facet.Count
+	}
+	for _, attribute := range attributes {
+		for _, value := range attribute.AttributeValues {
+			value.Count = countMap[value.ValueID]
+		}
+	}
+	return attributes, nil
+}
+
+// GetAdminAttributeTree 获取所有属性名以及属性值，用于后台管理
+func GetAdminAttributeTree(ctx context.Context) ([]*vo.AdminAttributeVO, error) {
+	attrs, err := mysql.SelectAllAttribute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// K: 属性名ID V: 属性名
+	groupMap := make(map[int64]*vo.AdminAttributeVO)
+	result := make([]*vo.AdminAttributeVO, 0)
+	for _, attr := range attrs {
+		if attr.ParentID == 0 {
+			group := buildAdminAttributeVO(attr)
+			group.Values = make([]*vo.AdminAttributeVO, 0)
+			groupMap[attr.ID] = group
+			result = append(result, group)
+		}
+	}
+	for _, attr := range attrs {
+		if group, ok := groupMap[attr.ParentID]; ok {
+			group.Values = append(group.Values, buildAdminAttributeVO(attr))
+		}
+	}
+	return result, nil
+}
+
+// AddAttribute 新增属性名或属性值
+func AddAttribute(ctx context.Context, attribute *dto.Attribute) error {
+	if attribute.ParentID != 0 {
+		// 属性值的父属性必须是属性名
+		parent, err := mysql.SelectAttributeByID(ctx, attribute.ParentID)
+		if err != nil {
+			if errors.Is(err, mysql.ErrorAttributeNotExist) {
+				return mysql.ErrorAttributeInvalidParent
+			}
+			return err
+		}
+		if parent.ParentID != 0 {
+			return mysql.ErrorAttributeInvalidParent
+		}
+	}
+	return mysql.InsertAttribute(ctx, &pojo.ProductAttribute{
+		Type:     attribute.Type,
+		ParentID: attribute.ParentID,
+		Name:     attribute.Name,
+		Sort:     attribute.Sort,
+	})
+}
+
+// UpdateAttribute 修改属性名或属性值
+func UpdateAttribute(ctx context.Context, attribute *dto.Attribute) error {
+	id, err := strconv.ParseInt(attribute.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+	if _, err = mysql.SelectAttributeByID(ctx, id); err != nil {
+		return err
+	}
+	return mysql.UpdateAttribute(ctx, &pojo.ProductAttribute{
+		ID:   id,
+		Type: attribute.Type,
+		Name: attribute.Name,
+		Sort: attribute.Sort,
+	})
+}
+
+// DelAttribute 删除属性名或属性值
+func DelAttribute(ctx context.Context, id int64) error {
+	return mysql.DelAttribute(ctx, id)
+}
+
+// AssignSpuAttribute 为商品spu分配属性值，属性值所属的属性名必须关联在spu所在分类或其祖先分类上
+func AssignSpuAttribute(ctx context.Context, spuID int64, attributeIDs []int64) error {
+	spu, err := mysql.SelectSpuByID(ctx, spuID)
+	if err != nil {
+		return err
+	}
+	attrIDSet, err := getCategoryAttrIDSet(ctx, spuCategoryID(spu))
+	if err != nil {
+		return err
+	}
+
+	// 属性值ID去重
+	uniqueIDs := make([]int64, 0, len(attributeIDs))
+	seen := make(map[int64]bool, len(attributeIDs))
+	for _, id := range attributeIDs {
+		if !seen[id] {
+			seen[id] = true
+			uniqueIDs = append(uniqueIDs, id)
+		}
+	}
+	attrs, err := mysql.SelectAttributeByIDs(ctx, uniqueIDs)
+	if err != nil {
+		return err
+	}
+	if len(attrs) != len(uniqueIDs) {
+		return mysql.ErrorAttributeNotExist
+	}
+	for _, attr := range attrs {
+		if attr.ParentID == 0 || !attrIDSet[attr.ParentID] {
+			// 不是属性值，或者属性值所属的属性名不属于spu所在的分类
+			return ErrorAttributeNotInCategory
+		}
+	}
+	return mysql.ReplaceSpuAttributeRel(ctx, spuID, uniqueIDs)
+}
+
+// getCategoryAttrIDSet 获取商品分类以及祖先分类关联的所有属性名ID
+func getCategoryAttrIDSet(ctx context.Context, categoryID int64) (map[int64]bool, error) {
+	categoryIDs := []int64{categoryID}
+	breadcrumbs, err := GetCategoryBreadcrumbs(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	for _, category := range breadcrumbs {
+		if category.ID != categoryID {
+			categoryIDs = append(categoryIDs, category.ID)
+		}
+	}
+	// category和attribute对应表集合
+	caRels, err := mysql.SelectAttrIDeByCategoryIDs(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	attrIDSet := make(map[int64]bool, len(caRels))
+	for _, caRel := range caRels {
+		attrIDSet[caRel.ProductAttributeID] = true
+	}
+	return attrIDSet, nil
+}
+
+// buildAdminAttributeVO 将商品属性pojo转换为vo
+func buildAdminAttributeVO(attr *pojo.ProductAttribute) *vo.AdminAttributeVO {
+	return &vo.AdminAttributeVO{
+		ID:       attr.ID,
+		Type:     attr.Type,
+		ParentID: attr.ParentID,
+		Name:     attr.Name,
+		Sort:     attr.Sort,
+	}
+}
@@ -0,0 +1,105 @@
+package logic
+
+import (
+	"context"
+	"shop-backend/dao/mysql"
+	"shop-backend/models/dto"
+	"shop-backend/models/pojo"
+	"shop-backend/models/vo"
+	"strconv"
+)
+
+// GetAllBrand 获取所有品牌以及品牌所属的分类，用于后台管理
+func GetAllBrand(ctx context.Context) ([]*vo.BrandVO, error) {
+	brands, err := mysql.SelectAllBrand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	brandIDs := make([]int64, 0, len(brands))
+	for _, brand := range brands {
+		brandIDs = append(brandIDs, brand.ID)
+	}
+	rels, err := mysql.SelectCategoryBrandRelByBrandIDs(ctx, brandIDs)
+	if err != nil {
+		return nil, err
+	}
+	// K: 品牌ID V: 品牌所属分类ID集合
+	categoryMap := make(map[int64][]int64)
+	for _, rel := range rels {
+		categoryMap[rel.BrandID] = append(categoryMap[rel.BrandID], rel.ProductCategoryID)
+	}
+
+	data := make([]*vo.BrandVO, 0, len(brands))
+	for _, brand := range brands {
+		brandVO := buildBrandVO(brand)
+		if ids, ok := categoryMap[brand.ID]; ok {
+			brandVO.ProductCategoryIds = ids
+		}
+		data = append(data, brandVO)
+	}
+	return data, nil
+}
+
+// GetBrandByCategoryID 获取商品分类下所有显示状态的品牌
+func GetBrandByCategoryID(ctx context.Context, categoryID int64) ([]*vo.BrandVO, error) {
+	brands, err := mysql.SelectShowBrandByCategoryID(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]*vo.BrandVO, 0, len(brands))
+	for _, brand := range brands {
+		data = append(data, buildBrandVO(brand))
+	}
+	return data, nil
+}
+
+// AddBrand 新增品牌
+func AddBrand(ctx context.Context, brand *dto.Brand) error {
+	return mysql.InsertBrand(ctx, createBrandPojo(brand), brand.ProductCategoryIds)
+}
+
+// UpdateBrand 修改品牌
+func UpdateBrand(ctx context.Context, brand *dto.Brand) error {
+	brandPojo := createBrandPojo(brand)
+	id, err := strconv.ParseInt(brand.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+	// 判断品牌是否存在
+	if _, err = mysql.SelectBrandByID(ctx, id); err != nil {
+		return err
+	}
+	brandPojo.ID = id
+	return mysql.UpdateBrand(ctx, brandPojo, brand.ProductCategoryIds)
+}
+
+// DelBrand 删除品牌
+func DelBrand(ctx context.Context, id int64) error {
+	return mysql.DelBrand(ctx, id)
+}
+
+// createBrandPojo 将品牌dto转换为pojo
+func createBrandPojo(brand *dto.Brand) *pojo.Brand {
+	return &pojo.Brand{
+		Name:        brand.Name,
+		FirstLetter: brand.FirstLetter,
+		Logo:        brand.Logo,
+		Description: brand.Description,
+		Sort:        brand.Sort,
+		ShowStatus:  brand.ShowStatus,
+	}
+}
+
+// buildBrandVO 将品牌pojo转换为vo
+func buildBrandVO(brand *pojo.Brand) *vo.BrandVO {
+	return &vo.BrandVO{
+		ID:                 brand.ID,
+		Name:               brand.Name,
+		FirstLetter:        brand.FirstLetter,
+		Logo:               brand.Logo,
+		Description:        brand.Description,
+		Sort:               brand.Sort,
+		ShowStatus:         brand.ShowStatus,
+		ProductCategoryIds: make([]int64, 0),
+	}
+}
@@ -1,90 +1,242 @@
 package logic
 
 import (
+	"context"
 	"go.uber.org/zap"
 	"shop-backend/dao/mysql"
 	"shop-backend/dao/redis"
+	"shop-backend/logger"
+	"shop-backend/metrics"
+	"shop-backend/models/dto"
 	"shop-backend/models/pojo"
 	"shop-backend/models/vo"
+	"strconv"
 )
 
-var errChannel = make(chan error, 2)
-
 // GetAllCategory 获取所有商品分类信息
-func GetAllCategory() ([]vo.FirstProductCategoryVO, error) {
+func GetAllCategory(ctx context.Context) ([]*vo.ProductCategoryTreeVO, error) {
 	// 先从缓存中获取商品分类信息
-	data, exist := redis.GetCategoryList()
+	data, exist := redis.GetCategoryList(ctx)
 	if exist {
-		zap.L().Info("使用商品分类信息缓存成功")
+		metrics.CacheHit(metrics.CacheCategory)
+		logger.Ctx(ctx).Info("使用商品分类信息缓存成功")
 		// 如果商品分类信息存在，直接返回
 		return data, nil
 	} // 缓存不存在，从数据库中查询，并放入缓存
-	zap.L().Info("使用商品分类信息缓存失败，查询数据库")
+	metrics.CacheMiss(metrics.CacheCategory)
+	logger.Ctx(ctx).Info("使用商品分类信息缓存失败，查询数据库")
+
+	// 查询数据库之前记录分类版本号，防止在查询期间分类被修改，导致旧的分类树覆盖新的分类树
+	version, err := redis.GetCategoryVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := buildCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 将商品分类信息缓存进Redis，写入失败不影响本次返回
+	ok, err := redis.SetCategoryListIfVersion(ctx, result, version)
+	if err != nil {
+		logger.Ctx(ctx).Error("商品分类信息写入缓存失败", zap.Int64("version", version), zap.Error(err))
+	} else if !ok {
+		// 查询期间分类被修改，由修改分类的请求负责写入缓存
+		logger.Ctx(ctx).Info("商品分类版本号已变化，放弃写入缓存", zap.Int64("version", version))
+	}
+	return result, nil
+}
+
+// RefreshCategoryCache 商品分类发生变化后，重新构建分类树并原子地覆盖缓存
+func RefreshCategoryCache(ctx context.Context) error {
+	version, err := redis.IncrCategoryVersion(ctx)
+	if err != nil {
+		// 版本号递增失败，直接删除缓存，避免返回旧的分类树
+		_ = redis.DelCategoryList(ctx)
+		return err
+	}
+	result, err := buildCategoryTree(ctx)
+	if err != nil {
+		_ = redis.DelCategoryList(ctx)
+		return err
+	}
+	ok, err := redis.SetCategoryListIfVersion(ctx, result, version)
+	if err != nil {
+		_ = redis.DelCategoryList(ctx)
+		return err
+	}
+	if !ok {
+		// 构建期间有更新的修改，由更新的修改负责写入缓存
+		logger.Ctx(ctx).Info("商品分类版本号已变化，放弃写入缓存", zap.Int64("version", version))
+	}
+	return nil
+}
+
+// AddCategory 新增商品分类
+func AddCategory(ctx context.Context, category *dto.Category) error {
+	categoryPojo := &pojo.ProductCategory{
+		ParentID:     category.ParentID,
+		Name:         category.Name,
+		Abbreviation: category.Abbreviation,
+		ShowStatus:   category.ShowStatus,
+		Icon:         category.Icon,
+		Sort:         category.Sort,
+	}
+	if category.ParentID != 0 {
+		// 分类层级为父分类层级加一
+		parent, err := mysql.SelectCategoryByID(ctx, category.ParentID)
+		if err != nil {
+			if err == mysql.ErrorCategoryNotExist {
+				return mysql.ErrorCategoryInvalidParent
+			}
+			return err
+		}
+		categoryPojo.Level = parent.Level + 1
+	}
+	if err := mysql.InsertCategory(ctx, categoryPojo, category.ProductAttributeIds); err != nil {
+		return err
+	}
+	return RefreshCategoryCache(ctx)
+}
+
+// UpdateCategory 修改商品分类的基本信息和属性
+func UpdateCategory(ctx context.Context, category *dto.Category) error {
+	id, err := strconv.ParseInt(category.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+	if _, err = mysql.SelectCategoryByID(ctx, id); err != nil {
+		return err
+	}
+	err = mysql.UpdateCategory(ctx, &pojo.ProductCategory{
+		ID:           id,
+		Name:         category.Name,
+		Abbreviation: category.Abbreviation,
+		Icon:         category.Icon,
+	}, category.ProductAttributeIds)
+	if err != nil {
+		return err
+	}
+	return RefreshCategoryCache(ctx)
+}
+
+// MoveCategory 移动商品分类
+func MoveCategory(ctx context.Context, id, parentID int64) error {
+	if err := mysql.MoveCategory(ctx, id, parentID); err != nil {
+		return err
+	}
+	return RefreshCategoryCache(ctx)
+}
 
-	// 获取商品一级分类信息
-	firstChannel := make(chan []*pojo.ProductCategory, 1)
-	// 获取商品二级分类信息
-	secondChannel := make(chan []*pojo.ProductCategory, 1)
+// SortCategory 修改商品分类排序
+func SortCategory(ctx context.Context, id int64, sort uint8) error {
+	if err := mysql.UpdateCategorySort(ctx, id, sort); err != nil {
+		return err
+	}
+	return RefreshCategoryCache(ctx)
+}
 
-	go getFirstCategories(firstChannel)
-	go getSecondCategories(secondChannel)
+// UpdateCategoryShowStatus 显示/隐藏商品分类
+func UpdateCategoryShowStatus(ctx context.Context, id int64, showStatus uint8) error {
+	if err := mysql.UpdateCategoryShowStatus(ctx, id, showStatus); err != nil {
+		return err
+	}
+	return RefreshCategoryCache(ctx)
+}
 
-	firstCategories := <-firstChannel
-	secondCategories := <-secondChannel
+// DelCategory 删除商品分类
+func DelCategory(ctx context.Context, id int64) error {
+	if err := mysql.DelCategory(ctx, id); err != nil {
+		return err
+	}
+	return RefreshCategoryCache(ctx)
+}
 
-	if len(errChannel) >= 1 {
-		err := <-errChannel
-		zap.L().Error("多协程获取一级分类，二级分类出错了", zap.Error(err))
+// GetCategoryBreadcrumbs 获取分类的面包屑导航，从一级分类开始一直到该分类本身
+func GetCategoryBreadcrumbs(ctx context.Context, categoryID int64) ([]*vo.CategoryVO, error) {
+	tree, err := GetAllCategory(ctx)
+	if err != nil {
 		return nil, err
 	}
+	breadcrumbs := make([]*vo.CategoryVO, 0)
+	path := findCategoryPath(tree, categoryID)
+	for _, node := range path {
+		breadcrumbs = append(breadcrumbs, &vo.CategoryVO{
+			ID:   node.ID,
+			Name: node.Name,
+		})
+	}
+	return breadcrumbs, nil
+}
 
-	// 返回的结果切片
-	var result = make([]vo.FirstProductCategoryVO, 0)
-	for _, category := range firstCategories {
-		// 遍历所有一级分类信息
-		firstCategoryVO := vo.FirstProductCategoryVO{
-			ID:         category.ID,
-			Name:       category.Name,
-			Level:      category.Level,
-			ShowStatus: category.ShowStatus,
-			Icon:       category.Icon,
+// GetCategoryDescendantIDs 获取分类自身以及所有子孙分类的ID，用于按任意祖先分类搜索商品。分类不存在时返回空集合
+func GetCategoryDescendantIDs(ctx context.Context, categoryID int64) ([]int64, error) {
+	tree, err := GetAllCategory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0)
+	path := findCategoryPath(tree, categoryID)
+	if len(path) == 0 {
+		return ids, nil
+	}
+	// 广度优先遍历子树
+	queue := []*vo.ProductCategoryTreeVO{path[len(path)-1]}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		ids = append(ids, node.ID)
+		queue = append(queue, node.Children...)
+	}
+	return ids, nil
+}
+
+// findCategoryPath 在分类树中查找从根节点到categoryID节点的路径，未找到时返回nil
+func findCategoryPath(nodes []*vo.ProductCategoryTreeVO, categoryID int64) []*vo.ProductCategoryTreeVO {
+	for _, node := range nodes {
+		if node.ID == categoryID {
+			return []*vo.ProductCategoryTreeVO{node}
 		}
-		// 筛选出从属该一级分类的二级分类信息
-		for _, reCategory := range secondCategories {
-			if reCategory.ParentID == category.ID {
-				firstCategoryVO.SecProductCategoryList = append(firstCategoryVO.SecProductCategoryList, &vo.SecondProductCategoryVO{
-					SecID:         reCategory.ID,
-					SecName:       reCategory.Name,
-					SecLevel:      reCategory.Level,
-					SecShowStatus: reCategory.ShowStatus,
-					SecIcon:       reCategory.Icon,
-				})
-			}
+		if path := findCategoryPath(node.Children, categoryID); path != nil {
+			return append([]*vo.ProductCategoryTreeVO{node}, path...)
 		}
-		// 加入到返回结果切片
-		result = append(result, firstCategoryVO)
 	}
-	// 将商品分类信息缓存进Redis
-	redis.SetCategoryList(result)
-	return result, nil
+	return nil
 }
 
-func getFirstCategories(channel chan []*pojo.ProductCategory) {
-	// 获取商品一级分类信息
-	firstCategories, err := mysql.SelectFirstCategory()
+// buildCategoryTree 一次性查询所有商品分类，并在内存中构建任意层级的分类树
+func buildCategoryTree(ctx context.Context) ([]*vo.ProductCategoryTreeVO, error) {
+	// 查询结果已按照层级、排序升序排列
+	categories, err := mysql.SelectAllCategory(ctx)
 	if err != nil {
-		errorChannel <- err
+		return nil, err
 	}
-	channel <- firstCategories
-	defer close(channel)
-}
 
-func getSecondCategories(channel chan []*pojo.ProductCategory) {
-	// 获取商品一级分类信息
-	secondCategories, err := mysql.SelectSecondCategory()
-	if err != nil {
-		errorChannel <- err
+	// K: 分类ID V: 分类树节点
+	nodeMap := make(map[int64]*vo.ProductCategoryTreeVO, len(categories))
+	for _, category := range categories {
+		nodeMap[category.ID] = &vo.ProductCategoryTreeVO{
+			ID:         category.ID,
+			ParentID:   category.ParentID,
+			Name:       category.Name,
+			Level:      category.Level,
+			ShowStatus: category.ShowStatus,
+			Icon:       category.Icon,
+			Sort:       category.Sort,
+			Children:   make([]*vo.ProductCategoryTreeVO, 0),
+		}
 	}
-	channel <- secondCategories
-	defer close(channel)
+
+	// 返回的结果切片(所有一级分类)
+	result := make([]*vo.ProductCategoryTreeVO, 0)
+	for _, category := range categories {
+		node := nodeMap[category.ID]
+		parent, ok := nodeMap[category.ParentID]
+		if category.ParentID == 0 || !ok {
+			// 父分类ID为0，或者父分类已不存在，作为一级分类
+			result = append(result, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return result, nil
 }
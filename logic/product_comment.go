@@ -0,0 +1,218 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"math"
+	"shop-backend/dao/mysql"
+	"shop-backend/errs"
+	"shop-backend/logger"
+	"shop-backend/models/dto"
+	"shop-backend/models/pojo"
+	"shop-backend/models/vo"
+	"shop-backend/utils/gen"
+	"shop-backend/utils/oss"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrorCommentNotOwner   = errs.Forbidden("只能追加自己的评价")
+	ErrorCommentPicIllegal = errs.Validation("评价图片不合法")
+	ErrorInvalidCommentID  = errs.Validation("评价ID或订单明细ID不合法")
+)
+
+// 评价列表单页最大记录数
+const maxCommentPageSize = 50
+
+// AddComment 用户评价已完成订单中的商品，每个订单明细只能评价一次
+func AddComment(ctx context.Context, uid int64, commentDTO *dto.Comment) error {
+	orderItemID, err := strconv.ParseInt(commentDTO.OrderItemID, 10, 64)
+	if err != nil {
+		return ErrorInvalidCommentID
+	}
+	item, err := mysql.SelectCompletedOrderItem(ctx, uid, orderItemID)
+	if err != nil {
+		return err
+	}
+	pics, err := marshalCommentPics(ctx, commentDTO.Pics)
+	if err != nil {
+		return err
+	}
+	comment := &pojo.Comment{
+		ID:          gen.GenSnowflakeID(),
+		SpuID:       item.SpuID,
+		SkuID:       item.SkuID,
+		OrderID:     item.OrderID,
+		OrderItemID: item.ID,
+		UserID:      uid,
+		Star:        commentDTO.Star,
+		Content:     strings.TrimSpace(commentDTO.Content),
+		Pics:        pics,
+	}
+	if len(commentDTO.Pics) != 0 {
+		comment.HasPic = 1
+	}
+	if err = mysql.InsertComment(ctx, comment); err != nil {
+		return err
+	}
+	// 商品详情缓存中包含评分汇总，新增评价后删除缓存
+	_ = InvalidateProductDetail(ctx, []int64{item.SpuID}, nil)
+	return nil
+}
+
+// AppendComment 用户追加评价，只能追加自己的评价
+func AppendComment(ctx context.Context, uid int64, appendDTO *dto.CommentAppend) error {
+	commentID, err := strconv.ParseInt(appendDTO.CommentID, 10, 64)
+	if err != nil {
+		return ErrorInvalidCommentID
+	}
+	comment, err := mysql.SelectCommentByID(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != uid {
+		return ErrorCommentNotOwner
+	}
+	pics, err := marshalCommentPics(ctx, appendDTO.Pics)
+	if err != nil {
+		return err
+	}
+	return mysql.InsertCommentAppend(ctx, &pojo.CommentAppend{
+		ID:        gen.GenSnowflakeID(),
+		CommentID: commentID,
+		UserID:    uid,
+		Content:   strings.TrimSpace(appendDTO.Content),
+		Pics:      pics,
+	})
+}
+
+// ReplyComment 商家回复评价，重复回复会覆盖之前的回复
+func ReplyComment(ctx context.Context, replyDTO *dto.CommentReply) error {
+	commentID, err := strconv.ParseInt(replyDTO.CommentID, 10, 64)
+	if err != nil {
+		return ErrorInvalidCommentID
+	}
+	return mysql.UpdateCommentReply(ctx, commentID, strings.TrimSpace(replyDTO.Content))
+}
+
+// VoteCommentHelpful 用户认为评价有用，每个用户对同一条评价只能投一次
+func VoteCommentHelpful(ctx context.Context, uid, commentID int64) error {
+	return mysql.InsertCommentHelpful(ctx, commentID, uid)
+}
+
+// GetCommentList 分页获取商品的评价列表，包含每条评价的追加评价
+func GetCommentList(ctx context.Context, spuID int64, condition *dto.CommentCondition) (*vo.Page[[]*vo.CommentVO], error) {
+	sort, _ := strconv.Atoi(condition.Sort)
+	pageNo, err := strconv.Atoi(condition.PageNo)
+	if err != nil || pageNo < 1 {
+		pageNo = 1
+	}
+	pageSize, err := strconv.Atoi(condition.PageSize)
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > maxCommentPageSize {
+		pageSize = maxCommentPageSize
+	}
+
+	comments, total, err := mysql.SelectCommentList(ctx, spuID, condition.WithPic, sort, pageNo, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	commentIDs := make([]int64, 0, len(comments))
+	// K: 评价ID V: 评价
+	commentMap := make(map[int64]*vo.CommentVO, len(comments))
+	for _, comment := range comments {
+		comment.Pics = unmarshalCommentPics(ctx, comment.PicsStr)
+		comment.Appends = make([]*vo.CommentAppendVO, 0)
+		commentIDs = append(commentIDs, comment.ID)
+		commentMap[comment.ID] = comment
+	}
+	appends, err := mysql.SelectCommentAppendByCommentIDs(ctx, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, commentAppend := range appends {
+		if comment, ok := commentMap[commentAppend.CommentID]; ok {
+			comment.Appends = append(comment.Appends, &vo.CommentAppendVO{
+				ID:          commentAppend.ID,
+				CommentID:   commentAppend.CommentID,
+				Content:     commentAppend.Content,
+				Pics:        unmarshalCommentPics(ctx, commentAppend.Pics),
+				CreatedTime: commentAppend.CreatedTime,
+			})
+		}
+	}
+	return &vo.Page[[]*vo.CommentVO]{
+		PageNo:     strconv.Itoa(pageNo),
+		PageSize:   strconv.Itoa(pageSize),
+		TotalCount: strconv.FormatInt(total, 10),
+		TotalPage:  strconv.FormatInt(getTotalPage(total, int64(pageSize)), 10),
+		Data:       comments,
+	}, nil
+}
+
+// GetRatingSummary 获取商品的评分汇总：平均评分、评价总数、有图评价数以及每个星级的评价数
+func GetRatingSummary(ctx context.Context, spuID int64) (*vo.RatingVO, error) {
+	starCounts, err := mysql.SelectCommentStarCounts(ctx, spuID)
+	if err != nil {
+		return nil, err
+	}
+	withPic, err := mysql.CountCommentWithPic(ctx, spuID)
+	if err != nil {
+		return nil, err
+	}
+	// K: 星级 V: 评价数
+	countMap := make(map[uint8]int64, len(starCounts))
+	for _, starCount := range starCounts {
+		countMap[starCount.Star] = starCount.Count
+	}
+	rating := &vo.RatingVO{
+		WithPic: withPic,
+		Stars:   make([]*vo.RatingStarVO, 0, 5),
+	}
+	var sum int64
+	for star := uint8(5); star >= 1; star-- {
+		count := countMap[star]
+		rating.Stars = append(rating.Stars, &vo.RatingStarVO{Star: star, Count: count})
+		rating.Total += count
+		sum += int64(star) * count
+	}
+	if rating.Total > 0 {
+		rating.Average = math.Round(float64(sum)/float64(rating.Total)*10) / 10
+	}
+	return rating, nil
+}
+
+// marshalCommentPics 校验评价图片都是上传到阿里云OSS的图片，并序列化为json数组
+func marshalCommentPics(ctx context.Context, pics []string) (string, error) {
+	if len(pics) == 0 {
+		return "[]", nil
+	}
+	for _, pic := range pics {
+		if !oss.IsCommentPic(pic) {
+			return "", ErrorCommentPicIllegal
+		}
+	}
+	bytes, err := json.Marshal(pics)
+	if err != nil {
+		logger.Ctx(ctx).Error("序列化评价图片失败", zap.Error(err))
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// unmarshalCommentPics 将json数组反序列化为评价图片集合
+func unmarshalCommentPics(ctx context.Context, pics string) []string {
+	result := make([]string, 0)
+	if pics == "" {
+		return result
+	}
+	if err := json.Unmarshal([]byte(pics), &result); err != nil {
+		logger.Ctx(ctx).Error("反序列化评价图片失败", zap.String("pics", pics), zap.Error(err))
+		return make([]string, 0)
+	}
+	return result
+}
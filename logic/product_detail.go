@@ -1,57 +1,56 @@
 package logic
 
 import (
+	"context"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"shop-backend/dao/mysql"
+	"shop-backend/logger"
+	"shop-backend/models/pojo"
 	"shop-backend/models/vo"
 	"sync"
 	"time"
 )
 
-// 存放协程函数信息的通道
-var errorChannel = make(chan error, 2)
-
-// GetCategories 获取sku分类信息
-func GetCategories(revChan chan<- []*vo.CategoryVO, cid1, cid2 int64) {
-	categories, err := mysql.SelectSpuCategoryByCID(cid1, cid2)
-	if err != nil {
-		errorChannel <- err
-	}
-	// 封装categoriesVO
-	categoriesVO := make([]*vo.CategoryVO, 0)
-	for _, cate := range categories {
-		categoriesVO = append(categoriesVO, &vo.CategoryVO{
-			ID:   cate.ID,
-			Name: cate.Name,
-		})
+// spuCategoryID 获取spu所属的末级分类ID，兼容只有cid1、cid2的旧数据
+func spuCategoryID(spu *pojo.Spu) int64 {
+	if spu.CategoryID != 0 {
+		return spu.CategoryID
 	}
-	// 发送到类型为[]*vo.CategoryVO的通道中
-	revChan <- categoriesVO
-	// 此通道为一发送一接收的对应关系，所以尽量在发送方关闭通道(即使关闭了通道，也能获取通道里数据)
-	defer close(revChan)
+	return spu.CID2
 }
 
-// GetSkuList 获取spu下属的sku集合
-func GetSkuList(revChan chan<- []*vo.SkuVO, spuID int64) {
+// buildSkuListVO 获取spu下属的sku集合以及每个sku的商品图片。所有sku的图片通过一次查询获取，避免N+1查询
+func buildSkuListVO(ctx context.Context, spuID int64) ([]*vo.SkuVO, error) {
+	skuListVO := make([]*vo.SkuVO, 0)
 	// 获取skuList
-	skuList, err := mysql.SelectSkuListBySpuID(spuID)
+	skuList, err := mysql.SelectSkuListBySpuID(ctx, spuID)
 	if err != nil {
-		errorChannel <- err
+		return skuListVO, err
 	}
-	// 封装skuListVO
-	skuListVO := make([]*vo.SkuVO, 0)
+	skuIDs := make([]int64, 0, len(skuList))
 	for _, sku := range skuList {
-		// 获取sku的所有商品图片
-		skuPicList, _ := mysql.SelectSkuPicBySkuID(sku.ID)
-		// 封装skuPicListVO
-		skuPicListVO := make([]*vo.SkuPicVO, 0)
-		for _, pic := range skuPicList {
-			skuPicListVO = append(skuPicListVO, &vo.SkuPicVO{
-				ID:        pic.ID,
-				SkuID:     pic.SkuID,
-				PicUrl:    pic.PicUrl,
-				IsDefault: pic.IsDefault,
-			})
+		skuIDs = append(skuIDs, sku.ID)
+	}
+	// 一次性获取所有sku的商品图片
+	skuPicList, err := mysql.SelectSkuPicBySkuIDs(ctx, skuIDs)
+	if err != nil {
+		return skuListVO, err
+	}
+	// K: skuID V: sku的商品图片集合
+	skuPicMap := make(map[int64][]*vo.SkuPicVO, len(skuList))
+	for _, pic := range skuPicList {
+		skuPicMap[pic.SkuID] = append(skuPicMap[pic.SkuID], &vo.SkuPicVO{
+			ID:        pic.ID,
+			SkuID:     pic.SkuID,
+			PicUrl:    pic.PicUrl,
+			IsDefault: pic.IsDefault,
+		})
+	}
+	for _, sku := range skuList {
+		skuPicListVO, ok := skuPicMap[sku.ID]
+		if !ok {
+			skuPicListVO = make([]*vo.SkuPicVO, 0)
 		}
 		skuListVO = append(skuListVO, &vo.SkuVO{
 			ID:                      sku.ID,
@@ -64,18 +63,15 @@ func GetSkuList(revChan chan<- []*vo.SkuVO, spuID int64) {
 			SkuPicList:              skuPicListVO,
 		})
 	}
-	// 发送到类型为[]*vo.SkuVO的通道中
-	revChan <- skuListVO
-	// 此通道为一发送一接收的对应关系，所以尽量在发送方关闭通道(即使关闭了通道，也能获取通道里数据)
-	defer close(revChan)
+	return skuListVO, nil
 }
 
 // GetProductDetailWithConcurrent 多协程获取商品详情
-func GetProductDetailWithConcurrent(skuID int64) (*vo.ProductDetailVO, error) {
+func GetProductDetailWithConcurrent(ctx context.Context, skuID int64) (*vo.ProductDetailVO, error) {
 	// start := time.Now()
 	detail := new(vo.ProductDetailVO)
 	// 获取spu
-	spu, err := mysql.SelectSpuBySkuID(skuID)
+	spu, err := mysql.SelectSpuBySkuID(ctx, skuID)
 	if err != nil {
 		return nil, err
 	}
@@ -88,40 +84,37 @@ func GetProductDetailWithConcurrent(skuID int64) (*vo.ProductDetailVO, error) {
 	}
 	detail.Spu = spuVO
 
-	// 创建两个类型不同的通道，分别用于获取category和skuList
-	revChan1 := make(chan []*vo.CategoryVO, 1)
-	revChan2 := make(chan []*vo.SkuVO, 1)
-
-	// 开启两个协程，并将对应的通道传递到协程函数中，由协程函数负责往通道里装入数据
-	go GetCategories(revChan1, spu.CID1, spu.CID2)
-	go GetSkuList(revChan2, spu.ID)
-
-	// case1：如果协程函数未执行完，从通道中接收数据的操作会在通道底层的接收队列阻塞等待。所以程序会阻塞在下面两行
-	// case2：如果协程函数已经执行完，那么直接从通道中获取数据，完成detail的赋值操作
-	detail.Categories = <-revChan1
-	detail.SkuList = <-revChan2
+	// 开启三个协程分别获取category、skuList和评分汇总，每个协程只赋值detail中自己的字段。
+	// 错误只属于这一次调用，任意一个协程失败都返回错误，不会返回(以及缓存)不完整的商品详情
+	var g errgroup.Group
+	g.Go(func() error {
+		categoriesVO, err := GetCategoryBreadcrumbs(ctx, spuCategoryID(spu))
+		detail.Categories = categoriesVO
+		return err
+	})
+	g.Go(func() error {
+		skuListVO, err := buildSkuListVO(ctx, spu.ID)
+		detail.SkuList = skuListVO
+		return err
+	})
+	g.Go(func() error {
+		rating, err := GetRatingSummary(ctx, spu.ID)
+		detail.Rating = rating
+		return err
+	})
 	// 处理异常
-	if len(errorChannel) > 0 {
-		err := <-errorChannel
-		zap.L().Error("多协程获取商品详情失败", zap.Error(err))
+	if err = g.Wait(); err != nil {
+		logger.Ctx(ctx).Error("多协程获取商品详情失败", zap.Error(err))
 		return nil, err
 	}
 	// cost := time.Since(start)
-	// zap.L().Info(" GetProductDetailWithConcurrent 商品详情接口耗时:", zap.Duration("cost", cost))
+	// logger.Ctx(ctx).Info(" GetProductDetailWithConcurrent 商品详情接口耗时:", zap.Duration("cost", cost))
 	return detail, nil
 }
 
-// GetCategories2 获取sku分类信息
-func GetCategories2(channel chan *vo.ProductDetailVO, cid1, cid2 int64) {
-	categories, _ := mysql.SelectSpuCategoryByCID(cid1, cid2)
-	// 封装categoriesVO
-	categoriesVO := make([]*vo.CategoryVO, 0)
-	for _, cate := range categories {
-		categoriesVO = append(categoriesVO, &vo.CategoryVO{
-			ID:   cate.ID,
-			Name: cate.Name,
-		})
-	}
+// GetCategories2 获取sku分类信息(面包屑导航)
+func GetCategories2(ctx context.Context, channel chan *vo.ProductDetailVO, categoryID int64) {
+	categoriesVO, _ := GetCategoryBreadcrumbs(ctx, categoryID)
 
 	// 通道中只有一个detail对象，如果两个协程函数都在方法第一行获取通道中的对象，那么肯定有一个协程函数会进入通道底层的接收队列中进行阻塞等待
 	// 与其直接在里面等待，不如先查询出要添加到detail的数据，在成功获取到通道中的detail时，直接赋值
@@ -136,35 +129,9 @@ func GetCategories2(channel chan *vo.ProductDetailVO, cid1, cid2 int64) {
 }
 
 // GetSkuList2 获取spu下属的sku集合
-func GetSkuList2(channel chan *vo.ProductDetailVO, spuID int64) {
+func GetSkuList2(ctx context.Context, channel chan *vo.ProductDetailVO, spuID int64) {
 	// 获取skuList
-	skuList, _ := mysql.SelectSkuListBySpuID(spuID)
-	// 封装skuListVO
-	skuListVO := make([]*vo.SkuVO, 0)
-	for _, sku := range skuList {
-		// 获取sku的所有商品图片
-		skuPicList, _ := mysql.SelectSkuPicBySkuID(sku.ID)
-		// 封装skuPicListVO
-		skuPicListVO := make([]*vo.SkuPicVO, 0)
-		for _, pic := range skuPicList {
-			skuPicListVO = append(skuPicListVO, &vo.SkuPicVO{
-				ID:        pic.ID,
-				SkuID:     pic.SkuID,
-				PicUrl:    pic.PicUrl,
-				IsDefault: pic.IsDefault,
-			})
-		}
-		skuListVO = append(skuListVO, &vo.SkuVO{
-			ID:                      sku.ID,
-			SpuID:                   sku.SpuID,
-			Title:                   sku.Title,
-			Price:                   sku.Price,
-			Unit:                    sku.Unit,
-			Stock:                   sku.Stock,
-			ProductSkuSpecification: sku.ProductSkuSpecification,
-			SkuPicList:              skuPicListVO,
-		})
-	}
+	skuListVO, _ := buildSkuListVO(ctx, spuID)
 
 	// 通道中只有一个detail对象，如果两个协程函数都在方法第一行获取通道中的对象，那么肯定有一个协程函数会进入通道底层的接收队列中进行阻塞等待
 	// 与其直接在里面等待，不如先查询出要添加到detail的数据，在成功获取到通道中的detail时，直接赋值
@@ -180,11 +147,11 @@ func GetSkuList2(channel chan *vo.ProductDetailVO, spuID int64) {
 var wg sync.WaitGroup
 
 // GetProductDetailWithConcurrent2 多协程获取商品详情
-func GetProductDetailWithConcurrent2(skuID int64) (*vo.ProductDetailVO, error) {
+func GetProductDetailWithConcurrent2(ctx context.Context, skuID int64) (*vo.ProductDetailVO, error) {
 	start := time.Now()
 	detail := new(vo.ProductDetailVO)
 	// 获取spu
-	spu, _ := mysql.SelectSpuBySkuID(skuID)
+	spu, _ := mysql.SelectSpuBySkuID(ctx, skuID)
 	// 封装spuVO
 	spuVO := &vo.SpuVO{
 		ID:                   spu.ID,
@@ -206,22 +173,22 @@ func GetProductDetailWithConcurrent2(skuID int64) (*vo.ProductDetailVO, error) {
 	// 下面的两个协程函数，如果未在函数返回前执行，那么最后返回的detail对象，skuList和categories为空，因为协程函数还没有执行完。
 	// 所以使用WaitGroup。
 	wg.Add(2)
-	go GetCategories2(channel, spu.CID1, spu.CID2)
-	go GetSkuList2(channel, spu.ID)
+	go GetCategories2(ctx, channel, spuCategoryID(spu))
+	go GetSkuList2(ctx, channel, spu.ID)
 
 	cost := time.Since(start)
-	zap.L().Info("GetProductDetailWithConcurrent2 商品详情接口耗时:", zap.Duration("cost", cost))
+	logger.Ctx(ctx).Info("GetProductDetailWithConcurrent2 商品详情接口耗时:", zap.Duration("cost", cost))
 	wg.Wait()
 	// 因为通道中的数据类型为指针类型，所以最后返回的detail包含spu、categories、skuList
 	return <-channel, nil
 }
 
 // GetProductDetail 获取商品详情
-func GetProductDetail(skuID int64) (*vo.ProductDetailVO, error) {
+func GetProductDetail(ctx context.Context, skuID int64) (*vo.ProductDetailVO, error) {
 	start := time.Now()
 	detail := new(vo.ProductDetailVO)
 	// 获取spu
-	spu, err := mysql.SelectSpuBySkuID(skuID)
+	spu, err := mysql.SelectSpuBySkuID(ctx, skuID)
 	if err != nil {
 		return nil, err
 	}
@@ -235,56 +202,19 @@ func GetProductDetail(skuID int64) (*vo.ProductDetailVO, error) {
 	detail.Spu = spuVO
 
 	// 获取categories
-	categories, err := mysql.SelectSpuCategoryByCID(spu.CID1, spu.CID2)
+	categoriesVO, err := GetCategoryBreadcrumbs(ctx, spuCategoryID(spu))
 	if err != nil {
 		return nil, err
 	}
-	// 封装categoriesVO
-	categoriesVO := make([]*vo.CategoryVO, 0)
-	for _, cate := range categories {
-		categoriesVO = append(categoriesVO, &vo.CategoryVO{
-			ID:   cate.ID,
-			Name: cate.Name,
-		})
-	}
 	detail.Categories = categoriesVO
 
 	// 获取skuList
-	skuList, err := mysql.SelectSkuListBySpuID(spu.ID)
+	skuListVO, err := buildSkuListVO(ctx, spu.ID)
 	if err != nil {
 		return nil, err
 	}
-	// 封装skuListVO
-	skuListVO := make([]*vo.SkuVO, 0)
-	for _, sku := range skuList {
-		// 获取sku的所有商品图片
-		skuPicList, err := mysql.SelectSkuPicBySkuID(sku.ID)
-		if err != nil {
-			return nil, err
-		}
-		// 封装skuPicListVO
-		skuPicListVO := make([]*vo.SkuPicVO, 0)
-		for _, pic := range skuPicList {
-			skuPicListVO = append(skuPicListVO, &vo.SkuPicVO{
-				ID:        pic.ID,
-				SkuID:     pic.SkuID,
-				PicUrl:    pic.PicUrl,
-				IsDefault: pic.IsDefault,
-			})
-		}
-		skuListVO = append(skuListVO, &vo.SkuVO{
-			ID:                      sku.ID,
-			SpuID:                   sku.SpuID,
-			Title:                   sku.Title,
-			Price:                   sku.Price,
-			Unit:                    sku.Unit,
-			Stock:                   sku.Stock,
-			ProductSkuSpecification: sku.ProductSkuSpecification,
-			SkuPicList:              skuPicListVO,
-		})
-	}
 	detail.SkuList = skuListVO
 	cost := time.Since(start)
-	zap.L().Info("商品详情接口耗时:", zap.Duration("cost", cost))
+	logger.Ctx(ctx).Info("商品详情接口耗时:", zap.Duration("cost", cost))
 	return detail, nil
 }
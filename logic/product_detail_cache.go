@@ -0,0 +1,84 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/sync/singleflight"
+	"shop-backend/dao/mysql"
+	"shop-backend/dao/redis"
+	"shop-backend/metrics"
+	"shop-backend/models/vo"
+	"strconv"
+	"time"
+)
+
+// 合并同一个sku并发的缓存重建请求，同一时刻只有一个请求查询数据库(防止缓存击穿)
+var productDetailGroup singleflight.Group
+
+// GetProductDetailWithCache 优先从缓存中获取商品详情，未命中缓存时查询数据库并重建缓存。
+// 不存在的skuID会缓存空值，在空值过期前直接返回ErrorSpuNotExist
+func GetProductDetailWithCache(ctx context.Context, skuID int64) (*vo.ProductDetailVO, error) {
+	if detail, ok, err := getProductDetailFromCache(ctx, skuID); ok {
+		metrics.CacheHit(metrics.CacheDetail)
+		return detail, err
+	}
+	metrics.CacheMiss(metrics.CacheDetail)
+	result, err, _ := productDetailGroup.Do(strconv.FormatInt(skuID, 10), func() (interface{}, error) {
+		// 合并后的查询由所有等待的请求共享，使用不会被取消的ctx，第一个请求断开或超时不会导致其他请求失败
+		ctx := detachedContext{ctx}
+		// 等待期间其他请求可能已经重建了缓存，再检查一次
+		if detail, ok, err := getProductDetailFromCache(ctx, skuID); ok {
+			return detail, err
+		}
+		detail, err := GetProductDetailWithConcurrent(ctx, skuID)
+		if errors.Is(err, mysql.ErrorSpuNotExist) {
+			_ = redis.SetProductDetailNull(ctx, skuID)
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 先缓存详情再缓存映射，保证映射存在时详情大概率也存在
+		_ = redis.SetProductDetail(ctx, detail.Spu.ID, detail)
+		_ = redis.SetProductDetailSpuID(ctx, skuID, detail.Spu.ID)
+		return detail, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*vo.ProductDetailVO), nil
+}
+
+// detachedContext 保留ctx中的值(日志、链路、请求ID)，但不会被取消也没有截止时间
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// getProductDetailFromCache 从缓存中获取商品详情，第二个返回值表示是否命中缓存(包括空值缓存)
+func getProductDetailFromCache(ctx context.Context, skuID int64) (*vo.ProductDetailVO, bool, error) {
+	spuID, ok := redis.GetProductDetailSpuID(ctx, skuID)
+	if !ok {
+		return nil, false, nil
+	}
+	if spuID == 0 {
+		return nil, true, mysql.ErrorSpuNotExist
+	}
+	detail, ok := redis.GetProductDetail(ctx, spuID)
+	if !ok {
+		return nil, false, nil
+	}
+	return detail, true, nil
+}
+
+// InvalidateProductDetail 删除spu的商品详情缓存以及sku到spu的映射缓存
+func InvalidateProductDetail(ctx context.Context, spuIDs, skuIDs []int64) error {
+	return redis.DelProductDetail(ctx, spuIDs, skuIDs)
+}
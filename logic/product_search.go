@@ -1,32 +1,277 @@
 package logic
 
 import (
+	"context"
+	"encoding/base64"
 	"go.uber.org/zap"
 	"shop-backend/dao/mysql"
+	"shop-backend/errs"
+	"shop-backend/logger"
 	"shop-backend/models/dto"
 	"shop-backend/models/vo"
+	"shop-backend/search"
+	"shop-backend/utils/concatstr"
+	"sort"
 	"strconv"
+	"strings"
 )
 
+var ErrorInvalidSearchCursor = errs.Validation("搜索游标不合法")
+
+// 游标中排序值和skuID的分隔符
+const searchCursorSeparator = "|"
+
 // Search 多条件搜索业务
-func Search(condition *dto.SearchCondition) (*vo.Page[[]*vo.ProductVO], error) {
+func Search(ctx context.Context, condition *dto.SearchCondition) (*vo.Page[[]*vo.ProductVO], error) {
+	noMatch, err := prepareCondition(ctx, condition)
+	if err != nil {
+		return nil, err
+	}
+	if noMatch {
+		// 全文索引中没有匹配的商品
+		return emptyPage(condition), nil
+	}
+	keyword := strings.TrimSpace(condition.Keyword)
+	if err = decodeSearchCursor(ctx, condition); err != nil {
+		return nil, err
+	}
 	// 获取符合条件的sku集合
-	products, _, err := mysql.BaseSearchCondition(condition, true)
+	products, err := mysql.BaseSearchCondition(ctx, condition)
+	if err != nil {
+		logger.Ctx(ctx).Error("mysql层BaseSearchCondition 查询失败", zap.Error(err))
+		return nil, err
+	}
+	// 获取总记录数
+	totalCount, err := mysql.CountSearchCondition(ctx, condition)
+	if err != nil {
+		logger.Ctx(ctx).Error("mysql层CountSearchCondition 查询失败", zap.Error(err))
+		return nil, err
+	}
+	// 获取品牌聚合
+	brands, err := mysql.SelectBrandFacets(ctx, condition)
+	if err != nil {
+		logger.Ctx(ctx).Error("mysql层SelectBrandFacets 查询失败", zap.Error(err))
+		return nil, err
+	}
+	// 获取二级分类聚合
+	categories, err := getCategoryFacets(ctx, condition)
 	if err != nil {
-		zap.L().Error("mysql层BaseSearchCondition(分页) 查询失败", zap.Error(err))
 		return nil, err
 	}
-	// 获取总页数
-	_, totalPage, err := mysql.BaseSearchCondition(condition, false)
+	// 获取属性值聚合
+	attributes, err := getAttributeFacets(ctx, condition)
 	if err != nil {
-		zap.L().Error("mysql层BaseSearchCondition(不分页) 查询失败", zap.Error(err))
 		return nil, err
 	}
+	if keyword != "" {
+		// 高亮商品名称中匹配搜索关键字的片段
+		for _, product := range products {
+			product.Highlight = search.Highlight(product.Name, keyword)
+		}
+	}
+	pageSize, _ := strconv.ParseInt(condition.PageSize, 10, 64)
+	var nextCursor string
+	if len(products) != 0 && int64(len(products)) == pageSize {
+		// 当前页已满，可能还有下一页
+		last := products[len(products)-1]
+		nextCursor = encodeSearchCursor(last.SortValue, last.ID)
+	}
 	page := &vo.Page[[]*vo.ProductVO]{
-		PageNo:    condition.PageNo,
-		PageSize:  condition.PageSize,
-		TotalPage: strconv.Itoa(totalPage),
-		Data:      products,
+		PageNo:     condition.PageNo,
+		PageSize:   condition.PageSize,
+		TotalCount: strconv.FormatInt(totalCount, 10),
+		TotalPage:  strconv.FormatInt(getTotalPage(totalCount, pageSize), 10),
+		NextCursor: nextCursor,
+		Data:       products,
+		Facets: &vo.SearchFacetVO{
+			Brands:     brands,
+			Categories: categories,
+			Attributes: attributes,
+		},
 	}
 	return page, nil
 }
+
+// emptyPage 没有符合条件的商品时返回的分页对象
+func emptyPage(condition *dto.SearchCondition) *vo.Page[[]*vo.ProductVO] {
+	return &vo.Page[[]*vo.ProductVO]{
+		PageNo:     condition.PageNo,
+		PageSize:   condition.PageSize,
+		TotalCount: strconv.Itoa(0),
+		TotalPage:  strconv.Itoa(0),
+		Data:       make([]*vo.ProductVO, 0),
+		Facets: &vo.SearchFacetVO{
+			Brands:     make([]*vo.BrandFacetVO, 0),
+			Categories: make([]*vo.CategoryFacetVO, 0),
+			Attributes: make([]*vo.AttributeVO, 0),
+		},
+	}
+}
+
+// getTotalPage 根据总记录数和页长计算总页数
+func getTotalPage(totalCount, pageSize int64) int64 {
+	if pageSize <= 0 {
+		return 0
+	}
+	return (totalCount + pageSize - 1) / pageSize
+}
+
+// encodeSearchCursor 将最后一条商品的排序值和skuID编码为游标
+func encodeSearchCursor(sortValue string, skuID int64) string {
+	raw := concatstr.ConcatString(sortValue, searchCursorSeparator, strconv.FormatInt(skuID, 10))
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeSearchCursor 解析搜索条件中的游标，并填充游标对应的排序值和skuID
+func decodeSearchCursor(ctx context.Context, condition *dto.SearchCondition) error {
+	cursor := strings.TrimSpace(condition.Cursor)
+	if cursor == "" {
+		return nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		logger.Ctx(ctx).Error("解析搜索游标失败", zap.String("cursor", cursor), zap.Error(err))
+		return ErrorInvalidSearchCursor
+	}
+	index := strings.LastIndex(string(raw), searchCursorSeparator)
+	if index == -1 {
+		return ErrorInvalidSearchCursor
+	}
+	skuID, err := strconv.ParseInt(string(raw[index+1:]), 10, 64)
+	if err != nil || skuID == 0 {
+		return ErrorInvalidSearchCursor
+	}
+	condition.CursorValue = string(raw[:index])
+	condition.CursorID = skuID
+	return nil
+}
+
+// getCategoryFacets 获取符合搜索条件的商品在二级分类上的聚合。
+// 商品可以挂在任意层级的分类下，聚合时将分类归并到其所属的二级分类，直接挂在一级分类下的商品不参与聚合
+func getCategoryFacets(ctx context.Context, condition *dto.SearchCondition) ([]*vo.CategoryFacetVO, error) {
+	facets, err := mysql.SelectCategoryFacets(ctx, condition)
+	if err != nil {
+		logger.Ctx(ctx).Error("mysql层SelectCategoryFacets 查询失败", zap.Error(err))
+		return nil, err
+	}
+	tree, err := GetAllCategory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// K: 二级分类ID V: 二级分类聚合
+	facetMap := make(map[int64]*vo.CategoryFacetVO)
+	result := make([]*vo.CategoryFacetVO, 0)
+	for _, facet := range facets {
+		path := findCategoryPath(tree, facet.ID)
+		if len(path) < 2 {
+			continue
+		}
+		second := path[1]
+		if categoryFacet, ok := facetMap[second.ID]; ok {
+			categoryFacet.Count += facet.Count
+			continue
+		}
+		categoryFacet := &vo.CategoryFacetVO{ID: second.ID, Name: second.Name, Count: facet.Count}
+		facetMap[second.ID] = categoryFacet
+		result = append(result, categoryFacet)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+	return result, nil
+}
+
+// getAttributeFacets 获取符合搜索条件的商品在属性值上的聚合，并按属性名分组
+func getAttributeFacets(ctx context.Context, condition *dto.SearchCondition) ([]*vo.AttributeVO, error) {
+	result := make([]*vo.AttributeVO, 0)
+	facets, err := mysql.SelectAttributeValueFacets(ctx, condition)
+	if err != nil {
+		logger.Ctx(ctx).Error("mysql层SelectAttributeValueFacets 查询失败", zap.Error(err))
+		return nil, err
+	}
+	if len(facets) == 0 {
+		return result, nil
+	}
+	// K: 属性值ID V: 商品数量
+	countMap := make(map[int64]int64, len(facets))
+	valueIDs := make([]int64, 0, len(facets))
+	for _, facet := range facets {
+		countMap[facet.ValueID] = facet.Count
+		valueIDs = append(valueIDs, facet.ValueID)
+	}
+	values, err := mysql.SelectAttributeByIDs(ctx, valueIDs)
+	if err != nil {
+		return nil, err
+	}
+	keyIDs := make([]int64, 0)
+	for _, value := range values {
+		if value.ParentID != 0 {
+			keyIDs = append(keyIDs, value.ParentID)
+		}
+	}
+	keys, err := mysql.SelectAttributeByIDs(ctx, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+	// K: 属性名ID V: 属性名
+	keyMap := make(map[int64]*vo.AttributeVO, len(keys))
+	for _, key := range keys {
+		attribute := &vo.AttributeVO{
+			KeyID:           key.ID,
+			KeyName:         key.Name,
+			AttributeValues: make([]*vo.AttributeValueVO, 0),
+		}
+		keyMap[key.ID] = attribute
+		result = append(result, attribute)
+	}
+	for _, value := range values {
+		if attribute, ok := keyMap[value.ParentID]; ok {
+			attribute.AttributeValues = append(attribute.AttributeValues, &vo.AttributeValueVO{
+				ValueID:   value.ID,
+				ValueName: value.Name,
+				Count:     countMap[value.ID],
+			})
+		}
+	}
+	return result, nil
+}
+
+// prepareCondition 预处理搜索条件，noMatch为true表示全文索引中没有匹配的商品。
+// 分类ID可以是任意层级的分类，展开为分类自身以及所有子孙分类ID；
+// 搜索关键字优先使用全文索引召回商品，索引不可用时降级为MySQL模糊查询
+func prepareCondition(ctx context.Context, condition *dto.SearchCondition) (noMatch bool, err error) {
+	if strings.TrimSpace(condition.ProductCategoryId) != "" {
+		categoryID, err := strconv.ParseInt(strings.TrimSpace(condition.ProductCategoryId), 10, 64)
+		if err != nil {
+			logger.Ctx(ctx).Error("ProductCategoryId转换为整型失败", zap.Error(err))
+			return false, err
+		}
+		categoryIDs, err := GetCategoryDescendantIDs(ctx, categoryID)
+		if err != nil {
+			return false, err
+		}
+		if len(categoryIDs) == 0 {
+			// 分类不存在，没有符合条件的商品
+			categoryIDs = []int64{categoryID}
+		}
+		condition.ProductCategoryTreeIds = categoryIDs
+	}
+
+	keyword := strings.TrimSpace(condition.Keyword)
+	if keyword == "" {
+		return false, nil
+	}
+	hits, ok := search.Query(keyword)
+	if !ok {
+		logger.Ctx(ctx).Info("全文索引不可用，降级为MySQL模糊查询")
+		return false, nil
+	}
+	if len(hits) == 0 {
+		return true, nil
+	}
+	condition.SkuIds = make([]int64, 0, len(hits))
+	for _, hit := range hits {
+		condition.SkuIds = append(condition.SkuIds, hit.SkuID)
+	}
+	return false, nil
+}
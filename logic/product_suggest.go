@@ -0,0 +1,196 @@
+package logic
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"shop-backend/dao/mysql"
+	"shop-backend/dao/redis"
+	"shop-backend/lifecycle"
+	"shop-backend/logger"
+	"shop-backend/search"
+	"shop-backend/settings"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 记录热度的搜索关键字最大长度，过长的关键字通常不是有意义的搜索词
+const maxHotKeywordLength = 32
+
+// 每个前缀最多从前缀索引中读取的条目数，过滤重复、屏蔽词后再截取
+const suggestScanFactor = 5
+
+var suggestConf = &settings.SearchConfig{
+	SuggestSize:            10,
+	SuggestRebuildInterval: 60,
+	HotDecayInterval:       60,
+	HotDecayFactor:         0.9,
+}
+
+// InitSearchSuggest 启动搜索补全前缀索引的定时重建，以及热搜词热度的定时衰减
+func InitSearchSuggest(cfg *settings.SearchConfig) {
+	if cfg != nil {
+		suggestConf = cfg
+	}
+	if suggestConf.SuggestRebuildInterval > 0 {
+		lifecycle.Go("搜索补全索引重建", lifecycle.PhaseIntake, func(ctx context.Context) {
+			period := time.Duration(suggestConf.SuggestRebuildInterval) * time.Minute
+			for {
+				if ok, err := redis.TryLockSuggestRebuild(ctx, period); err == nil && ok {
+					if err = RebuildSuggestIndex(ctx); err != nil {
+						zap.L().Error("重建搜索补全前缀索引失败", zap.Error(err))
+					}
+				}
+				if !lifecycle.Sleep(ctx, period) {
+					return
+				}
+			}
+		})
+	}
+	if suggestConf.HotDecayInterval > 0 && suggestConf.HotDecayFactor > 0 && suggestConf.HotDecayFactor < 1 {
+		lifecycle.Go("热搜词热度衰减", lifecycle.PhaseIntake, func(ctx context.Context) {
+			period := time.Duration(suggestConf.HotDecayInterval) * time.Minute
+			for lifecycle.Sleep(ctx, period) {
+				if _, err := redis.DecayHotKeywords(ctx, suggestConf.HotDecayFactor, period); err != nil {
+					zap.L().Error("衰减热搜词热度失败", zap.Error(err))
+				}
+			}
+		})
+	}
+}
+
+// RebuildSuggestIndex 使用商品标题、品牌名称、分类名称重建搜索补全前缀索引
+func RebuildSuggestIndex(ctx context.Context) error {
+	docs, err := mysql.SelectSearchDocuments(ctx, nil, nil)
+	if err != nil {
+		return err
+	}
+	// K: 索引key V: 展示文本集合
+	entries := make(map[string][]string)
+	// 已经加入索引的展示文本，避免同一个品牌、分类重复计算
+	seen := make(map[string]bool)
+	add := func(text string) {
+		text = strings.TrimSpace(text)
+		if text == "" || seen[text] {
+			return
+		}
+		seen[text] = true
+		for _, key := range search.SuggestKeys(text) {
+			entries[key] = append(entries[key], text)
+		}
+	}
+	for _, doc := range docs {
+		add(doc.Title)
+		add(doc.BrandName)
+		add(doc.CategoryName)
+	}
+	if err = redis.ReplaceSuggestIndex(ctx, entries); err != nil {
+		return err
+	}
+	logger.Ctx(ctx).Info("重建搜索补全前缀索引成功", zap.Int("count", len(seen)))
+	return nil
+}
+
+// RecordSearchKeyword 记录用户的搜索关键字，用于统计热搜词。被屏蔽或过长的关键字不记录
+func RecordSearchKeyword(ctx context.Context, keyword string) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" || utf8.RuneCountInString(keyword) > maxHotKeywordLength {
+		return
+	}
+	blocked, err := redis.GetBlockedKeywords(ctx)
+	if err != nil {
+		return
+	}
+	if isBlockedKeyword(keyword, blocked) {
+		return
+	}
+	_ = redis.IncrSearchKeyword(ctx, keyword)
+}
+
+// GetSearchSuggestions 根据用户输入的前缀获取搜索补全，支持中文、全拼以及拼音首字母前缀
+func GetSearchSuggestions(ctx context.Context, q string) ([]string, error) {
+	result := make([]string, 0)
+	prefix := search.NormalizeSuggest(q)
+	if prefix == "" {
+		return result, nil
+	}
+	size := getSuggestSize()
+	suggestions, err := redis.GetSuggestions(ctx, prefix, size*suggestScanFactor)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := redis.GetBlockedKeywords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(suggestions))
+	for _, suggestion := range suggestions {
+		if seen[suggestion] || isBlockedKeyword(suggestion, blocked) {
+			continue
+		}
+		seen[suggestion] = true
+		result = append(result, suggestion)
+		if int64(len(result)) == size {
+			break
+		}
+	}
+	return result, nil
+}
+
+// GetHotKeywords 获取热搜词，过滤被屏蔽的关键字
+func GetHotKeywords(ctx context.Context) ([]string, error) {
+	size := getSuggestSize()
+	keywords, err := redis.GetHotKeywords(ctx, size*2)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := redis.GetBlockedKeywords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, size)
+	for _, keyword := range keywords {
+		if isBlockedKeyword(keyword, blocked) {
+			continue
+		}
+		result = append(result, keyword)
+		if int64(len(result)) == size {
+			break
+		}
+	}
+	return result, nil
+}
+
+// GetBlockedKeywords 获取所有被屏蔽的搜索关键字
+func GetBlockedKeywords(ctx context.Context) ([]string, error) {
+	return redis.GetBlockedKeywords(ctx)
+}
+
+// BlockKeyword 屏蔽搜索关键字，包含该关键字的热搜词、搜索补全都不会再展示
+func BlockKeyword(ctx context.Context, keyword string) error {
+	return redis.AddBlockedKeyword(ctx, strings.TrimSpace(keyword))
+}
+
+// UnblockKeyword 取消屏蔽搜索关键字
+func UnblockKeyword(ctx context.Context, keyword string) error {
+	return redis.DelBlockedKeyword(ctx, strings.TrimSpace(keyword))
+}
+
+// getSuggestSize 获取搜索补全、热搜词返回的最大数量，未配置时默认为10
+func getSuggestSize() int64 {
+	if suggestConf.SuggestSize <= 0 {
+		return 10
+	}
+	return int64(suggestConf.SuggestSize)
+}
+
+// isBlockedKeyword 文本中是否包含被屏蔽的关键字(忽略大小写)
+func isBlockedKeyword(text string, blocked []string) bool {
+	text = strings.ToLower(text)
+	for _, keyword := range blocked {
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
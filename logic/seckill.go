@@ -1,11 +1,12 @@
 package logic
 
 import (
+	"context"
 	"shop-backend/dao/mysql"
 	"shop-backend/models/pojo"
 )
 
 // GetAllSecKillSku 获取所有正在秒杀的商品
-func GetAllSecKillSku() ([]*pojo.SecKillSku, error) {
-	return mysql.SelectAllSecKillSku()
+func GetAllSecKillSku(ctx context.Context) ([]*pojo.SecKillSku, error) {
+	return mysql.SelectAllSecKillSku(ctx)
 }
@@ -1,11 +1,14 @@
 package logic
 
 import (
+	"context"
 	"errors"
 	"github.com/DanPlayer/randomname"
 	"go.uber.org/zap"
 	"shop-backend/dao/mysql"
 	"shop-backend/dao/redis"
+	"shop-backend/errs"
+	"shop-backend/logger"
 	"shop-backend/models/dto"
 	"shop-backend/models/pojo"
 	"shop-backend/models/vo"
@@ -14,56 +17,56 @@ import (
 )
 
 var (
-	ErrorUserIsRegistered    = errors.New("用户已注册，请直接登录")
-	ErrorMustRequestCode     = errors.New("请先获取验证码")
+	ErrorUserIsRegistered    = errs.Conflict("用户已注册，请直接登录")
+	ErrorMustRequestCode     = errs.Validation("请先获取验证码")
 	ErrorRequestCodeFrequent = errors.New("验证码已发送，请注意查收")
-	ErrorWrongVerifyCode     = errors.New("验证码错误")
-	ErrorUserNotExist        = errors.New("用户不存在~")
+	ErrorWrongVerifyCode     = errs.Validation("验证码错误")
+	ErrorUserNotExist        = errs.NotFound("用户不存在~")
 	ErrorWrongPass           = errors.New("密码错误")
 	ErrorServeBusy           = errors.New("服务器繁忙")
 )
 
 // SendVerifyCode 生成验证码并缓存到Redis中
-func SendVerifyCode(phone string) (code string, err error) {
+func SendVerifyCode(ctx context.Context, phone string) (code string, err error) {
 	// 接口幂等性
-	code, err = redis.GetVerifyCode(phone)
+	code, err = redis.GetVerifyCode(ctx, phone)
 	if code != "" {
 		err = ErrorRequestCodeFrequent
-		zap.L().Error("用户频繁获取手机验证码", zap.String("phone", phone))
+		logger.Ctx(ctx).Error("用户频繁获取手机验证码", zap.String("phone", phone))
 		return
 	}
 	// 生成验证码
 	if code, err = gen.GenVerifyCode(); err != nil {
-		zap.L().Error("生成手机验证码失败", zap.Error(err))
+		logger.Ctx(ctx).Error("生成手机验证码失败", zap.Error(err))
 		return
 	}
 	// 缓存到Redis，并设置五分钟的存活时间
-	if err = redis.SetVerifyCode(phone, code); err != nil {
-		zap.L().Error("手机验证码缓存到Redis失败", zap.Error(err))
+	if err = redis.SetVerifyCode(ctx, phone, code); err != nil {
+		logger.Ctx(ctx).Error("手机验证码缓存到Redis失败", zap.Error(err))
 		return
 	}
 
 	// 将消息发送到RabbitMQ中，异步发送验证码
-	err = rabbitmq.SendSms(phone, code)
+	err = rabbitmq.SendSms(ctx, phone, code)
 	if err != nil {
-		zap.L().Error("发送验证码信息发送到RabbitMQ失败", zap.Error(err))
+		logger.Ctx(ctx).Error("发送验证码信息发送到RabbitMQ失败", zap.Error(err))
 		return
 	}
 	return
 }
 
 // SignUp 用户注册逻辑
-func SignUp(u *dto.SignUp) error {
+func SignUp(ctx context.Context, u *dto.SignUp) error {
 	// 到此，用户手机号格式一定是正确的
 	// 如果用户已经注册
-	if ok := mysql.SelectUserByPhone(u.Phone); ok {
+	if ok := mysql.SelectUserByPhone(ctx, u.Phone); ok {
 		return ErrorUserIsRegistered
 	}
 	// 通过手机号从Redis获取验证码
-	niceCode, err := redis.GetVerifyCode(u.Phone)
+	niceCode, err := redis.GetVerifyCode(ctx, u.Phone)
 	if err != nil {
 		// 如果获取验证码失败
-		zap.L().Error("redis.GetVerifyCode(u.Phone) failed", zap.Error(err))
+		logger.Ctx(ctx).Error("redis.GetVerifyCode(ctx, u.Phone) failed", zap.Error(err))
 		return err
 	}
 	if niceCode == "" {
@@ -86,14 +89,14 @@ func SignUp(u *dto.SignUp) error {
 	}
 
 	// 入库
-	err = mysql.InsertUser(user)
+	err = mysql.InsertUser(ctx, user)
 	return err
 }
 
 // Login 登录逻辑
-func Login(p *dto.Login) (uid int64, aToken, rToken string, err error) {
+func Login(ctx context.Context, p *dto.Login) (uid int64, aToken, rToken string, err error) {
 	var ok bool
-	ok = mysql.SelectUserByPhone(p.Phone)
+	ok = mysql.SelectUserByPhone(ctx, p.Phone)
 	if !ok {
 		// 如果用户不存在
 		err = ErrorUserNotExist
@@ -106,7 +109,7 @@ func Login(p *dto.Login) (uid int64, aToken, rToken string, err error) {
 		Password: p.Password,
 	}
 
-	if uid, ok = mysql.SelectUserByPhoneAndPass(user); !ok {
+	if uid, ok = mysql.SelectUserByPhoneAndPass(ctx, user); !ok {
 		// 用户输入密码错误
 		err = ErrorWrongPass
 		return
@@ -116,7 +119,7 @@ func Login(p *dto.Login) (uid int64, aToken, rToken string, err error) {
 	aToken, rToken, err = gen.GenToken(uid)
 
 	// 将AccessToken缓存到Redis中，用来完成同一时间只有一台设备可以登录
-	err = redis.SetAccessToken(uid, aToken)
+	err = redis.SetAccessToken(ctx, uid, aToken)
 	if err != nil {
 		// 插入AccessToken失败
 		err = ErrorServeBusy
@@ -126,29 +129,29 @@ func Login(p *dto.Login) (uid int64, aToken, rToken string, err error) {
 }
 
 // GetSomeInfo 返回简略信息
-func GetSomeInfo(uid int64) (info *vo.SomeInfoVO, err error) {
-	info, err = mysql.SelectSomeInfoByUID(uid)
+func GetSomeInfo(ctx context.Context, uid int64) (info *vo.SomeInfoVO, err error) {
+	info, err = mysql.SelectSomeInfoByUID(ctx, uid)
 	return
 }
 
 // GetUserInfos 获取用户详细信息
 // todo 邮箱登录功能未实现
-func GetUserInfos(uid int64) (infos *vo.UserInfosVO, err error) {
-	return mysql.SelectInfosByUID(uid)
+func GetUserInfos(ctx context.Context, uid int64) (infos *vo.UserInfosVO, err error) {
+	return mysql.SelectInfosByUID(ctx, uid)
 }
 
 // UpdateInfos 更新用户信息
-func UpdateInfos(infos *dto.Infos) (err error) {
-	return mysql.UpdateUserInfosByUID(infos)
+func UpdateInfos(ctx context.Context, infos *dto.Infos) (err error) {
+	return mysql.UpdateUserInfosByUID(ctx, infos)
 }
 
 // SignOut 用户退出操作，删除Redis中保存的AccessToken
-func SignOut(idStr string) (err error) {
-	err = redis.DelAccessTokenByUID(idStr)
+func SignOut(ctx context.Context, idStr string) (err error) {
+	err = redis.DelAccessTokenByUID(ctx, idStr)
 	return
 }
 
 // UpdateUserAvatar 修改用户头像
-func UpdateUserAvatar(uid int64, path string) error {
-	return mysql.UpdateAvatarByUID(uid, path)
+func UpdateUserAvatar(ctx context.Context, uid int64, path string) error {
+	return mysql.UpdateAvatarByUID(ctx, uid, path)
 }
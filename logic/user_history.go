@@ -0,0 +1,65 @@
+package logic
+
+import (
+	"context"
+	"shop-backend/dao/mysql"
+	"shop-backend/dao/redis"
+	"shop-backend/models/vo"
+	"strings"
+)
+
+// RecordSearchHistory 记录登录用户的搜索关键字，uid为0表示匿名用户，不记录
+func RecordSearchHistory(ctx context.Context, uid int64, keyword string) {
+	keyword = strings.TrimSpace(keyword)
+	if uid == 0 || keyword == "" {
+		return
+	}
+	_ = redis.AddSearchHistory(ctx, uid, keyword)
+}
+
+// RecordBrowseHistory 记录登录用户浏览的商品，uid为0表示匿名用户，不记录
+func RecordBrowseHistory(ctx context.Context, uid, skuID int64) {
+	if uid == 0 {
+		return
+	}
+	_ = redis.AddBrowseHistory(ctx, uid, skuID)
+}
+
+// GetSearchHistory 获取用户的搜索历史，最近搜索的在前
+func GetSearchHistory(ctx context.Context, uid int64) ([]string, error) {
+	return redis.GetSearchHistory(ctx, uid)
+}
+
+// ClearSearchHistory 清空用户的搜索历史
+func ClearSearchHistory(ctx context.Context, uid int64) error {
+	return redis.DelSearchHistory(ctx, uid)
+}
+
+// GetBrowseHistory 获取用户浏览过的商品，最近浏览的在前。已经删除的商品不再返回
+func GetBrowseHistory(ctx context.Context, uid int64) ([]*vo.ProductVO, error) {
+	skuIDs, err := redis.GetBrowseHistory(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	products, err := mysql.SelectProductBySkuIDs(ctx, skuIDs)
+	if err != nil {
+		return nil, err
+	}
+	// K: skuID V: 商品信息
+	productMap := make(map[int64]*vo.ProductVO, len(products))
+	for _, product := range products {
+		productMap[product.ID] = product
+	}
+	result := make([]*vo.ProductVO, 0, len(products))
+	for _, skuID := range skuIDs {
+		if product, ok := productMap[skuID]; ok {
+			result = append(result, product)
+		}
+	}
+	return result, nil
+}
+
+// ClearBrowseHistory 清空用户的浏览历史
+func ClearBrowseHistory(ctx context.Context, uid int64) error {
+	return redis.DelBrowseHistory(ctx, uid)
+}
@@ -1,9 +1,11 @@
 package logic
 
 import (
-	"go.uber.org/zap"
+	"context"
 	"shop-backend/dao/mysql"
 	"shop-backend/dao/redis"
+	"shop-backend/logger"
+	"shop-backend/metrics"
 	"shop-backend/models/dto"
 	"shop-backend/models/pojo"
 	"shop-backend/models/vo"
@@ -11,18 +13,20 @@ import (
 )
 
 // GetAllAddress 获取数据库中所有的地址信息
-func GetAllAddress() ([]*vo.PCDDicVO, error) {
+func GetAllAddress(ctx context.Context) ([]*vo.PCDDicVO, error) {
 	// 先从缓存中获取地址信息
-	list, err := redis.GetAllAddress()
+	list, err := redis.GetAllAddress(ctx)
 	if err == nil && len(list) > 0 {
 		// 缓存中的数据可用
-		zap.L().Info("成功使用缓存中的地址信息")
+		metrics.CacheHit(metrics.CacheAddress)
+		logger.Ctx(ctx).Info("成功使用缓存中的地址信息")
 		return list, nil
 	}
 
-	zap.L().Info("未成功使用缓存中的地址信息")
+	metrics.CacheMiss(metrics.CacheAddress)
+	logger.Ctx(ctx).Info("未成功使用缓存中的地址信息")
 	// 所有地址信息
-	addresses, err := mysql.SelectAllAddress()
+	addresses, err := mysql.SelectAllAddress(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -51,34 +55,34 @@ func GetAllAddress() ([]*vo.PCDDicVO, error) {
 	}
 
 	// 存入缓存中
-	_ = redis.SetAllAddress(data)
+	_ = redis.SetAllAddress(ctx, data)
 	return data, nil
 }
 
 // AddReceiverAddress 添加用户收货地址
-func AddReceiverAddress(address *dto.ReceiverAddress, uid int64) error {
-	return mysql.InsertReceiverAddress(createReceiverAddressPojo(address, uid))
+func AddReceiverAddress(ctx context.Context, address *dto.ReceiverAddress, uid int64) error {
+	return mysql.InsertReceiverAddress(ctx, createReceiverAddressPojo(address, uid))
 }
 
 // UpdateReceiverAddress 修改用户收货地址
-func UpdateReceiverAddress(address *dto.ReceiverAddress, uid int64) error {
+func UpdateReceiverAddress(ctx context.Context, address *dto.ReceiverAddress, uid int64) error {
 	addressPojo := createReceiverAddressPojo(address, uid)
 	id, _ := strconv.ParseInt(address.ID, 10, 64)
 	addressPojo.ID = id
 	if address.DefaultStatus == 1 {
 		// 用户要将该地址设置为默认地址
 		// 此时应该将用户的其他地址的状态都设置为2(不是默认地址)
-		return mysql.UpdateDefaultReceiverAddress(addressPojo)
+		return mysql.UpdateDefaultReceiverAddress(ctx, addressPojo)
 	} else {
 		// 修改用户收货地址信息
-		return mysql.UpdateReceiverAddress(addressPojo)
+		return mysql.UpdateReceiverAddress(ctx, addressPojo)
 	}
 }
 
 // GetPersonAllAddress 获取用户所有的收货地址
-func GetPersonAllAddress(uid int64) ([]*vo.AddressVO, error) {
+func GetPersonAllAddress(ctx context.Context, uid int64) ([]*vo.AddressVO, error) {
 	// 此时的地址结构体只包含区县ID，需要找出所在的省和市
-	addresses, err := mysql.SelectPersonAllAddress(uid)
+	addresses, err := mysql.SelectPersonAllAddress(ctx, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -95,21 +99,21 @@ func GetPersonAllAddress(uid int64) ([]*vo.AddressVO, error) {
 			DetailAddress: address.DetailAddress,
 		}
 		// 区
-		region, err := mysql.SelectPCDByID(address.CountyID)
+		region, err := mysql.SelectPCDByID(ctx, address.CountyID)
 		if err != nil {
 			return nil, err
 		}
 		addressVO.Region = region.Name
 
 		// 市
-		city, err := mysql.SelectPCDByID(region.ParentID)
+		city, err := mysql.SelectPCDByID(ctx, region.ParentID)
 		if err != nil {
 			return nil, err
 		}
 		addressVO.City = city.Name
 
 		// 省
-		province, err := mysql.SelectPCDByID(city.ParentID)
+		province, err := mysql.SelectPCDByID(ctx, city.ParentID)
 		if err != nil {
 			return nil, err
 		}
@@ -121,8 +125,8 @@ func GetPersonAllAddress(uid int64) ([]*vo.AddressVO, error) {
 }
 
 // DelReceiverAddress 删除用户的一条收货地址
-func DelReceiverAddress(id int, uid int64) error {
-	return mysql.DelReceiverAddress(id, uid)
+func DelReceiverAddress(ctx context.Context, id int, uid int64) error {
+	return mysql.DelReceiverAddress(ctx, id, uid)
 }
 
 // 创建ReceiverAddress POJO结构体
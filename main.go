@@ -11,10 +11,14 @@ import (
 	"shop-backend/canal"
 	"shop-backend/dao/mysql"
 	"shop-backend/dao/redis"
+	"shop-backend/lifecycle"
 	"shop-backend/logger"
+	"shop-backend/logic"
 	"shop-backend/rabbitmq"
 	"shop-backend/router"
+	"shop-backend/search"
 	"shop-backend/settings"
+	"shop-backend/tracing"
 	"shop-backend/utils/gen"
 	"shop-backend/utils/oss"
 	"shop-backend/utils/pay"
@@ -45,19 +49,38 @@ func main() {
 	defer zap.L().Sync()
 	zap.L().Debug("logger init success...")
 
+	// 初始化链路追踪，最先初始化的子系统最后关闭，导出其他子系统关闭时产生的span
+	if err := tracing.Init(settings.Conf.Name, settings.Conf.Version, settings.Conf.TracingConfig); err != nil {
+		fmt.Printf("init tracing failed, err:%v\n", err)
+		return
+	}
+
+	// 服务退出或初始化失败时，按照注册的阶段依次关闭已经启动的子系统
+	shutdownTimeout := time.Duration(settings.Conf.ShutdownTimeout) * time.Second
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	defer lifecycle.Shutdown(shutdownTimeout)
+
 	// 初始化MySQL连接
 	if err := mysql.Init(settings.Conf.MySQLConfig); err != nil {
 		fmt.Printf("init logger failed, err:%v\n", err)
 		return
 	}
-	defer mysql.Close()
+	lifecycle.Register("MySQL", lifecycle.PhaseStorage, func(context.Context) error {
+		mysql.Close()
+		return nil
+	})
 
 	// 初始化Redis连接
 	if err := redis.Init(settings.Conf.RedisConfig); err != nil {
 		fmt.Printf("init logger failed, err:%v\n", err)
 		return
 	}
-	defer redis.Close()
+	lifecycle.Register("Redis", lifecycle.PhaseStorage, func(context.Context) error {
+		redis.Close()
+		return nil
+	})
 
 	// 初始化雪花算法
 	if err := gen.Init(settings.Conf.StartTime, settings.Conf.MachineId); err != nil {
@@ -77,15 +100,27 @@ func main() {
 		return
 	}
 
-	// 初始化RabbitMQ
-	go rabbitmq.Init(settings.Conf.RabbitMQConfig)
+	// 初始化消息总线(RabbitMQ)，连接在后台建立
+	if err := rabbitmq.Init(settings.Conf.RabbitMQConfig); err != nil {
+		fmt.Printf("init rabbitmq failed, err:%v\n", err)
+		return
+	}
 
-	// 初始化Canal
-	go canal.Init(settings.Conf.CanalConfig)
+	// 初始化数据库变更监听(canal或轮询)
+	if err := canal.Init(settings.Conf.CanalConfig); err != nil {
+		fmt.Printf("init canal failed, err:%v\n", err)
+		return
+	}
 
 	// 初始化支付模块
 	go pay.Init(settings.Conf.AliPayConfig)
 
+	// 初始化商品全文索引
+	search.Init(settings.Conf.SearchConfig)
+
+	// 初始化搜索补全、热搜词
+	logic.InitSearchSuggest(settings.Conf.SearchConfig)
+
 	// 注册路由
 	r := router.SetupRouter(settings.Conf.Mode)
 
@@ -94,6 +129,8 @@ func main() {
 		Addr:    fmt.Sprintf(":%d", settings.Conf.Port),
 		Handler: r,
 	}
+	// 最先停止接收新的请求，并等待正在处理的请求处理完成
+	lifecycle.Register("HTTP服务", lifecycle.PhaseIntake, srv.Shutdown)
 	go func() {
 		// 开启一个goroutine启动服务
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -101,7 +138,7 @@ func main() {
 		}
 	}()
 
-	// 等待中断信号来优雅地关闭服务器，为关闭服务器操作设置一个5秒的超时
+	// 等待中断信号来优雅地关闭服务
 	quit := make(chan os.Signal, 1)
 	// kill 默认会发送 syscall.SIGTERM 信号
 	// kill -2 发送syscall.SIGINT信号，CTRL + C 就是触发系统SIGINT信号
@@ -109,13 +146,10 @@ func main() {
 	// signal.Notify把收到的 syscall.SIGINT或syscall.SIGTERM信号转发给quit
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 	<-quit // 阻塞在此，当收到上述两种信号时才会往下执行
-	zap.L().Info("Shutdown Server...")
-	// 创建一个5秒超时的context
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	// 5秒内优雅关闭服务（将未处理完的请求处理完再关闭服务），超过5秒就会超时退出
-	if err := srv.Shutdown(ctx); err != nil {
-		zap.L().Fatal("Server shutdown", zap.Error(err))
+	zap.L().Info("Shutdown Server...", zap.Duration("timeout", shutdownTimeout))
+	// 依次停止接收请求和数据、等待正在处理的请求和消息、发送发件箱中剩余的消息、关闭RabbitMQ、Redis、MySQL
+	if err := lifecycle.Shutdown(shutdownTimeout); err != nil {
+		zap.L().Error("Server shutdown", zap.Error(err))
 	}
 	zap.L().Info("Server exiting...")
 }
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+)
+
+// 所有指标的前缀
+const namespace = "shop"
+
+// 缓存名称
+const (
+	CacheCart     = "cart"
+	CacheCategory = "category"
+	CacheAddress  = "address"
+	CacheSpec     = "spec"
+	CacheDetail   = "detail"
+)
+
+var (
+	// HTTP请求耗时，按照路由、方法、状态码区分。未匹配到路由的请求route为unmatched
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求耗时",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"route", "method", "status"})
+
+	// 缓存命中、未命中次数
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Redis缓存命中(hit)、未命中(miss)次数",
+	}, []string{"cache", "result"})
+
+	// 发送到交换机的消息数量，result为success或failure
+	mqPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_published_total",
+		Help:      "发送到交换机的消息数量",
+	}, []string{"exchange", "routing_key", "result"})
+
+	// 接收者处理的消息数量，result为success或failure
+	mqConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_consumed_total",
+		Help:      "接收者处理的消息数量",
+	}, []string{"queue", "result"})
+
+	// 处理失败后发送到延时重试队列的消息数量
+	mqRetried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_retried_total",
+		Help:      "处理失败后延时重试的消息数量",
+	}, []string{"queue"})
+
+	// 失败次数达到上限后进入死信队列的消息数量
+	mqDeadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_dead_lettered_total",
+		Help:      "进入死信队列的消息数量",
+	}, []string{"queue"})
+
+	ordersSubmitted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_submitted_total",
+		Help:      "提交的订单数量",
+	})
+
+	ordersPaid = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_paid_total",
+		Help:      "支付完成的订单数量",
+	})
+
+	ordersTimedOut = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_timed_out_total",
+		Help:      "超时未支付被回滚的订单数量",
+	})
+
+	// 秒杀请求数量，result为accepted(已进入秒杀队列)或rejected(队列已满等原因被拒绝)
+	secKillRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "seckill_requests_total",
+		Help:      "秒杀请求数量",
+	}, []string{"result"})
+
+	// 发送的短信数量，result为success或failure
+	smsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sms_sent_total",
+		Help:      "发送的短信数量",
+	}, []string{"result"})
+)
+
+// Handler 以Prometheus的格式输出所有指标
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTPRequest 记录一次HTTP请求的耗时(秒)
+func ObserveHTTPRequest(route, method string, status int, seconds float64) {
+	httpRequestDuration.WithLabelValues(route, method, strconv.Itoa(status)).Observe(seconds)
+}
+
+// CacheHit 缓存命中
+func CacheHit(cache string) {
+	cacheRequests.WithLabelValues(cache, "hit").Inc()
+}
+
+// CacheMiss 缓存未命中，需要查询数据库
+func CacheMiss(cache string) {
+	cacheRequests.WithLabelValues(cache, "miss").Inc()
+}
+
+// MQPublished 记录count条消息的发送结果
+func MQPublished(exchange, routingKey string, count int, err error) {
+	mqPublished.WithLabelValues(exchange, routingKey, result(err == nil)).Add(float64(count))
+}
+
+// MQConsumed 记录接收者处理一条消息的结果
+func MQConsumed(queue string, ok bool) {
+	mqConsumed.WithLabelValues(queue, result(ok)).Inc()
+}
+
+// MQRetried 一条消息被发送到延时重试队列
+func MQRetried(queue string) {
+	mqRetried.WithLabelValues(queue).Inc()
+}
+
+// MQDeadLettered 一条消息进入死信队列
+func MQDeadLettered(queue string) {
+	mqDeadLettered.WithLabelValues(queue).Inc()
+}
+
+// OrderSubmitted 提交了一个订单
+func OrderSubmitted() {
+	ordersSubmitted.Inc()
+}
+
+// OrderPaid 一个订单支付完成
+func OrderPaid() {
+	ordersPaid.Inc()
+}
+
+// OrderTimedOut 一个订单超时未支付，已回滚库存
+func OrderTimedOut() {
+	ordersTimedOut.Inc()
+}
+
+// SecKillAccepted 秒杀请求进入秒杀队列
+func SecKillAccepted() {
+	secKillRequests.WithLabelValues("accepted").Inc()
+}
+
+// SecKillRejected 秒杀请求被拒绝
+func SecKillRejected() {
+	secKillRequests.WithLabelValues("rejected").Inc()
+}
+
+// SmsSent 记录一条短信的发送结果
+func SmsSent(err error) {
+	smsSent.WithLabelValues(result(err == nil)).Inc()
+}
+
+func result(ok bool) string {
+	if ok {
+		return "success"
+	}
+	return "failure"
+}
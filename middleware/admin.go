@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"shop-backend/controller"
+	"shop-backend/logger"
+	"shop-backend/settings"
+)
+
+// AdminAuthMiddleware 后台管理员鉴权中间件，需要在JWTAuthMiddleware之后使用
+func AdminAuthMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		uid := c.GetInt64(CtxUserIdKey)
+		if settings.Conf.AdminConfig != nil {
+			for _, adminID := range settings.Conf.AdminConfig.UserIDs {
+				if adminID == uid {
+					c.Next()
+					return
+				}
+			}
+		}
+		// 不是管理员
+		logger.Ctx(c.Request.Context()).Warn("非管理员用户访问后台接口", zap.Int64("uid", uid), zap.String("path", c.Request.URL.Path))
+		controller.ResponseError(c, controller.CodeNoPermission)
+		c.Abort()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"go.uber.org/zap"
 	"shop-backend/controller"
 	"shop-backend/dao/redis"
+	"shop-backend/logger"
 	"shop-backend/utils/check"
 	"shop-backend/utils/gen"
 	"strings"
@@ -23,7 +24,7 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 		authHeader := c.Request.Header.Get("Authorization")
 		if authHeader == "" {
 			// 未携带Token
-			zap.L().Error("c.Request.Header.Get(\"Authorization\") is nil")
+			logger.Ctx(c.Request.Context()).Error("c.Request.Header.Get(\"Authorization\") is nil")
 			controller.ResponseError(c, controller.CodeTokenIsEmpty)
 			c.Abort()
 			return
@@ -33,7 +34,7 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			// 携带的Token格式有误
-			zap.L().Error("trings.SplitN(authHeader, \" \", 2) failed", zap.String("authHeader", authHeader))
+			logger.Ctx(c.Request.Context()).Error("trings.SplitN(authHeader, \" \", 2) failed", zap.String("authHeader", authHeader))
 			controller.ResponseError(c, controller.CodeTokenIsWrongFormat)
 			c.Abort()
 			return
@@ -48,11 +49,11 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 		if err != nil {
 			if err == check.ErrorATokenExpired {
 				// 如果错误类型为accessToken过期错误，那么需要使用refreshToken协助刷新
-				zap.L().Info("accessToken is expired", zap.Int64("uid", mc.UserID))
+				logger.Ctx(c.Request.Context()).Info("accessToken is expired", zap.Int64("uid", mc.UserID))
 				_, err = check.CheckToken(refreshToken)
 				if err != nil {
 					// 如果解析refreshToken出现错误
-					zap.L().Error("parseRefreshToken error", zap.Int64("uid", mc.UserID))
+					logger.Ctx(c.Request.Context()).Error("parseRefreshToken error", zap.Int64("uid", mc.UserID))
 					controller.ResponseError(c, controller.CodeNeedReLogin)
 					c.Abort()
 					return
@@ -61,7 +62,7 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 					newToken, _, err = gen.GenToken(mc.UserID)
 					if err != nil {
 						// 生成AccessToken失败
-						zap.L().Error("use refreshToken refresh accessToken failed")
+						logger.Ctx(c.Request.Context()).Error("use refreshToken refresh accessToken failed")
 						controller.ResponseError(c, controller.CodeTokenRefreshFailed)
 						c.Abort()
 						return
@@ -74,7 +75,7 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 				}
 			} else {
 				// 解析失败，Token不合法
-				zap.L().Error("received an illegal token", zap.String("accessToken", accessToken))
+				logger.Ctx(c.Request.Context()).Error("received an illegal token", zap.String("accessToken", accessToken))
 				controller.ResponseError(c, controller.CodeTokenIsInvalid)
 				c.Abort()
 				return
@@ -84,6 +85,30 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 		// 将JWT中携带的用户ID和AccessToken存到中间件链路中
 		c.Set(CtxUserIdKey, mc.UserID)
 		c.Set(CtxAToken, accessToken)
+		setLoggerUID(c, mc.UserID)
+		c.Next()
+	}
+}
+
+// JWTOptionalMiddleware 可选的JWT认证中间件，用于匿名用户也可以访问的接口。
+// 携带了合法且未过期的AccessToken时，将用户ID存到中间件链路中；未携带或者Token不合法时按照匿名用户继续处理，不会中断请求
+func JWTOptionalMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		authHeader := c.Request.Header.Get("Authorization")
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.Next()
+			return
+		}
+		accessToken := strings.SplitN(parts[1], "&", 2)[0]
+		mc, err := check.CheckToken(accessToken)
+		if err != nil {
+			c.Next()
+			return
+		}
+		c.Set(CtxUserIdKey, mc.UserID)
+		c.Set(CtxAToken, accessToken)
+		setLoggerUID(c, mc.UserID)
 		c.Next()
 	}
 }
@@ -92,7 +117,7 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 func JWTLimitLoginMiddleware() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		// 从Redis中获取AccessToken
-		token, err := redis.GetAccessTokenByUID(c.GetString(CtxUserIdKey))
+		token, err := redis.GetAccessTokenByUID(c.Request.Context(), c.GetString(CtxUserIdKey))
 		if err != nil {
 			// 获取失败
 			controller.ResponseError(c, controller.CodeServeBusy)
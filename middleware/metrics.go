@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"shop-backend/metrics"
+	"time"
+)
+
+// Metrics 记录每个路由的请求耗时以及状态码，路由使用注册时的路径(例如/api/pms/product/detail/:skuID)，避免路径参数导致指标过多
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(route, c.Request.Method, c.Writer.Status(), time.Since(start).Seconds())
+	}
+}
@@ -17,7 +17,7 @@ func RateLimitMiddleware() func(c *gin.Context) {
 		// 如果取不到令牌，最大等待5秒。如果5秒后仍然没有取到令牌，则中断本次请求
 		_, ok := bucket.TakeMaxDuration(1, time.Second*5)
 		if !ok {
-			controller.ResponseBadError(c, controller.CodeToManyRequest)
+			controller.ResponseError(c, controller.CodeToManyRequest)
 			c.Abort()
 			return
 		}
@@ -9,10 +9,10 @@ import (
 // RateLimitRedisMiddleware 限制用户5秒钟只能抢购一次商品
 func RateLimitRedisMiddleware() func(c *gin.Context) {
 	return func(c *gin.Context) {
-		ok := redis.SetNXSecKillUID(c.GetInt64("uid"))
+		ok := redis.SetNXSecKillUID(c.Request.Context(), c.GetInt64("uid"))
 		if !ok {
 			// 用户已经在5秒钟内抢购过商品
-			controller.ResponseBadError(c, controller.CodeToManyRequest)
+			controller.ResponseError(c, controller.CodeToManyRequest)
 			c.Abort()
 			return
 		}
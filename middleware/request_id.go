@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/utils/gen"
+)
+
+// 上游传递的请求ID的最大长度
+const maxRequestIDLength = 64
+
+// RequestID 请求ID中间件。沿用上游传递的X-Request-ID，没有或者不合法时生成新的请求ID，
+// 写入响应头，并将带有请求ID、trace_id的日志放入c.Request.Context()
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(logger.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = gen.GenRequestID()
+		}
+		c.Header(logger.RequestIDHeader, requestID)
+
+		ctx := logger.WithRequestID(c.Request.Context(), requestID)
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			ctx = logger.With(ctx, zap.String("trace_id", spanContext.TraceID().String()))
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// validRequestID 请求ID只能包含字母、数字、-、_、.，避免日志注入以及无法写入消息头
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// setLoggerUID 将用户ID加入请求的日志
+func setLoggerUID(c *gin.Context, uid int64) {
+	c.Request = c.Request.WithContext(logger.With(c.Request.Context(), zap.Int64(CtxUserIdKey, uid)))
+}
@@ -3,6 +3,48 @@
 SET NAMES utf8mb4;
 SET FOREIGN_KEY_CHECKS = 0;
 
+-- ----------------------------
+-- Table structure for cdc_tombstone
+-- ----------------------------
+DROP TABLE IF EXISTS `cdc_tombstone`;
+CREATE TABLE `cdc_tombstone`  (
+                                  `id` bigint NOT NULL AUTO_INCREMENT,
+                                  `table_name` varchar(64) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL COMMENT '被删除行所在的表',
+                                  `row_data` text CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL COMMENT '被删除行的列，json对象',
+                                  `created_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                                  PRIMARY KEY (`id`) USING BTREE,
+                                  INDEX `idx_created_time`(`created_time`) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '删除墓碑表(轮询模式)' ROW_FORMAT = Dynamic;
+
+-- ----------------------------
+-- Records of cdc_tombstone
+-- ----------------------------
+
+-- ----------------------------
+-- Table structure for mq_outbox
+-- ----------------------------
+DROP TABLE IF EXISTS `mq_outbox`;
+CREATE TABLE `mq_outbox`  (
+                              `id` bigint NOT NULL COMMENT '消息ID(雪花算法生成)',
+                              `exchange` varchar(128) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL COMMENT '交换机名称',
+                              `routing_key` varchar(128) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL COMMENT '路由',
+                              `body` text CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL COMMENT '消息体，json',
+                              `headers` varchar(512) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL DEFAULT '' COMMENT '链路追踪信息，json对象',
+                              `expiration` varchar(16) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL DEFAULT '' COMMENT '消息过期时间(毫秒)，从创建时间开始计算',
+                              `status` tinyint NOT NULL DEFAULT 0 COMMENT '状态：0->待发送；1->已发送',
+                              `attempts` int NOT NULL DEFAULT 0 COMMENT '发送失败的次数',
+                              `next_retry_time` datetime NOT NULL COMMENT '下一次可以发送的时间',
+                              `sent_time` datetime NULL DEFAULT NULL COMMENT '发送成功的时间',
+                              `created_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                              PRIMARY KEY (`id`) USING BTREE,
+                              INDEX `idx_status_next_retry_time`(`status`, `next_retry_time`) USING BTREE,
+                              INDEX `idx_status_sent_time`(`status`, `sent_time`) USING BTREE
+) ENGINE = InnoDB CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '消息发件箱表' ROW_FORMAT = Dynamic;
+
+-- ----------------------------
+-- Records of mq_outbox
+-- ----------------------------
+
 -- ----------------------------
 -- Table structure for oms_cart
 -- ----------------------------
@@ -154,6 +196,72 @@ CREATE TABLE `pms_brand`  (
 -- Records of pms_brand
 -- ----------------------------
 
+-- ----------------------------
+-- Table structure for pms_comment
+-- ----------------------------
+DROP TABLE IF EXISTS `pms_comment`;
+CREATE TABLE `pms_comment`  (
+                                `id` bigint NOT NULL COMMENT '评价ID(雪花算法生成)',
+                                `spu_id` bigint NOT NULL COMMENT '商品spuID(对应商品spu表主键ID)',
+                                `sku_id` bigint NOT NULL COMMENT '商品skuID(对应商品sku表主键ID)',
+                                `order_id` bigint NOT NULL COMMENT '订单ID(对应订单表主键ID)',
+                                `order_item_id` bigint NOT NULL COMMENT '订单明细ID(对应订单明细表主键ID)',
+                                `user_id` bigint NOT NULL COMMENT '用户ID(对应用户表主键ID)',
+                                `star` tinyint NOT NULL COMMENT '评分：1~5星',
+                                `content` varchar(500) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL DEFAULT '' COMMENT '评价内容',
+                                `pics` varchar(2000) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL DEFAULT '[]' COMMENT '评价图片URL，json数组',
+                                `has_pic` tinyint NOT NULL DEFAULT 0 COMMENT '是否有图：0->无图；1->有图',
+                                `helpful_count` int NOT NULL DEFAULT 0 COMMENT '有用票数',
+                                `reply` varchar(500) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL DEFAULT '' COMMENT '商家回复',
+                                `reply_time` datetime NULL DEFAULT NULL COMMENT '商家回复时间',
+                                `created_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                                `updated_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '修改时间',
+                                PRIMARY KEY (`id`) USING BTREE,
+                                UNIQUE INDEX `uk_order_item_id`(`order_item_id`) USING BTREE,
+                                INDEX `idx_spu_id_created_time`(`spu_id`, `created_time`) USING BTREE,
+                                INDEX `idx_spu_id_helpful_count`(`spu_id`, `helpful_count`) USING BTREE
+) ENGINE = InnoDB CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '商品评价表' ROW_FORMAT = Dynamic;
+
+-- ----------------------------
+-- Records of pms_comment
+-- ----------------------------
+
+-- ----------------------------
+-- Table structure for pms_comment_append
+-- ----------------------------
+DROP TABLE IF EXISTS `pms_comment_append`;
+CREATE TABLE `pms_comment_append`  (
+                                       `id` bigint NOT NULL COMMENT '追加评价ID(雪花算法生成)',
+                                       `comment_id` bigint NOT NULL COMMENT '评价ID(对应商品评价表主键ID)',
+                                       `user_id` bigint NOT NULL COMMENT '用户ID(对应用户表主键ID)',
+                                       `content` varchar(500) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL DEFAULT '' COMMENT '追加评价内容',
+                                       `pics` varchar(2000) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL DEFAULT '[]' COMMENT '追加评价图片URL，json数组',
+                                       `created_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                                       PRIMARY KEY (`id`) USING BTREE,
+                                       INDEX `idx_comment_id`(`comment_id`) USING BTREE
+) ENGINE = InnoDB CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '商品追加评价表' ROW_FORMAT = Dynamic;
+
+-- ----------------------------
+-- Records of pms_comment_append
+-- ----------------------------
+
+-- ----------------------------
+-- Table structure for pms_comment_helpful
+-- ----------------------------
+DROP TABLE IF EXISTS `pms_comment_helpful`;
+CREATE TABLE `pms_comment_helpful`  (
+                                        `id` bigint NOT NULL AUTO_INCREMENT,
+                                        `comment_id` bigint NOT NULL COMMENT '评价ID(对应商品评价表主键ID)',
+                                        `user_id` bigint NOT NULL COMMENT '投票的用户ID(对应用户表主键ID)',
+                                        `created_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                                        PRIMARY KEY (`id`) USING BTREE,
+                                        UNIQUE INDEX `uk_comment_id_user_id`(`comment_id`, `user_id`) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '商品评价有用投票表' ROW_FORMAT = Dynamic;
+
+-- ----------------------------
+-- Records of pms_comment_helpful
+-- ----------------------------
+
 -- ----------------------------
 -- Table structure for pms_product_attribute
 -- ----------------------------
@@ -654,6 +762,7 @@ CREATE TABLE `pms_spu`  (
                             `brand_id` bigint NULL DEFAULT NULL COMMENT '品牌ID(对应品牌表主键ID)',
                             `cid1` bigint NULL DEFAULT NULL COMMENT '一级分类ID(对应商品分类表主键ID)',
                             `cid2` bigint NULL DEFAULT NULL COMMENT '二级分类ID(对应商品分类表主键ID)',
+                            `category_id` bigint NOT NULL DEFAULT 0 COMMENT '末级分类ID(对应商品分类表主键ID)',
                             `name` varchar(64) CHARACTER SET utf8 COLLATE utf8_general_ci NOT NULL COMMENT '商品名称',
                             `sub_title` varchar(255) CHARACTER SET utf8 COLLATE utf8_general_ci NULL DEFAULT NULL COMMENT '副标题',
                             `sale` int NULL DEFAULT NULL COMMENT '商品总销量',
@@ -668,24 +777,25 @@ CREATE TABLE `pms_spu`  (
                             `created_time` datetime NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
                             `updated_time` datetime NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '修改时间',
                             PRIMARY KEY (`id`) USING BTREE,
-                            INDEX `idx_pic_url`(`default_pic_url`) USING BTREE
+                            INDEX `idx_pic_url`(`default_pic_url`) USING BTREE,
+                            INDEX `idx_category_id`(`category_id`) USING BTREE
 ) ENGINE = InnoDB AUTO_INCREMENT = 13 CHARACTER SET = utf8 COLLATE = utf8_general_ci COMMENT = '商品spu表' ROW_FORMAT = Dynamic;
 
 -- ----------------------------
 -- Records of pms_spu
 -- ----------------------------
-INSERT INTO `pms_spu` VALUES (1, 1, 1, 13, '加拿大原装进口百加世NOW FRESH 无谷小型犬全龄配方粮', '加拿大原装进口 无谷粮 四叶形颗粒', 35000, 4, 541, '{\"规格\":[\"6磅\",\"12磅\",\"25磅\"]}', 279.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/1-1-1.jpg', 1, 1, 1, '2020-08-31 01:50:44', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (2, 17, 3, 21, '伊丽Elite 清爽冰垫', '直径40cm 炎热夏日 物理降温 冰凉持久 可防置冰箱', 550, 3, 203, '{\"规格\":[\"S|哈皮骨头\",\"S|围巾猫咪\",\"S|小鱼肥猫\",\"L|哈皮骨头\",\"L|小鱼肥猫\"]}', 22.90, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/2-1-1.jpg', 1, 1, 1, '2020-08-30 01:50:44', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (3, 13, 1, 13, '加拿大原装进口纽顿 无谷低升糖系列 去骨鳟鱼&三文鱼小型全犬粮', 'WDJ推荐 无谷低升糖草本组合配方 放心食材 消化易吸收', 0, 4, 7, '{\"规格\":[\"1.82kg\",\"6kg\"]}', 198.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/3-1-1.jpg', 1, 1, 1, '2020-10-16 08:47:16', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (4, 18, 1, 13, '加拿大原装进口 爱肯拿Acana 无谷深海鱼配方全犬粮', '渴望狗粮同厂出品 增加含肉量 低过敏 健肤美毛', 100, 4, 87, '{\"规格\":[\"2kg\",\"6kg\",\"11.4kg\"]}', 225.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/4-1-1.jpg', 1, 1, 1, '2020-10-16 09:25:33', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (5, 19, 1, 14, '伯纳天纯 低敏中大型犬成犬粮', '健胃促吸收 蓬松亮毛 体态优化 健骨护关节', 22950, 4, 20003, '{\"规格\":[\"4kg\",\"10kg\",\"15kg\"]}', 199.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/5-1-1.jpg', 1, 1, 1, '2020-10-16 15:09:15', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (6, 20, 1, 13, '美国原装进口 Instinct生鲜本能 无谷系列 鸡肉配方全犬粮', '中国B2C平台合作伙伴 生肉喷涂 营养增食欲 高量蛋白质 美毛健肤', 6, 3, 6, '{\"规格\":[\"22.5磅\"]}', 709.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/6-1-1.jpg', 1, 1, 1, '2020-10-19 15:58:31', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (7, 21, 1, 13, '加拿大原装进口 原始猎食渴望 无谷配方 成犬粮', '肉含量85% 高蛋白+低醣+低碳水 低敏易消化', 1964, 4, 566, '{\"规格\":[\"2kg\",\"6kg\",\"11.4kg\"]}', 240.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/7-1-1.jpg', 1, 1, 1, '2020-10-19 16:11:37', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (8, 2, 1, 14, '海尔仕 香酥牛肉味全犬种成犬粮', '全犬种适用 强壮骨骼 健肤亮毛 营养全面', 2432, 5, 3423, '{\"规格\":[\"10kg\"]}', 109.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/8-1-1.jpg', 1, 1, 1, '2020-10-26 15:48:13', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (9, 3, 1, 14, '皇家royal canin MIS30小型犬奶糕/怀孕母犬/哺乳母犬', '高蛋白子母粮 补充能量 均衡营养 好吸收易吸收', 1082, 5, 12466, '{\"规格\":[\"1kg\"]}', 79.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/9-1-1.jpg', 1, 1, 1, '2020-10-26 15:58:53', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (10, 4, 1, 14, '海洋之星 三文鱼配方 成犬粮 小颗粒', '源自英国品牌 挪威三文鱼 抗敏感美毛无谷天然粮 （非真空包装）', 810, 4, 13762, '{\"规格\":[\"6kg\"]}', 409.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/10-1-1.jpg', 1, 1, 1, '2020-10-26 16:04:47', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (11, 5, 1, 14, '醇粹Purich 经典系列 全价大型幼犬粮', '适合18月龄以下狗狗 合理膳食 呵护肠胃 强壮骨骼 促进发育', 216, 4, 2788, '{\"规格\":[\"15kg\"]}', 319.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/11-1-1.jpg', 1, 1, 1, '2020-10-26 16:10:13', '2022-10-23 15:20:36');
-INSERT INTO `pms_spu` VALUES (12, 6, 1, 14, '蓝氏LegendSandy 牛肉海洋鱼全犬粮', '缓解泪痕 美毛亮毛 调节肠胃 提高免疫力', 2554, 4, 15421, '{\"规格\":[\"9磅\"]}', 135.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/12-1-1.jpg', 1, 1, 1, '2020-10-26 16:18:48', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (1, 1, 1, 13, 13, '加拿大原装进口百加世NOW FRESH 无谷小型犬全龄配方粮', '加拿大原装进口 无谷粮 四叶形颗粒', 35000, 4, 541, '{\"规格\":[\"6磅\",\"12磅\",\"25磅\"]}', 279.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/1-1-1.jpg', 1, 1, 1, '2020-08-31 01:50:44', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (2, 17, 3, 21, 21, '伊丽Elite 清爽冰垫', '直径40cm 炎热夏日 物理降温 冰凉持久 可防置冰箱', 550, 3, 203, '{\"规格\":[\"S|哈皮骨头\",\"S|围巾猫咪\",\"S|小鱼肥猫\",\"L|哈皮骨头\",\"L|小鱼肥猫\"]}', 22.90, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/2-1-1.jpg', 1, 1, 1, '2020-08-30 01:50:44', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (3, 13, 1, 13, 13, '加拿大原装进口纽顿 无谷低升糖系列 去骨鳟鱼&三文鱼小型全犬粮', 'WDJ推荐 无谷低升糖草本组合配方 放心食材 消化易吸收', 0, 4, 7, '{\"规格\":[\"1.82kg\",\"6kg\"]}', 198.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/3-1-1.jpg', 1, 1, 1, '2020-10-16 08:47:16', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (4, 18, 1, 13, 13, '加拿大原装进口 爱肯拿Acana 无谷深海鱼配方全犬粮', '渴望狗粮同厂出品 增加含肉量 低过敏 健肤美毛', 100, 4, 87, '{\"规格\":[\"2kg\",\"6kg\",\"11.4kg\"]}', 225.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/4-1-1.jpg', 1, 1, 1, '2020-10-16 09:25:33', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (5, 19, 1, 14, 14, '伯纳天纯 低敏中大型犬成犬粮', '健胃促吸收 蓬松亮毛 体态优化 健骨护关节', 22950, 4, 20003, '{\"规格\":[\"4kg\",\"10kg\",\"15kg\"]}', 199.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/5-1-1.jpg', 1, 1, 1, '2020-10-16 15:09:15', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (6, 20, 1, 13, 13, '美国原装进口 Instinct生鲜本能 无谷系列 鸡肉配方全犬粮', '中国B2C平台合作伙伴 生肉喷涂 营养增食欲 高量蛋白质 美毛健肤', 6, 3, 6, '{\"规格\":[\"22.5磅\"]}', 709.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/6-1-1.jpg', 1, 1, 1, '2020-10-19 15:58:31', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (7, 21, 1, 13, 13, '加拿大原装进口 原始猎食渴望 无谷配方 成犬粮', '肉含量85% 高蛋白+低醣+低碳水 低敏易消化', 1964, 4, 566, '{\"规格\":[\"2kg\",\"6kg\",\"11.4kg\"]}', 240.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/7-1-1.jpg', 1, 1, 1, '2020-10-19 16:11:37', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (8, 2, 1, 14, 14, '海尔仕 香酥牛肉味全犬种成犬粮', '全犬种适用 强壮骨骼 健肤亮毛 营养全面', 2432, 5, 3423, '{\"规格\":[\"10kg\"]}', 109.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/8-1-1.jpg', 1, 1, 1, '2020-10-26 15:48:13', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (9, 3, 1, 14, 14, '皇家royal canin MIS30小型犬奶糕/怀孕母犬/哺乳母犬', '高蛋白子母粮 补充能量 均衡营养 好吸收易吸收', 1082, 5, 12466, '{\"规格\":[\"1kg\"]}', 79.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/9-1-1.jpg', 1, 1, 1, '2020-10-26 15:58:53', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (10, 4, 1, 14, 14, '海洋之星 三文鱼配方 成犬粮 小颗粒', '源自英国品牌 挪威三文鱼 抗敏感美毛无谷天然粮 （非真空包装）', 810, 4, 13762, '{\"规格\":[\"6kg\"]}', 409.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/10-1-1.jpg', 1, 1, 1, '2020-10-26 16:04:47', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (11, 5, 1, 14, 14, '醇粹Purich 经典系列 全价大型幼犬粮', '适合18月龄以下狗狗 合理膳食 呵护肠胃 强壮骨骼 促进发育', 216, 4, 2788, '{\"规格\":[\"15kg\"]}', 319.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/11-1-1.jpg', 1, 1, 1, '2020-10-26 16:10:13', '2022-10-23 15:20:36');
+INSERT INTO `pms_spu` VALUES (12, 6, 1, 14, 14, '蓝氏LegendSandy 牛肉海洋鱼全犬粮', '缓解泪痕 美毛亮毛 调节肠胃 提高免疫力', 2554, 4, 15421, '{\"规格\":[\"9磅\"]}', 135.00, 'https://pet-project-imgage.oss-cn-beijing.aliyuncs.com/pms/product/sku/12-1-1.jpg', 1, 1, 1, '2020-10-26 16:18:48', '2022-10-23 15:20:36');
 
 -- ----------------------------
 -- Table structure for ums_pcd_dic
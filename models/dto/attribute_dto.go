@@ -0,0 +1,23 @@
+package dto
+
+// Attribute 封装新增/修改商品属性的请求体
+type Attribute struct {
+	// 主键ID。新增属性不需要携带；修改属性时需要携带
+	ID string `json:"id"`
+	// 属性类型
+	Type uint8 `json:"type"`
+	// 父属性ID。新增属性名(属性分组)时为0，新增属性值时为属性名ID
+	ParentID int64 `json:"parentId,string"`
+	// 属性名称
+	Name string `json:"name" binding:"required"`
+	// 排序(越小越靠前)
+	Sort uint8 `json:"sort"`
+}
+
+// SpuAttribute 封装为商品spu分配属性值的请求体
+type SpuAttribute struct {
+	// spuID
+	SpuID int64 `json:"spuId,string" binding:"required"`
+	// 属性值ID数组，会覆盖spu原有的属性值
+	ProductAttributeIds []int64 `json:"productAttributeIds"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+// Brand 封装新增/修改商品品牌的请求体
+type Brand struct {
+	// 主键ID。新增品牌不需要携带；修改品牌时需要携带
+	ID string `json:"id"`
+	// 品牌名称
+	Name string `json:"name" binding:"required"`
+	// 品牌首字母
+	FirstLetter string `json:"firstLetter"`
+	// 品牌Logo URL(先调用上传Logo接口获取)
+	Logo string `json:"logo"`
+	// 品牌描述
+	Description string `json:"description"`
+	// 排序(越小越靠前)
+	Sort uint8 `json:"sort"`
+	// 显示状态：0->不显示；1->显示
+	ShowStatus uint8 `json:"showStatus"`
+	// 品牌所属的商品分类ID数组
+	ProductCategoryIds []int64 `json:"productCategoryIds"`
+}
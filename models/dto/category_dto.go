@@ -0,0 +1,45 @@
+package dto
+
+// Category 封装新增/修改商品分类的请求体
+type Category struct {
+	// 主键ID。新增分类不需要携带；修改分类时需要携带
+	ID string `json:"id"`
+	// 父分类ID，一级分类为0。仅新增分类时使用，移动分类请使用移动分类接口
+	ParentID int64 `json:"parentId,string"`
+	// 分类名称
+	Name string `json:"name" binding:"required"`
+	// 分类简称
+	Abbreviation string `json:"abbreviation"`
+	// 分类图标URL
+	Icon string `json:"icon"`
+	// 排序(越小越靠前)，仅新增分类时使用
+	Sort uint8 `json:"sort"`
+	// 显示状态：0->不显示；1->显示。仅新增分类时使用
+	ShowStatus uint8 `json:"showStatus"`
+	// 分类对应的商品属性ID数组
+	ProductAttributeIds []int64 `json:"productAttributeIds"`
+}
+
+// CategoryMove 封装移动商品分类的请求体
+type CategoryMove struct {
+	// 分类ID
+	ID int64 `json:"id,string" binding:"required"`
+	// 新的父分类ID，移动为一级分类时为0
+	ParentID int64 `json:"parentId,string"`
+}
+
+// CategorySort 封装修改商品分类排序的请求体
+type CategorySort struct {
+	// 分类ID
+	ID int64 `json:"id,string" binding:"required"`
+	// 排序(越小越靠前)
+	Sort uint8 `json:"sort"`
+}
+
+// CategoryShowStatus 封装修改商品分类显示状态的请求体
+type CategoryShowStatus struct {
+	// 分类ID
+	ID int64 `json:"id,string" binding:"required"`
+	// 显示状态：0->不显示；1->显示
+	ShowStatus uint8 `json:"showStatus"`
+}
@@ -0,0 +1,43 @@
+package dto
+
+// Comment 封装发表商品评价的请求体
+type Comment struct {
+	// 订单明细ID
+	OrderItemID string `json:"orderItemId" binding:"required"`
+	// 评分：1~5星
+	Star uint8 `json:"star" binding:"required,min=1,max=5"`
+	// 评价内容
+	Content string `json:"content" binding:"max=500"`
+	// 评价图片URL(先调用上传评价图片接口获取)，最多9张
+	Pics []string `json:"pics" binding:"max=9"`
+}
+
+// CommentAppend 封装追加评价的请求体
+type CommentAppend struct {
+	// 评价ID
+	CommentID string `json:"commentId" binding:"required"`
+	// 追评内容
+	Content string `json:"content" binding:"required,max=500"`
+	// 追评图片URL，最多9张
+	Pics []string `json:"pics" binding:"max=9"`
+}
+
+// CommentReply 封装商家回复评价的请求体
+type CommentReply struct {
+	// 评价ID
+	CommentID string `json:"commentId" binding:"required"`
+	// 回复内容
+	Content string `json:"content" binding:"required,max=500"`
+}
+
+// CommentCondition 封装查询商品评价列表的条件
+type CommentCondition struct {
+	// 是否只看有图评价
+	WithPic bool `form:"withPic"`
+	// 排序：1->最新；2->最有用
+	Sort string `form:"sort"`
+	// 页码(从1开始),默认为1
+	PageNo string `form:"pageNo"`
+	// 页长,默认为10
+	PageSize string `form:"pageSize"`
+}
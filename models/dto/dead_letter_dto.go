@@ -0,0 +1,9 @@
+package dto
+
+// DeadLetterReplay 后台重新发送死信队列中消息的请求体
+type DeadLetterReplay struct {
+	// 原队列名称
+	Queue string `json:"queue" binding:"required"`
+	// 重新发送的消息数量，不传时重新发送所有消息
+	Count int `json:"count" binding:"min=0"`
+}
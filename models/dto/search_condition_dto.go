@@ -5,20 +5,36 @@ import "strconv"
 // SearchCondition 封装搜索条件的请求体
 type SearchCondition struct {
 	// 品牌ID
-	// todo 未使用，可以通过品牌ID查询商品
-	BrandId string `json:"brandId"`
-	// 二级分类ID
-	ProductCategoryId string `json:"productCategoryId"`
+	BrandId string `json:"brandId" form:"brandId"`
+	// 品牌ID数组，可以同时选择多个品牌，与BrandId取并集
+	BrandIds []int64 `json:"brandIds" form:"brandIds"`
+	// 分类ID，可以是任意层级的分类，搜索结果包含所有子孙分类下的商品
+	ProductCategoryId string `json:"productCategoryId" form:"productCategoryId"`
+	// ProductCategoryId及其所有子孙分类ID，由logic层根据分类树填充
+	ProductCategoryTreeIds []int64 `json:"-" form:"-"`
 	// 搜索条件
-	Keyword string `json:"keyword"`
+	Keyword string `json:"keyword" form:"keyword"`
+	// 全文索引按相关度降序召回的skuID集合，由logic层填充。不为空时不再使用Keyword模糊查询
+	SkuIds []int64 `json:"-" form:"-"`
 	// 商品属性ID数组
-	ProductAttributeIds []int64 `json:"productAttributeIds"`
-	// 排序: 1->默认; 2->销量
-	Sort string `json:"sort"`
+	ProductAttributeIds []int64 `json:"productAttributeIds" form:"productAttributeIds"`
+	// 最低价格(包含)
+	MinPrice string `json:"minPrice" form:"minPrice"`
+	// 最高价格(包含)
+	MaxPrice string `json:"maxPrice" form:"maxPrice"`
+	// 是否只显示有货商品
+	InStock bool `json:"inStock" form:"inStock"`
+	// 排序: 1->默认(有关键字时按相关度，否则按创建时间); 2->销量; 3->价格升序; 4->价格降序
+	Sort string `json:"sort" form:"sort"`
 	// 页码(从1开始),默认为1
-	PageNo string `json:"pageNo"`
+	PageNo string `json:"pageNo" form:"pageNo"`
 	// 页长,默认为20
-	PageSize string `json:"pageSize"`
+	PageSize string `json:"pageSize" form:"pageSize"`
+	// 游标，取自上一页返回的nextCursor。不为空时使用游标分页，忽略页码
+	Cursor string `json:"cursor" form:"cursor"`
+	// 游标解析后的排序值和skuID，由logic层填充
+	CursorValue string `json:"-" form:"-"`
+	CursorID    int64  `json:"-" form:"-"`
 }
 
 // NewCondition 初始化搜索条件，并指定分页默认值和排序方式(创建时间排序)
@@ -29,3 +45,9 @@ func NewCondition() *SearchCondition {
 		Sort:     strconv.Itoa(1),
 	}
 }
+
+// SearchKeyword 后台屏蔽、取消屏蔽搜索关键字的请求体
+type SearchKeyword struct {
+	// 搜索关键字
+	Keyword string `json:"keyword" form:"keyword" binding:"required"`
+}
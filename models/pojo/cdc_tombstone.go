@@ -0,0 +1,16 @@
+package pojo
+
+import "time"
+
+// CDCTombstone 被删除行的墓碑记录，轮询变更时用于发现删除
+type CDCTombstone struct {
+	ID    int64  `gorm:"column:id;primaryKey;autoIncrement"`
+	Table string `gorm:"column:table_name"`
+	// 被删除行的列，json对象
+	RowData     string    `gorm:"column:row_data"`
+	CreatedTime time.Time `gorm:"column:created_time;autoCreateTime"`
+}
+
+func (CDCTombstone) TableName() string {
+	return "cdc_tombstone"
+}
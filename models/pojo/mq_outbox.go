@@ -0,0 +1,41 @@
+package pojo
+
+import "time"
+
+// 发件箱消息的状态
+const (
+	// OutboxStatusPending 待发送
+	OutboxStatusPending uint8 = 0
+	// OutboxStatusSent 已发送(已收到RabbitMQ的发布确认)
+	OutboxStatusSent uint8 = 1
+)
+
+// OutboxMessage 发件箱消息，与业务数据在同一个事务中写入，由中继发送到RabbitMQ
+type OutboxMessage struct {
+	// 雪花算法生成的主键ID
+	ID int64 `gorm:"column:id;primaryKey"`
+	// 交换机名称
+	Exchange string `gorm:"column:exchange"`
+	// 路由
+	RoutingKey string `gorm:"column:routing_key"`
+	// 消息体，json
+	Body string `gorm:"column:body"`
+	// 写入消息时的链路信息，json对象，发送时写入消息头。没有链路时为空
+	Headers string `gorm:"column:headers"`
+	// 延时发送的时间(单位:毫秒)，为空表示立即发送。从创建时间开始计算
+	Expiration string `gorm:"column:expiration"`
+	// 状态：0->待发送；1->已发送
+	Status uint8 `gorm:"column:status"`
+	// 发送失败的次数
+	Attempts int `gorm:"column:attempts"`
+	// 下一次可以发送的时间，发送失败或被中继领取后推迟
+	NextRetryTime time.Time `gorm:"column:next_retry_time"`
+	// 发送成功的时间
+	SentTime *time.Time `gorm:"column:sent_time"`
+	// 创建时间
+	CreatedTime time.Time `gorm:"column:created_time;autoCreateTime"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "mq_outbox"
+}
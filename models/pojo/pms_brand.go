@@ -0,0 +1,29 @@
+package pojo
+
+import "time"
+
+// Brand 商品品牌表
+type Brand struct {
+	// 主键
+	ID int64 `gorm:"column:id"`
+	// 品牌名称
+	Name string `gorm:"column:name"`
+	// 品牌首字母
+	FirstLetter string `gorm:"column:first_letter"`
+	// 品牌Logo URL
+	Logo string `gorm:"column:logo"`
+	// 品牌描述
+	Description string `gorm:"column:description"`
+	// 排序(越小越靠前)
+	Sort uint8 `gorm:"column:sort"`
+	// 显示状态：0->不显示；1->显示
+	ShowStatus uint8 `gorm:"column:show_status"`
+	// 创建时间
+	CreatedTime time.Time `gorm:"column:created_time;autoCreateTime"`
+	// 修改时间
+	UpdatedTime time.Time `gorm:"column:updated_time;autoUpdateTime"`
+}
+
+func (Brand) TableName() string {
+	return "pms_brand"
+}
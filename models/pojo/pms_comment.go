@@ -0,0 +1,77 @@
+package pojo
+
+import "time"
+
+// Comment 商品评价表，每个订单明细只能评价一次
+type Comment struct {
+	// 雪花算法生成的主键ID
+	ID int64 `gorm:"column:id"`
+	// 商品spuID(对应商品spu表主键ID)
+	SpuID int64 `gorm:"column:spu_id"`
+	// 商品skuID(对应商品sku表主键ID)
+	SkuID int64 `gorm:"column:sku_id"`
+	// 订单ID(对应订单表主键ID)
+	OrderID int64 `gorm:"column:order_id"`
+	// 订单明细ID(对应订单明细表主键ID)，唯一索引
+	OrderItemID int64 `gorm:"column:order_item_id"`
+	// 用户ID
+	UserID int64 `gorm:"column:user_id"`
+	// 评分：1~5星
+	Star uint8 `gorm:"column:star"`
+	// 评价内容
+	Content string `gorm:"column:content"`
+	// 评价图片URL，json数组
+	Pics string `gorm:"column:pics"`
+	// 是否有图片：0->没有；1->有
+	HasPic uint8 `gorm:"column:has_pic"`
+	// 有用数
+	HelpfulCount int `gorm:"column:helpful_count"`
+	// 商家回复
+	Reply string `gorm:"column:reply"`
+	// 商家回复时间
+	ReplyTime *time.Time `gorm:"column:reply_time"`
+	// 创建时间
+	CreatedTime time.Time `gorm:"column:created_time;autoCreateTime"`
+	// 修改时间
+	UpdatedTime time.Time `gorm:"column:updated_time;autoUpdateTime"`
+}
+
+func (Comment) TableName() string {
+	return "pms_comment"
+}
+
+// CommentAppend 商品追加评价表
+type CommentAppend struct {
+	// 雪花算法生成的主键ID
+	ID int64 `gorm:"column:id"`
+	// 评价ID(对应商品评价表主键ID)
+	CommentID int64 `gorm:"column:comment_id"`
+	// 用户ID
+	UserID int64 `gorm:"column:user_id"`
+	// 追评内容
+	Content string `gorm:"column:content"`
+	// 追评图片URL，json数组
+	Pics string `gorm:"column:pics"`
+	// 创建时间
+	CreatedTime time.Time `gorm:"column:created_time;autoCreateTime"`
+}
+
+func (CommentAppend) TableName() string {
+	return "pms_comment_append"
+}
+
+// CommentHelpful 商品评价有用投票表，comment_id和user_id组成唯一索引
+type CommentHelpful struct {
+	// 主键ID
+	ID int64 `gorm:"column:id"`
+	// 评价ID(对应商品评价表主键ID)
+	CommentID int64 `gorm:"column:comment_id"`
+	// 用户ID
+	UserID int64 `gorm:"column:user_id"`
+	// 创建时间
+	CreatedTime time.Time `gorm:"column:created_time;autoCreateTime"`
+}
+
+func (CommentHelpful) TableName() string {
+	return "pms_comment_helpful"
+}
@@ -2,7 +2,7 @@ package pojo
 
 import "time"
 
-// ProductAttribute 商品属性表
+// ProductAttribute 商品属性表。parent_id为0的是属性名(属性分组)，parent_id为属性名ID的是属性值
 type ProductAttribute struct {
 	ID          int64     `gorm:"column:id"`
 	Type        uint8     `gorm:"column:type"`
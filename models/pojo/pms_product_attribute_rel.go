@@ -0,0 +1,16 @@
+package pojo
+
+import "time"
+
+// ProductAttributeRel 商品spu和属性值中间表
+type ProductAttributeRel struct {
+	ID                 int64     `gorm:"column:id"`
+	SpuID              int64     `gorm:"column:spu_id"`
+	ProductAttributeID int64     `gorm:"column:product_attribute_id"`
+	CreatedTime        time.Time `gorm:"column:created_time;autoCreateTime"`
+	UpdatedTime        time.Time `gorm:"column:updated_time;autoUpdateTime"`
+}
+
+func (ProductAttributeRel) TableName() string {
+	return "pms_product_attribute_rel"
+}
@@ -2,7 +2,7 @@ package pojo
 
 import "time"
 
-// ProductCategory 商品分类表
+// ProductCategory 商品分类表，通过parent_id组成任意层级的分类树，level从0开始
 type ProductCategory struct {
 	ID           int64     `gorm:"column:id"`
 	ParentID     int64     `gorm:"column:parent_id"`
@@ -0,0 +1,16 @@
+package pojo
+
+import "time"
+
+// ProductCategoryBrandRel 商品分类和品牌中间表
+type ProductCategoryBrandRel struct {
+	ID                int64     `gorm:"column:id"`
+	ProductCategoryID int64     `gorm:"column:product_category_id"`
+	BrandID           int64     `gorm:"column:brand_id"`
+	CreatedTime       time.Time `gorm:"column:created_time;autoCreateTime"`
+	UpdatedTime       time.Time `gorm:"column:updated_time;autoUpdateTime"`
+}
+
+func (ProductCategoryBrandRel) TableName() string {
+	return "pms_product_category_brand_rel"
+}
@@ -8,9 +8,11 @@ type Spu struct {
 	ID int64 `gorm:"column:id"`
 	// 品牌ID(对应品牌表主键ID)
 	BrandId int64 `gorm:"column:brand_id"`
-	// 一级分类ID(对应商品分类表主键ID)
+	// 商品所属的末级分类ID(对应商品分类表主键ID)，分类层级不限，祖先分类通过分类树获取
+	CategoryID int64 `gorm:"column:category_id"`
+	// 一级分类ID(对应商品分类表主键ID)，仅用于兼容两级分类的旧数据
 	CID1 int64 `gorm:"column:cid1"`
-	// 二级分类ID(对应商品分类表主键ID)
+	// 二级分类ID(对应商品分类表主键ID)，仅用于兼容两级分类的旧数据
 	CID2 int64 `gorm:"column:cid2"`
 	// 商品总销量
 	Sale int `gorm:"column:sale"`
@@ -11,4 +11,23 @@ type AttributeVO struct {
 type AttributeValueVO struct {
 	ValueID   int64  `json:"valueID,string"`
 	ValueName string `json:"valueName"`
+	// 符合当前搜索条件且拥有该属性值的商品数量
+	Count int64 `json:"count"`
+}
+
+// AttributeValueFacetVO 搜索结果中的属性值聚合
+type AttributeValueFacetVO struct {
+	ValueID int64 `gorm:"column:value_id"`
+	Count   int64 `gorm:"column:count"`
+}
+
+// AdminAttributeVO 后台管理使用的商品属性树
+type AdminAttributeVO struct {
+	ID       int64  `json:"id,string"`
+	Type     uint8  `json:"type"`
+	ParentID int64  `json:"parentId,string"`
+	Name     string `json:"name"`
+	Sort     uint8  `json:"sort"`
+	// 属性值集合
+	Values []*AdminAttributeVO `json:"values,omitempty"`
 }
@@ -0,0 +1,22 @@
+package vo
+
+// BrandVO 商品品牌
+type BrandVO struct {
+	ID          int64  `json:"id,string"`
+	Name        string `json:"name"`
+	FirstLetter string `json:"firstLetter"`
+	Logo        string `json:"logo"`
+	Description string `json:"description"`
+	Sort        uint8  `json:"sort"`
+	ShowStatus  uint8  `json:"showStatus"`
+	// 品牌所属的商品分类ID数组
+	ProductCategoryIds []int64 `json:"productCategoryIds"`
+}
+
+// BrandFacetVO 搜索结果中的品牌聚合，Count为该品牌下符合搜索条件的商品数量
+type BrandFacetVO struct {
+	ID    int64  `json:"id,string" gorm:"column:id"`
+	Name  string `json:"name" gorm:"column:name"`
+	Logo  string `json:"logo" gorm:"column:logo"`
+	Count int64  `json:"count" gorm:"column:count"`
+}
@@ -0,0 +1,20 @@
+package vo
+
+import "time"
+
+// CanalPositionVO canal最后一次成功处理(所有变更事件都被MQ确认并Ack)的binlog位置
+type CanalPositionVO struct {
+	// 变更来源：canal、poll
+	Source string `json:"source"`
+	// 轮询模式下最后处理的表
+	Table         string `json:"table,omitempty"`
+	BatchID       int64  `json:"batchId,string"`
+	LogfileName   string `json:"logfileName"`
+	LogfileOffset int64  `json:"logfileOffset"`
+	// binlog中记录的变更执行时间，轮询模式下为最后处理的updated_time
+	ExecuteTime time.Time `json:"executeTime"`
+	// Ack的时间
+	AckTime time.Time `json:"ackTime"`
+	// 当前时间距离变更执行时间的毫秒数，持续增大说明缓存同步已经落后(或者数据库一直没有变更)
+	DelayMs int64 `json:"delayMs"`
+}
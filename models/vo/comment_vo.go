@@ -0,0 +1,52 @@
+package vo
+
+import "time"
+
+// CommentVO 商品评价
+type CommentVO struct {
+	ID       int64  `json:"id,string" gorm:"column:id"`
+	SkuID    int64  `json:"skuId,string" gorm:"column:sku_id"`
+	UserID   int64  `json:"-" gorm:"column:user_id"`
+	Username string `json:"username" gorm:"column:username"`
+	Avatar   string `json:"avatar" gorm:"column:avatar"`
+	// 评分：1~5星
+	Star    uint8    `json:"star" gorm:"column:star"`
+	Content string   `json:"content" gorm:"column:content"`
+	PicsStr string   `json:"-" gorm:"column:pics"`
+	Pics    []string `json:"pics" gorm:"-"`
+	// 有用数
+	HelpfulCount int `json:"helpfulCount" gorm:"column:helpful_count"`
+	// 商家回复
+	Reply       string     `json:"reply" gorm:"column:reply"`
+	ReplyTime   *time.Time `json:"replyTime" gorm:"column:reply_time"`
+	CreatedTime time.Time  `json:"createdTime" gorm:"column:created_time"`
+	// 追加评价
+	Appends []*CommentAppendVO `json:"appends" gorm:"-"`
+}
+
+// CommentAppendVO 商品追加评价
+type CommentAppendVO struct {
+	ID          int64     `json:"id,string"`
+	CommentID   int64     `json:"-"`
+	Content     string    `json:"content"`
+	Pics        []string  `json:"pics"`
+	CreatedTime time.Time `json:"createdTime"`
+}
+
+// RatingVO 商品评分汇总
+type RatingVO struct {
+	// 平均评分，保留一位小数
+	Average float64 `json:"average"`
+	// 评价总数
+	Total int64 `json:"total"`
+	// 有图评价数
+	WithPic int64 `json:"withPic"`
+	// 每个星级的评价数，从5星到1星
+	Stars []*RatingStarVO `json:"stars"`
+}
+
+// RatingStarVO 某个星级的评价数
+type RatingStarVO struct {
+	Star  uint8 `json:"star" gorm:"column:star"`
+	Count int64 `json:"count" gorm:"column:count"`
+}
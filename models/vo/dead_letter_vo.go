@@ -0,0 +1,20 @@
+package vo
+
+// DeadLetterQueueVO 死信队列以及其中的消息数量
+type DeadLetterQueueVO struct {
+	// 原队列名称
+	Queue           string `json:"queue"`
+	DeadLetterQueue string `json:"deadLetterQueue"`
+	Count           int    `json:"count"`
+}
+
+// DeadLetterVO 死信队列中的消息
+type DeadLetterVO struct {
+	Queue string `json:"queue"`
+	// 进入死信队列前失败的次数
+	Attempts    int    `json:"attempts"`
+	ContentType string `json:"contentType"`
+	Body        string `json:"body"`
+	// 进入死信队列的时间
+	DeadTime string `json:"deadTime"`
+}
@@ -0,0 +1,19 @@
+package vo
+
+// DependencyStatusVO 一个依赖的检查结果
+type DependencyStatusVO struct {
+	// 依赖名称：mysql、redis、rabbitmq、canal、pay
+	Name string `json:"name"`
+	// up或down
+	Status string `json:"status"`
+	// 检查耗时
+	LatencyMs int64 `json:"latencyMs"`
+	// 检查失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+// ReadinessVO 服务是否可以接收请求，所有依赖都可用时为up
+type ReadinessVO struct {
+	Status       string                `json:"status"`
+	Dependencies []*DependencyStatusVO `json:"dependencies"`
+}
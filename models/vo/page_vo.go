@@ -1,7 +1,7 @@
 package vo
 
 type Pageable interface {
-	[]*ProductVO
+	[]*ProductVO | []*CommentVO
 }
 type Page[T Pageable] struct {
 	// 起始页
@@ -9,9 +9,15 @@ type Page[T Pageable] struct {
 	// 每次大小
 	PageSize string `json:"pageSize"`
 	// 根据条件查询出来的总记录数(不分页)
+	TotalCount string `json:"totalCount"`
+	// 总页数
 	TotalPage string `json:"totalPage"`
+	// 下一页的游标，为空表示没有更多数据。下次请求时传入cursor即可从该位置继续查询
+	NextCursor string `json:"nextCursor,omitempty"`
 	// 数据集合
 	Data T `json:"data"`
+	// 搜索结果的聚合信息(品牌等)，仅商品搜索接口返回
+	Facets *SearchFacetVO `json:"facets,omitempty"`
 }
 
 // NewPage 初始化Page对象，并指定分页字段默认值
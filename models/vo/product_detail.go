@@ -2,9 +2,12 @@ package vo
 
 // ProductDetailVO 商品详情信息
 type ProductDetailVO struct {
-	Spu        *SpuVO        `json:"spu"`
+	Spu *SpuVO `json:"spu"`
+	// 商品所属分类的面包屑导航，从一级分类开始一直到末级分类
 	Categories []*CategoryVO `json:"categories"`
 	SkuList    []*SkuVO      `json:"skuList"`
+	// 商品评分汇总
+	Rating *RatingVO `json:"rating"`
 }
 
 type SpuVO struct {
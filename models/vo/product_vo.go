@@ -12,8 +12,29 @@ type ProductVO struct {
 	Name string `json:"name" gorm:"column:name"`
 	// 商品默认图片URL
 	DefaultPicUrl string `json:"defaultPicUrl" gorm:"column:defaultPicUrl"`
+	// 高亮后的商品名称，匹配搜索关键字的片段使用<em></em>包裹
+	Highlight string `json:"highlight,omitempty" gorm:"-"`
+	// 商品在当前排序方式下的排序值，用于生成游标
+	SortValue string `json:"-" gorm:"column:sortValue"`
 }
 
 func (ProductVO) TableName() string {
 	return "pms_sku"
 }
+
+// SearchFacetVO 商品搜索结果的聚合信息
+type SearchFacetVO struct {
+	// 品牌聚合
+	Brands []*BrandFacetVO `json:"brands"`
+	// 二级分类聚合
+	Categories []*CategoryFacetVO `json:"categories"`
+	// 属性值聚合，按属性名分组
+	Attributes []*AttributeVO `json:"attributes"`
+}
+
+// CategoryFacetVO 搜索结果中的分类聚合，Count为该分类及其子孙分类下符合搜索条件的商品数量
+type CategoryFacetVO struct {
+	ID    int64  `json:"id,string" gorm:"column:id"`
+	Name  string `json:"name" gorm:"-"`
+	Count int64  `json:"count" gorm:"column:count"`
+}
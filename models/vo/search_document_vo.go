@@ -0,0 +1,12 @@
+package vo
+
+// SearchDocumentVO 建立全文索引使用的商品文档(默认规格的sku)
+type SearchDocumentVO struct {
+	SkuID        int64  `gorm:"column:sku_id"`
+	SpuID        int64  `gorm:"column:spu_id"`
+	Title        string `gorm:"column:title"`
+	SpuName      string `gorm:"column:spu_name"`
+	SubTitle     string `gorm:"column:sub_title"`
+	BrandName    string `gorm:"column:brand_name"`
+	CategoryName string `gorm:"column:category_name"`
+}
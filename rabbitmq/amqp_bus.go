@@ -0,0 +1,492 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+	"shop-backend/models/vo"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// 等待MQ确认消息的最长时间
+	confirmTimeout = 10 * time.Second
+	// 发布确认通道的缓冲区大小
+	confirmBuffer = 1024
+	// 延时队列名称：<交换机>.<路由>.delay.<延时毫秒数>，每个延时使用单独的队列并设置队列级别的过期时间，
+	// 避免队头的长延时消息阻塞后面的短延时消息。消息过期后通过死信交换机发送到目标交换机
+	delayQueueInfix = ".delay."
+	// 延时精度，延时向上取整到秒，限制延时队列的数量
+	delayPrecision = time.Second
+	// 延时队列在最后一条消息过期后闲置多久被RabbitMQ删除
+	delayQueueIdle = 5 * time.Minute
+	// 重新声明延时队列的间隔，重新声明会重置队列的闲置时间
+	delayQueueRedeclare = time.Minute
+)
+
+// amqpBus 使用RabbitMQ实现的消息总线
+type amqpBus struct {
+	manager   *connManager
+	publisher *confirmPublisher
+
+	mu sync.Mutex
+	// 已经声明的交换机 K: 交换机名称 V: 交换机类型。发布通道重新打开后会重新声明
+	exchanges map[string]string
+	// 所有订阅创建的RabbitMQ对象，关闭时按照顺序停止
+	consumers []*RabbitMQ
+}
+
+// newAMQPBus 创建使用RabbitMQ的消息总线，连接在后台建立
+func newAMQPBus(url string) *amqpBus {
+	b := &amqpBus{
+		manager:   newConnManager(url),
+		exchanges: make(map[string]string),
+	}
+	b.publisher = &confirmPublisher{manager: b.manager, setup: b.declareExchanges}
+	return b
+}
+
+// DeclareExchange 登记交换机，在发布通道、消费通道打开时声明
+func (b *amqpBus) DeclareExchange(exchange, kind string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.exchanges[exchange] = kind
+	return nil
+}
+
+// declareExchanges 在新打开的发布通道上声明所有登记过的交换机。交换机不存在时发布消息会导致通道关闭
+func (b *amqpBus) declareExchanges(channel *amqp.Channel) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for exchange, kind := range b.exchanges {
+		if err := channel.ExchangeDeclare(exchange, kind, true, false, false, false, nil); err != nil {
+			zap.L().Error("RabbitMQ初始化交换机失败", zap.String("exchange", exchange), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// exchangeKind 获取已经登记的交换机的类型
+func (b *amqpBus) exchangeKind(exchange string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kind, ok := b.exchanges[exchange]
+	return kind, ok
+}
+
+// Publish 发送持久化消息到交换机，并等待RabbitMQ的发布确认
+func (b *amqpBus) Publish(ctx context.Context, exchange, routingKey string, body []byte) error {
+	return b.PublishBatch(ctx, exchange, []*Message{{RoutingKey: routingKey, Body: body}})
+}
+
+// PublishBatch 发送一批持久化消息到交换机，所有消息发送后再一起等待确认
+func (b *amqpBus) PublishBatch(ctx context.Context, exchange string, messages []*Message) error {
+	if _, ok := b.exchangeKind(exchange); !ok {
+		return ErrorExchangeNotExist
+	}
+	confirms := make([]<-chan error, 0, len(messages))
+	for _, message := range messages {
+		confirm, err := b.publisher.publish(exchange, message.RoutingKey, newPublishing(ctx, message.Body))
+		if err != nil {
+			return err
+		}
+		confirms = append(confirms, confirm)
+	}
+	return waitConfirms(confirms)
+}
+
+// PublishDelayed 将消息发送到延时队列，消息在延时队列中过期后由RabbitMQ发送到目标交换机
+func (b *amqpBus) PublishDelayed(ctx context.Context, exchange, routingKey string, body []byte, delay time.Duration) error {
+	if _, ok := b.exchangeKind(exchange); !ok {
+		return ErrorExchangeNotExist
+	}
+	if delay <= 0 {
+		return b.Publish(ctx, exchange, routingKey, body)
+	}
+	delay = (delay + delayPrecision - 1).Truncate(delayPrecision)
+	confirm, err := b.publisher.publishDelayed(delayQueueName(exchange, routingKey, delay), exchange, routingKey, delay,
+		newPublishing(ctx, body))
+	if err != nil {
+		return err
+	}
+	return waitConfirms([]<-chan error{confirm})
+}
+
+// Subscribe 为接收者创建RabbitMQ对象，每次连接成功后在新的通道上声明队列并开始消费
+func (b *amqpBus) Subscribe(exchange string, receiver Receiver) error {
+	kind, ok := b.exchangeKind(exchange)
+	if !ok {
+		return ErrorExchangeNotExist
+	}
+	mq := newRabbitMQ(b.manager, exchange, kind)
+	mq.RegisterReceiver(receiver)
+	mq.Start()
+	b.mu.Lock()
+	b.consumers = append(b.consumers, mq)
+	b.mu.Unlock()
+	return nil
+}
+
+// Connected 是否已经连接到RabbitMQ
+func (b *amqpBus) Connected() bool {
+	return b.manager.connected()
+}
+
+// Ping 在当前连接上打开并关闭一个通道，未连接时返回ErrorNotConnected
+func (b *amqpBus) Ping() error {
+	channel, err := b.manager.channel()
+	if err != nil {
+		return err
+	}
+	return channel.Close()
+}
+
+// Drain 通知所有消费者停止接收新的消息，然后等待正在处理的消息处理完成
+func (b *amqpBus) Drain(ctx context.Context) error {
+	b.mu.Lock()
+	consumers := b.consumers
+	b.mu.Unlock()
+	for _, mq := range consumers {
+		mq.Stop()
+	}
+	for _, mq := range consumers {
+		if err := waitDone(ctx, mq.Wait); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 按照顺序关闭：所有消费者停止接收新的消息，等待正在处理的消息处理完成，
+// 然后关闭发布通道以及RabbitMQ连接，关闭后不再重连。
+// ctx结束时不再等待消费者，直接关闭连接并返回ctx.Err()，未应答的消息会回到队列
+func (b *amqpBus) Close(ctx context.Context) error {
+	err := b.Drain(ctx)
+	b.publisher.close()
+	b.manager.close()
+	return err
+}
+
+// ListDeadLetterQueues 获取所有死信队列以及其中的消息数量
+func (b *amqpBus) ListDeadLetterQueues() ([]*vo.DeadLetterQueueVO, error) {
+	retryQueuesMu.RLock()
+	queueNames := make([]string, 0, len(retryQueues))
+	for queueName := range retryQueues {
+		queueNames = append(queueNames, queueName)
+	}
+	retryQueuesMu.RUnlock()
+	sort.Strings(queueNames)
+
+	channel, err := b.manager.channel()
+	if err != nil {
+		return nil, err
+	}
+	defer channel.Close()
+	result := make([]*vo.DeadLetterQueueVO, 0, len(queueNames))
+	for _, queueName := range queueNames {
+		queue, err := channel.QueueInspect(deadLetterQueueName(queueName))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &vo.DeadLetterQueueVO{
+			Queue:           queueName,
+			DeadLetterQueue: queue.Name,
+			Count:           queue.Messages,
+		})
+	}
+	return result, nil
+}
+
+// PeekDeadLetters 查看死信队列中的前count条消息，消息仍然保留在死信队列中
+func (b *amqpBus) PeekDeadLetters(queueName string, count int) ([]*vo.DeadLetterVO, error) {
+	channel, err := b.deadLetterChannel(queueName)
+	if err != nil {
+		return nil, err
+	}
+	// 关闭通道时，未应答的消息会回到死信队列
+	defer channel.Close()
+	result := make([]*vo.DeadLetterVO, 0, count)
+	for i := 0; i < count; i++ {
+		msg, ok, err := channel.Get(deadLetterQueueName(queueName), false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		result = append(result, buildDeadLetterVO(queueName, msg))
+	}
+	return result, nil
+}
+
+// ReplayDeadLetters 将死信队列中的前count条消息重新发送到原队列，失败次数清零。返回重新发送的消息数量。
+// 每条消息收到发布确认后才从死信队列中删除，发送失败或没有收到确认时消息退回死信队列并停止发送
+func (b *amqpBus) ReplayDeadLetters(queueName string, count int) (int, error) {
+	channel, err := b.deadLetterChannel(queueName)
+	if err != nil {
+		return 0, err
+	}
+	defer channel.Close()
+	if err = channel.Confirm(false); err != nil {
+		return 0, fmt.Errorf("开启发布确认失败: %w", err)
+	}
+	confirms := channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	replayed := 0
+	for replayed < count {
+		msg, ok, err := channel.Get(deadLetterQueueName(queueName), false)
+		if err != nil {
+			return replayed, err
+		}
+		if !ok {
+			break
+		}
+		headers := amqp.Table{}
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+		delete(headers, retryAttemptHeader)
+		delete(headers, originalQueueHeader)
+		delete(headers, deadTimeHeader)
+		err = channel.Publish("", queueName, false, false, amqp.Publishing{
+			Headers:      headers,
+			DeliveryMode: 2, // 2 表示消息持久化
+			ContentType:  msg.ContentType,
+			Body:         msg.Body,
+		})
+		if err == nil {
+			err = waitConfirm(confirms)
+		}
+		if err != nil {
+			// 原队列没有确认接收，死信退回死信队列，不能丢失消息
+			_ = msg.Nack(false, true)
+			zap.L().Error("重新发送死信失败，消息退回死信队列", zap.String("queue", queueName), zap.Int("replayed", replayed), zap.Error(err))
+			return replayed, err
+		}
+		if err = msg.Ack(false); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	zap.L().Info("重新发送死信队列中的消息", zap.String("queue", queueName), zap.Int("count", replayed))
+	return replayed, nil
+}
+
+// PurgeDeadLetters 清空死信队列，返回删除的消息数量
+func (b *amqpBus) PurgeDeadLetters(queueName string) (int, error) {
+	channel, err := b.deadLetterChannel(queueName)
+	if err != nil {
+		return 0, err
+	}
+	defer channel.Close()
+	count, err := channel.QueuePurge(deadLetterQueueName(queueName), false)
+	if err != nil {
+		return 0, err
+	}
+	zap.L().Info("清空死信队列", zap.String("queue", queueName), zap.Int("count", count))
+	return count, nil
+}
+
+// deadLetterChannel 校验队列存在死信队列，并打开一个新的通道用于操作死信队列
+func (b *amqpBus) deadLetterChannel(queueName string) (*amqp.Channel, error) {
+	retryQueuesMu.RLock()
+	exist := retryQueues[queueName]
+	retryQueuesMu.RUnlock()
+	if !exist {
+		return nil, ErrorQueueNotExist
+	}
+	channel, err := b.manager.channel()
+	if err != nil {
+		return nil, fmt.Errorf("打开Channel失败: %w", err)
+	}
+	return channel, nil
+}
+
+// delayQueueName 延时队列的名称
+func delayQueueName(exchange, routingKey string, delay time.Duration) string {
+	return exchange + "." + routingKey + delayQueueInfix + strconv.FormatInt(delay.Milliseconds(), 10)
+}
+
+// newPublishing 构建持久化的json消息，ctx中的链路信息写入消息头
+func newPublishing(ctx context.Context, body []byte) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:      injectHeaders(ctx),
+		DeliveryMode: 2, // 2 表示消息持久化
+		ContentType:  "application/json",
+		Body:         body,
+	}
+}
+
+// confirmPublisher 开启发布确认模式的发布者，多个协程可以同时发送消息并各自等待确认。
+// 通道关闭(例如连接断开)后，下一次发送时在新的连接上重新打开
+type confirmPublisher struct {
+	manager *connManager
+	// 打开通道后的初始化操作，例如声明交换机
+	setup func(channel *amqp.Channel) error
+
+	// 发送消息时加锁，保证DeliveryTag与发送顺序一致
+	mu      sync.Mutex
+	current *confirmChannel
+}
+
+// confirmChannel 开启了发布确认模式的通道，以及等待确认的消息
+type confirmChannel struct {
+	channel *amqp.Channel
+	// 已经发布的消息数量，等于最后一条消息的DeliveryTag
+	deliveryTag uint64
+	// 在该通道上声明过的延时队列 K: 队列名称 V: 最后一次声明的时间
+	delayQueues map[string]time.Time
+
+	// 等待确认的消息 K: DeliveryTag V: 接收确认结果的通道
+	pendingMu sync.Mutex
+	pending   map[uint64]chan error
+	closed    bool
+}
+
+// get 获取一个可用的通道，通道已经关闭时重新打开，调用前需要持有p.mu
+func (p *confirmPublisher) get() (*confirmChannel, error) {
+	if p.current != nil {
+		p.current.pendingMu.Lock()
+		closed := p.current.closed
+		p.current.pendingMu.Unlock()
+		if !closed {
+			return p.current, nil
+		}
+		p.current = nil
+	}
+	channel, err := p.manager.channel()
+	if err != nil {
+		return nil, err
+	}
+	if err = channel.Confirm(false); err != nil {
+		_ = channel.Close()
+		return nil, err
+	}
+	if p.setup != nil {
+		if err = p.setup(channel); err != nil {
+			_ = channel.Close()
+			return nil, err
+		}
+	}
+	cc := &confirmChannel{
+		channel:     channel,
+		delayQueues: make(map[string]time.Time),
+		pending:     make(map[uint64]chan error),
+	}
+	go cc.dispatch(channel.NotifyPublish(make(chan amqp.Confirmation, confirmBuffer)))
+	p.current = cc
+	return cc, nil
+}
+
+// publish 发送一条消息，返回接收确认结果的通道
+func (p *confirmPublisher) publish(exchange, routingKey string, msg amqp.Publishing) (<-chan error, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cc, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	return cc.publish(exchange, routingKey, msg)
+}
+
+// publishDelayed 声明延时队列后，通过默认交换机发送消息到延时队列。
+// 延时队列设置了闲置删除时间，超过delayQueueRedeclare后重新声明以重置闲置时间，保证队列在消息过期前不会被删除
+func (p *confirmPublisher) publishDelayed(queueName, exchange, routingKey string, delay time.Duration, msg amqp.Publishing) (<-chan error, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cc, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(cc.delayQueues[queueName]) > delayQueueRedeclare {
+		_, err = cc.channel.QueueDeclare(queueName, true, false, false, false, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-expires":                 (delay + delayQueueIdle).Milliseconds(),
+			"x-dead-letter-exchange":    exchange,
+			"x-dead-letter-routing-key": routingKey,
+		})
+		if err != nil {
+			zap.L().Error("RabbitMQ初始化延时队列失败", zap.String("queue", queueName), zap.Error(err))
+			return nil, err
+		}
+		cc.delayQueues[queueName] = time.Now()
+	}
+	return cc.publish("", queueName, msg)
+}
+
+// close 关闭发布通道
+func (p *confirmPublisher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current != nil {
+		_ = p.current.channel.Close()
+		p.current = nil
+	}
+}
+
+// publish 发送消息。先登记等待确认的通道再发送，避免确认先于登记到达
+func (cc *confirmChannel) publish(exchange, routingKey string, msg amqp.Publishing) (<-chan error, error) {
+	tag := cc.deliveryTag + 1
+	confirm := make(chan error, 1)
+	cc.pendingMu.Lock()
+	if cc.closed {
+		cc.pendingMu.Unlock()
+		return nil, ErrorNotConnected
+	}
+	cc.pending[tag] = confirm
+	cc.pendingMu.Unlock()
+
+	if err := cc.channel.Publish(exchange, routingKey, false, false, msg); err != nil {
+		cc.pendingMu.Lock()
+		delete(cc.pending, tag)
+		cc.pendingMu.Unlock()
+		return nil, err
+	}
+	cc.deliveryTag = tag
+	return confirm, nil
+}
+
+// dispatch 将发布确认分发给等待的发送者，通道关闭后所有未确认的消息都视为失败
+func (cc *confirmChannel) dispatch(confirms <-chan amqp.Confirmation) {
+	for confirm := range confirms {
+		cc.pendingMu.Lock()
+		ch, ok := cc.pending[confirm.DeliveryTag]
+		delete(cc.pending, confirm.DeliveryTag)
+		cc.pendingMu.Unlock()
+		if ok {
+			if confirm.Ack {
+				ch <- nil
+			} else {
+				ch <- ErrorPublishRejected
+			}
+		}
+	}
+	cc.pendingMu.Lock()
+	defer cc.pendingMu.Unlock()
+	cc.closed = true
+	for tag, ch := range cc.pending {
+		ch <- fmt.Errorf("发布通道已关闭: %w", ErrorPublishNotConfirmed)
+		delete(cc.pending, tag)
+	}
+}
+
+// waitConfirms 等待所有消息的发布确认
+func waitConfirms(confirms []<-chan error) error {
+	timer := time.NewTimer(confirmTimeout)
+	defer timer.Stop()
+	for _, confirm := range confirms {
+		select {
+		case err := <-confirm:
+			if err != nil {
+				return err
+			}
+		case <-timer.C:
+			return fmt.Errorf("等待RabbitMQ发布确认超时: %w", ErrorPublishNotConfirmed)
+		}
+	}
+	return nil
+}
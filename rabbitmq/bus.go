@@ -0,0 +1,140 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shop-backend/models/vo"
+	"time"
+)
+
+// 消息总线的实现
+const (
+	// DriverAMQP 使用RabbitMQ，默认
+	DriverAMQP = "amqp"
+	// DriverMemory 使用进程内的消息总线，用于单元测试以及没有RabbitMQ的开发环境，服务重启后未处理的消息会丢失
+	DriverMemory = "memory"
+)
+
+var (
+	ErrorPublishNotConfirmed = errors.New("消息未被确认接收")
+	// ErrorPublishRejected 消息被拒绝接收，例如队列已满并且溢出策略为reject-publish
+	ErrorPublishRejected  = fmt.Errorf("消息被拒绝接收: %w", ErrorPublishNotConfirmed)
+	ErrorExchangeNotExist = errors.New("交换机不存在")
+)
+
+// Message 批量发送的一条消息
+type Message struct {
+	RoutingKey string
+	Body       []byte
+}
+
+// Bus 消息总线，负责消息的发送、延时发送以及订阅。
+// 业务代码只依赖该接口，生产环境使用RabbitMQ实现，单元测试使用进程内实现
+type Bus interface {
+	// DeclareExchange 声明交换机，kind为direct、topic或fanout。发送消息、订阅前交换机必须已经声明
+	DeclareExchange(exchange, kind string) error
+	// Publish 发送消息到交换机，消息被确认接收后返回。没有队列与路由匹配的消息会被丢弃。
+	// ctx中的链路信息随消息头传递给消费者，ctx取消不会中断发送
+	Publish(ctx context.Context, exchange, routingKey string, body []byte) error
+	// PublishBatch 发送一批消息到交换机，所有消息都被确认接收后返回
+	PublishBatch(ctx context.Context, exchange string, messages []*Message) error
+	// PublishDelayed 在delay后将消息发送到交换机
+	PublishDelayed(ctx context.Context, exchange, routingKey string, body []byte, delay time.Duration) error
+	// Subscribe 声明接收者的队列并绑定到交换机，开始消费消息。
+	// 处理失败的消息按照接收者的重试策略延时重试，失败次数达到上限后进入死信队列
+	Subscribe(exchange string, receiver Receiver) error
+	// Connected 是否可以发送消息
+	Connected() bool
+	// Ping 检查连接以及通道是否可用
+	Ping() error
+	// Drain 停止所有消费者接收新的消息，等待正在处理的消息处理完成，ctx结束时不再等待。
+	// Drain之后仍然可以发送消息
+	Drain(ctx context.Context) error
+	// Close 停止消费并关闭总线，ctx结束时不再等待正在处理的消息，返回ctx.Err()
+	Close(ctx context.Context) error
+
+	// ListDeadLetterQueues 获取所有死信队列以及其中的消息数量
+	ListDeadLetterQueues() ([]*vo.DeadLetterQueueVO, error)
+	// PeekDeadLetters 查看死信队列中的前count条消息，消息仍然保留在死信队列中
+	PeekDeadLetters(queueName string, count int) ([]*vo.DeadLetterVO, error)
+	// ReplayDeadLetters 将死信队列中的前count条消息重新发送到原队列，失败次数清零。返回重新发送的消息数量
+	ReplayDeadLetters(queueName string, count int) (int, error)
+	// PurgeDeadLetters 清空死信队列，返回删除的消息数量
+	PurgeDeadLetters(queueName string) (int, error)
+}
+
+// 当前使用的消息总线，Init之前发送消息会返回ErrorNotConnected
+var bus Bus = disconnectedBus{}
+
+// Connected 是否已经连接到消息总线
+func Connected() bool {
+	return bus.Connected()
+}
+
+// Ping 检查消息总线的连接以及通道是否可用，Init之前返回ErrorNotConnected
+func Ping() error {
+	return bus.Ping()
+}
+
+// ListDeadLetterQueues 获取所有死信队列以及其中的消息数量
+func ListDeadLetterQueues() ([]*vo.DeadLetterQueueVO, error) {
+	return bus.ListDeadLetterQueues()
+}
+
+// PeekDeadLetters 查看死信队列中的前count条消息，消息仍然保留在死信队列中
+func PeekDeadLetters(queueName string, count int) ([]*vo.DeadLetterVO, error) {
+	return bus.PeekDeadLetters(queueName, count)
+}
+
+// ReplayDeadLetters 将死信队列中的前count条消息重新发送到原队列，失败次数清零。返回重新发送的消息数量
+func ReplayDeadLetters(queueName string, count int) (int, error) {
+	return bus.ReplayDeadLetters(queueName, count)
+}
+
+// PurgeDeadLetters 清空死信队列，返回删除的消息数量
+func PurgeDeadLetters(queueName string) (int, error) {
+	return bus.PurgeDeadLetters(queueName)
+}
+
+// disconnectedBus 初始化之前使用的消息总线，所有操作都返回ErrorNotConnected
+type disconnectedBus struct{}
+
+func (disconnectedBus) DeclareExchange(string, string) error { return ErrorNotConnected }
+func (disconnectedBus) Publish(context.Context, string, string, []byte) error {
+	return ErrorNotConnected
+}
+func (disconnectedBus) PublishBatch(context.Context, string, []*Message) error {
+	return ErrorNotConnected
+}
+func (disconnectedBus) Subscribe(string, Receiver) error           { return ErrorNotConnected }
+func (disconnectedBus) Connected() bool                            { return false }
+func (disconnectedBus) Ping() error                                { return ErrorNotConnected }
+func (disconnectedBus) Drain(context.Context) error                { return nil }
+func (disconnectedBus) Close(context.Context) error                { return nil }
+func (disconnectedBus) PurgeDeadLetters(string) (int, error)       { return 0, ErrorNotConnected }
+func (disconnectedBus) ReplayDeadLetters(string, int) (int, error) { return 0, ErrorNotConnected }
+func (disconnectedBus) ListDeadLetterQueues() ([]*vo.DeadLetterQueueVO, error) {
+	return nil, ErrorNotConnected
+}
+func (disconnectedBus) PeekDeadLetters(string, int) ([]*vo.DeadLetterVO, error) {
+	return nil, ErrorNotConnected
+}
+func (disconnectedBus) PublishDelayed(context.Context, string, string, []byte, time.Duration) error {
+	return ErrorNotConnected
+}
+
+// waitDone 等待wait返回，ctx结束时不再等待并返回ctx的错误
+func waitDone(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
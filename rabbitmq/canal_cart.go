@@ -1,12 +1,12 @@
 package rabbitmq
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/golang/protobuf/proto"
-	"github.com/streadway/amqp"
-	pbe "github.com/withlin/canal-go/protocol/entry"
 	"go.uber.org/zap"
+	"shop-backend/cdc"
 	"shop-backend/dao/redis"
+	"shop-backend/logger"
 	"shop-backend/models/pojo"
 	"shop-backend/models/vo"
 	"shop-backend/utils/build"
@@ -14,11 +14,13 @@ import (
 	"time"
 )
 
-// CanalCartReceiver 实现了Receiver接口
+// 购物车变更的表名
+const cartTable = "oms_cart"
+
+// CanalCartReceiver 实现了Receiver接口，负责异步缓存用户购物车列表
 type CanalCartReceiver struct {
 	queueName string
 	routerKey string
-	e         error
 	body      []byte
 }
 
@@ -40,170 +42,75 @@ func (r *CanalCartReceiver) RoutingKey() string {
 	return r.routerKey
 }
 
-// OnError 将执行过程中产生的异常赋值到接收者
+// OnError 记录消费者初始化通道时产生的异常，该消费者会关闭通道并稍后重试，不影响其他消费者
 func (r *CanalCartReceiver) OnError(e error) {
-	r.e = e
+	zap.L().Error("购物车缓存的消费者初始化通道失败", zap.String("queue", r.queueName), zap.Error(e))
 }
 
-// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会反复处理消息，直到处理成功
+// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会延时重试，失败次数达到上限后进入死信队列
 func (r *CanalCartReceiver) OnReceive(body []byte) bool {
-	if r.e != nil {
-		zap.L().Error("消费canal购物车消息出现异常", zap.Error(r.e))
+	return r.OnReceiveContext(context.Background(), body)
+}
+
+// OnReceiveContext 在查询购物车请求的链路中将购物车列表写入缓存
+func (r *CanalCartReceiver) OnReceiveContext(ctx context.Context, body []byte) bool {
+	// 查询类型的数据
+	data := new(vo.UserCartProductVOList)
+	// 反序列json
+	err := json.Unmarshal(body, &data)
+	if err != nil {
+		logger.Ctx(ctx).Error("canal购物车服务，解析json失败", zap.Error(err))
 		return false
 	}
 
-	if r.queueName != CanalCartSelectQueueName {
-		// 接收到的为数据库变更信息，增加、删除、更新。更新到Redis缓存的是一条购物车商品数据，所以序列化为pojo.Cart类型
-		data := new(pojo.Cart)
-		// 反序列json
-		err := json.Unmarshal(body, &data)
-		if err != nil {
-			zap.L().Error("canal购物车服务，解析json失败", zap.Error(err))
-			return false
-		}
-
-		if r.queueName == CanalCartInsertQueueName {
-			// 新增或更新类型的变更消息
-			// 创建一个存入购物车商品展示对象的通道，缓存区为1
-			channel := make(chan *vo.CartProductVO, 1)
-			defer close(channel)
-			// 添加到Redis缓存中
-			if err = redis.AddCartProduct(data.UserID, data.SkuID, build.CreateCartProductVO(data, channel)); err != nil {
-				return false
-			}
-		} else if r.queueName == CanalCartDeleteQueueName {
-			// 删除类型的变更消息
-			if err = redis.DelCartProduct(data.UserID, data.SkuID); err != nil {
-				return false
-			}
-		}
-	} else if r.queueName == CanalCartSelectQueueName {
-		// 查询类型的数据
-		data := new(vo.UserCartProductVOList)
-		// 反序列json
-		err := json.Unmarshal(body, &data)
-		if err != nil {
-			zap.L().Error("canal购物车服务，解析json失败", zap.Error(err))
-			return false
-		}
-
-		// 将用户购物车列表添加到缓存中
-		if err = redis.AddCartProductList(data.UserID, data.CartList); err != nil {
-			return false
-		}
+	// 将用户购物车列表添加到缓存中
+	if err = redis.AddCartProductList(ctx, data.UserID, data.CartList); err != nil {
+		return false
 	}
 	// 成功消费
 	return true
 }
 
-// SendDBInfo2MQ 将数据库变更信息发送到MQ中
-func SendDBInfo2MQ(entrys []pbe.Entry) error {
-	// 循环消费数据库变更消息
-	for _, entry := range entrys {
-		if entry.GetEntryType() == pbe.EntryType_TRANSACTIONBEGIN || entry.GetEntryType() == pbe.EntryType_TRANSACTIONEND {
-			// 如果信息类型为事务开始和事务停止，就跳过此条信息
-			continue
-		}
-		// 解析变更信息
-		rowChange := new(pbe.RowChange)
-		err := proto.Unmarshal(entry.GetStoreValue(), rowChange)
-		if err != nil {
-			zap.L().Error("反序列化canal消息失败", zap.Error(err))
-			continue
-		}
-
-		// 行变更信息不为空
-		if rowChange != nil {
-			// 获取行变更数据的类型(增、删、改、查)
-			eventType := rowChange.GetEventType()
-			for _, rowData := range rowChange.GetRowDatas() {
-				// 到此变更数据都为单条
-				if eventType == pbe.EventType_UPDATE || eventType == pbe.EventType_INSERT {
-					// 变更信息为更新/新增数据
-					sendMess2Queue(rowData.GetAfterColumns(), CanalCartInsertRoutingKey)
-				} else if eventType == pbe.EventType_DELETE {
-					// 变更信息为删除数据
-					sendMess2Queue(rowData.GetBeforeColumns(), CanalCartDeleteRoutingKey)
-				} else {
-					continue
-				}
-			}
-		}
+// handleCartEvent 根据oms_cart的变更事件更新Redis购物车缓存。更新到Redis缓存的是一条购物车商品数据
+func handleCartEvent(event *cdc.Event) error {
+	// 数据库变更事件不是由请求产生的，没有请求ID
+	ctx := context.Background()
+	data := buildCartVO(event.Row())
+	if event.Type == cdc.EventDelete {
+		// 删除类型的变更事件
+		return redis.DelCartProduct(ctx, data.UserID, data.SkuID)
 	}
-	return nil
-}
-
-// 负责发送更新或新增、删除类型的数据库变更数据
-func sendMess2Queue(columns []*pbe.Column, routingKey string) {
-	// 构建要发送到MQ的对象
-	data := buildCartVO(columns)
-	if data == nil {
-		// 如果对象为空，则不发送
-		return
-	}
-	// 转换为json数据
-	dataJson, err := json.Marshal(data)
-	if err != nil {
-		zap.L().Error("canal购物车服务，购物车商品序列化为json失败", zap.Error(err))
-		return
-	}
-
-	// 发送消息
-	err = rabbitmqChannel2.Publish(
-		CanalCartExchangeName,
-		routingKey,
-		false,
-		false,
-		amqp.Publishing{
-			DeliveryMode: 2, // 2 表示消息持久化
-			ContentType:  "application/json",
-			Body:         dataJson,
-		},
-	)
-	if err != nil {
-		zap.L().Error("canal购物车服务，发送消息到RabbitMQ失败", zap.Error(err))
-		return
-	}
-	zap.L().Info("canal购物车服务，发送消息到RabbitMQ成功")
-	return
+	// 新增或更新类型的变更事件
+	// 创建一个存入购物车商品展示对象的通道，缓存区为1
+	channel := make(chan *vo.CartProductVO, 1)
+	defer close(channel)
+	// 添加到Redis缓存中
+	return redis.AddCartProduct(ctx, data.UserID, data.SkuID, build.CreateCartProductVO(ctx, data, channel))
 }
 
 // SendListMess2Queue 负责发送用户购物车列表数据
-func SendListMess2Queue(data *vo.UserCartProductVOList) {
+func SendListMess2Queue(ctx context.Context, data *vo.UserCartProductVOList) {
 	// 转换为json数据
 	dataJson, err := json.Marshal(data)
 	if err != nil {
-		zap.L().Error("canal购物车服务，购物车商品列表序列化为json失败", zap.Error(err))
+		logger.Ctx(ctx).Error("canal购物车服务，购物车商品列表序列化为json失败", zap.Error(err))
 		return
 	}
 
 	// 发送消息
-	err = rabbitmqChannel2.Publish(
-		CanalCartExchangeName,
-		CanalCartSelectRoutingKey,
-		false,
-		false,
-		amqp.Publishing{
-			DeliveryMode: 2, // 2 表示消息持久化
-			ContentType:  "application/json",
-			Body:         dataJson,
-		},
-	)
+	err = bus.Publish(ctx, CanalCartExchangeName, CanalCartSelectRoutingKey, dataJson)
 	if err != nil {
-		zap.L().Error("canal购物车服务，发送消息到RabbitMQ失败", zap.Error(err))
+		logger.Ctx(ctx).Error("canal购物车服务，发送消息到RabbitMQ失败", zap.Error(err))
 		return
 	}
-	zap.L().Info("canal购物车服务，发送消息到RabbitMQ成功")
+	logger.Ctx(ctx).Info("canal购物车服务，发送消息到RabbitMQ成功")
 	return
 }
 
 // 构建购物车商品对象
-func buildCartVO(columns []*pbe.Column) *pojo.Cart {
+func buildCartVO(row map[string]string) *pojo.Cart {
 	product := new(pojo.Cart)
-	for _, col := range columns {
-		key := col.GetName()
-		value := col.GetValue()
-
+	for key, value := range row {
 		if key == "user_id" {
 			uid, _ := strconv.ParseInt(value, 10, 64)
 			product.UserID = uid
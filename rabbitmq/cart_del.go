@@ -1,8 +1,9 @@
 package rabbitmq
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/streadway/amqp"
+	"errors"
 	"go.uber.org/zap"
 	"shop-backend/dao/mysql"
 	"shop-backend/models/dto"
@@ -13,7 +14,6 @@ import (
 type CartDelReceiver struct {
 	queueName string
 	routerKey string
-	e         error
 	body      []byte
 }
 
@@ -35,52 +35,36 @@ func (r *CartDelReceiver) RoutingKey() string {
 	return r.routerKey
 }
 
-// OnError 将执行过程中产生的异常赋值到接收者
+// OnError 记录消费者初始化通道时产生的异常，该消费者会关闭通道并稍后重试，不影响其他消费者
 func (r *CartDelReceiver) OnError(e error) {
-	r.e = e
+	zap.L().Error("异步删除购物车的消费者初始化通道失败", zap.String("queue", r.queueName), zap.Error(e))
 }
 
-// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会反复处理消息，直到处理成功
+// ConsumerOptions 删除不同用户的购物车互不影响，使用多个消费者
+func (r *CartDelReceiver) ConsumerOptions() *ConsumerOptions {
+	return &ConsumerOptions{Workers: 2, Prefetch: 8}
+}
+
+// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会延时重试，失败次数达到上限后进入死信队列
 func (r *CartDelReceiver) OnReceive(body []byte) bool {
-	if r.e != nil {
-		zap.L().Error("消费异步删除购物车消息出现异常", zap.Error(r.e))
-		return false
-	}
+	return r.OnReceiveContext(context.Background(), body)
+}
+
+// OnReceiveContext 在提交订单请求的链路中删除购物车
+func (r *CartDelReceiver) OnReceiveContext(ctx context.Context, body []byte) bool {
 	data := new(dto.CartProductListDTO)
 	_ = json.Unmarshal(body, &data)
 	uid := data.UserID
 	for _, cart := range data.CartProductList {
 		skuId, _ := strconv.ParseInt(cart.SkuID, 10, 64)
-		err := mysql.DelCartProductBySkuIDAndUID(uid, skuId, cart.Specification)
+		err := mysql.DelCartProductBySkuIDAndUID(ctx, uid, skuId, cart.Specification)
+		if errors.Is(err, mysql.ErrorCartProductNotExist) {
+			// 发件箱至少发送一次，重复的消息中的商品已经被删除
+			continue
+		}
 		if err != nil {
 			return false
 		}
 	}
 	return true
 }
-
-// SendCartDelMess2MQ 负责发送要删除的购物车商品信息到RabbitMQ
-func SendCartDelMess2MQ(data *dto.CartProductListDTO) {
-	sendSucc := false
-	for !sendSucc {
-		// 转换为json数据
-		dataJson, _ := json.Marshal(data)
-		// 发送消息
-		err = rabbitmqChannel3.Publish(
-			CartDelExchangeName,
-			CartDeleteRoutingKey,
-			false,
-			false,
-			amqp.Publishing{
-				DeliveryMode: 2, // 2 表示消息持久化
-				ContentType:  "application/json",
-				Body:         dataJson,
-			},
-		)
-		if err != nil {
-			zap.L().Error("异步删除购物车服务，发送消息到RabbitMQ失败", zap.Error(err))
-		}
-		sendSucc = true
-		zap.L().Info("异步删除购物车服务，发送消息到RabbitMQ成功")
-	}
-}
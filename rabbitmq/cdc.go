@@ -0,0 +1,107 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"shop-backend/cdc"
+	"sort"
+)
+
+var (
+	// cacheDispatcher 维护Redis缓存一致性的变更事件分发器，所有服务实例共享一个队列
+	cacheDispatcher = cdc.NewDispatcher().
+			Register(cartTable, handleCartEvent).
+			Register(productTableSpu, handleProductCacheEvent).
+			Register(productTableSku, handleProductCacheEvent).
+			Register(productTableSkuPic, handleProductCacheEvent).
+			Register(categoryTable, handleCategoryCacheEvent)
+	// searchIndexDispatcher 更新内存全文索引的变更事件分发器，每个服务实例一个队列
+	searchIndexDispatcher = cdc.NewDispatcher().
+				Register(productTableSku, handleProductIndexEvent).
+				Register(productTableSpu, handleProductIndexEvent)
+)
+
+// CDCTables 返回所有注册过变更事件Handler的表，canal只需要订阅这些表
+func CDCTables() []string {
+	set := make(map[string]struct{})
+	for _, dispatcher := range []*cdc.Dispatcher{cacheDispatcher, searchIndexDispatcher} {
+		for _, table := range dispatcher.Tables() {
+			set[table] = struct{}{}
+		}
+	}
+	tables := make([]string, 0, len(set))
+	for table := range set {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// CDCReceiver 实现了Receiver、MultiRoutingReceiver接口，负责将数据库变更事件交给分发器处理
+type CDCReceiver struct {
+	queueName  string
+	dispatcher *cdc.Dispatcher
+}
+
+// NewCDCReceiver 初始化一个消费数据库变更事件的mq接收者，队列绑定分发器中注册过的所有表、变更类型
+func NewCDCReceiver(queueName string, dispatcher *cdc.Dispatcher) *CDCReceiver {
+	return &CDCReceiver{
+		queueName:  queueName,
+		dispatcher: dispatcher,
+	}
+}
+
+// QueueName 返回队列名称
+func (r *CDCReceiver) QueueName() string {
+	return r.queueName
+}
+
+// RoutingKey 返回RoutingKey
+func (r *CDCReceiver) RoutingKey() string {
+	return ""
+}
+
+// RoutingKeys 返回队列需要绑定的所有RoutingKey
+func (r *CDCReceiver) RoutingKeys() []string {
+	return r.dispatcher.RoutingKeys()
+}
+
+// OnError 记录消费者初始化通道时产生的异常，该消费者会关闭通道并稍后重试，不影响其他消费者
+func (r *CDCReceiver) OnError(e error) {
+	zap.L().Error("数据库变更事件的消费者初始化通道失败", zap.String("queue", r.queueName), zap.Error(e))
+}
+
+// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会延时重试，失败次数达到上限后进入死信队列
+func (r *CDCReceiver) OnReceive(body []byte) bool {
+	event := new(cdc.Event)
+	if err := json.Unmarshal(body, event); err != nil {
+		zap.L().Error("数据库变更事件服务，解析json失败", zap.Error(err))
+		// 格式错误的消息重试也无法成功
+		return true
+	}
+	return r.dispatcher.Dispatch(event) == nil
+}
+
+// PublishCDCEvents 将数据库变更事件按照 cdc.<表名>.<变更类型> 路由发送到MQ中，
+// 只有所有事件都被MQ确认(持久化)后才返回nil
+func PublishCDCEvents(ctx context.Context, events []*cdc.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	messages := make([]*Message, 0, len(events))
+	for _, event := range events {
+		dataJson, err := json.Marshal(event)
+		if err != nil {
+			zap.L().Error("数据库变更事件服务，序列化为json失败", zap.Error(err))
+			return err
+		}
+		messages = append(messages, &Message{RoutingKey: event.RoutingKey(), Body: dataJson})
+	}
+	if err := bus.PublishBatch(ctx, CDCExchangeName, messages); err != nil {
+		zap.L().Error("数据库变更事件服务，发送消息到RabbitMQ失败", zap.Int("count", len(events)), zap.Error(err))
+		return err
+	}
+	zap.L().Info("数据库变更事件服务，发送消息到RabbitMQ成功", zap.Int("count", len(events)))
+	return nil
+}
@@ -8,17 +8,11 @@ const (
 	SmsRoutingKey   = "sms_routing_key"
 )
 
-// Canal购物车服务消息队列配置
+// 异步缓存用户购物车列表消息队列配置
 const (
 	CanalCartExchangeName = "canal_cart_direct_exchange"
 	CanalCartExchangeType = "direct"
 
-	CanalCartInsertQueueName  = "canal_cart_insert_queue"
-	CanalCartInsertRoutingKey = "canal_cart_insert_routing_key"
-
-	CanalCartDeleteQueueName  = "canal_cart_delete_queue"
-	CanalCartDeleteRoutingKey = "canal_cart_delete_routing_key"
-
 	CanalCartSelectQueueName  = "canal_cart_select_queue"
 	CanalCartSelectRoutingKey = "canal_cart_select_routing_key"
 )
@@ -61,3 +55,14 @@ const (
 	// SecKillOverflow 队列满了之后的拒绝策略为直接拒绝发布新的消息
 	SecKillOverflow = "reject-publish"
 )
+
+// 数据库变更事件(CDC)消息队列配置。canal解析binlog后，以 cdc.<表名>.<变更类型> 为路由发送到topic交换机
+const (
+	CDCExchangeName = "cdc_topic_exchange"
+	CDCExchangeType = "topic"
+
+	// CDCCacheQueueName 购物车、商品详情、商品分类等Redis缓存所有服务实例共享，只需要一个队列
+	CDCCacheQueueName = "cdc_cache_queue"
+	// CDCSearchIndexQueuePrefix 每个服务实例都维护自己的内存索引，所以每个实例使用独立的队列(队列名后缀为machine_id)
+	CDCSearchIndexQueuePrefix = "cdc_search_index_queue_"
+)
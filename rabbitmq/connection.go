@@ -0,0 +1,163 @@
+package rabbitmq
+
+import (
+	"errors"
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	// 重连的初始等待时间以及最大等待时间，每次失败后等待时间翻倍
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = time.Minute
+)
+
+var ErrorNotConnected = errors.New("RabbitMQ未连接")
+
+// connManager 管理与RabbitMQ的连接：监听连接关闭并按照退避时间重连，为消费者、发布者提供新的通道
+type connManager struct {
+	url string
+
+	mu   sync.RWMutex
+	conn *amqp.Connection
+	// 连接成功时关闭，用于唤醒等待连接的消费者。连接断开后替换为新的通道
+	ready chan struct{}
+	// 调用close后不再重连
+	closed bool
+	// 调用close时关闭，用于唤醒等待重连的协程
+	done chan struct{}
+}
+
+// newConnManager 创建连接管理器，并在后台开始连接
+func newConnManager(url string) *connManager {
+	m := &connManager{
+		url:   url,
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// run 连接RabbitMQ，连接断开后按照退避时间重连
+func (m *connManager) run() {
+	backoff := minReconnectBackoff
+	for {
+		conn, err := amqp.Dial(m.url)
+		if err != nil {
+			if m.isClosed() {
+				return
+			}
+			zap.L().Error("连接RabbitMQ失败，等待后重连", zap.Duration("backoff", backoff), zap.Error(err))
+			if !m.sleep(backoff) {
+				return
+			}
+			backoff = nextReconnectBackoff(backoff)
+			continue
+		}
+		closeNotify := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		m.conn = conn
+		close(m.ready)
+		m.mu.Unlock()
+		zap.L().Info("连接RabbitMQ成功")
+		backoff = minReconnectBackoff
+
+		// 阻塞直到连接关闭
+		amqpErr := <-closeNotify
+
+		m.mu.Lock()
+		m.conn = nil
+		m.ready = make(chan struct{})
+		closed := m.closed
+		m.mu.Unlock()
+		if closed {
+			return
+		}
+		zap.L().Error("RabbitMQ连接断开，等待后重连", zap.Duration("backoff", backoff), zap.Any("reason", amqpErr))
+		if !m.sleep(backoff) {
+			return
+		}
+	}
+}
+
+// sleep 等待backoff后返回true，等待期间调用了close时立即返回false
+func (m *connManager) sleep(backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-m.done:
+		return false
+	}
+}
+
+// isClosed 是否已经调用了close
+func (m *connManager) isClosed() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.closed
+}
+
+// connected 是否已经连接到RabbitMQ
+func (m *connManager) connected() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.conn != nil
+}
+
+// channel 在当前连接上打开一个新的通道，未连接时返回ErrorNotConnected
+func (m *connManager) channel() (*amqp.Channel, error) {
+	m.mu.RLock()
+	conn := m.conn
+	m.mu.RUnlock()
+	if conn == nil {
+		return nil, ErrorNotConnected
+	}
+	return conn.Channel()
+}
+
+// waitChannel 等待连接成功后打开一个新的通道，stop关闭时不再等待，返回ErrorNotConnected
+func (m *connManager) waitChannel(stop <-chan struct{}) (*amqp.Channel, error) {
+	m.mu.RLock()
+	ready := m.ready
+	m.mu.RUnlock()
+	select {
+	case <-ready:
+		return m.channel()
+	case <-stop:
+		return nil, ErrorNotConnected
+	}
+}
+
+// close 关闭连接，并不再重连
+func (m *connManager) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	close(m.done)
+	if m.conn != nil {
+		_ = m.conn.Close()
+	}
+}
+
+// nextReconnectBackoff 等待时间翻倍，不超过最大等待时间
+func nextReconnectBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > maxReconnectBackoff {
+		return maxReconnectBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,51 @@
+package rabbitmq
+
+import "shop-backend/settings"
+
+// ConsumerOptions 接收者的消费者数量以及每个消费者的预取数量
+type ConsumerOptions struct {
+	// 消费者(协程)数量，每个消费者使用独立的通道。大于1时同一个队列中的消息不再按照顺序处理
+	Workers int
+	// 每个消费者未应答的最大消息数量
+	Prefetch int
+}
+
+// ConcurrentReceiver 声明默认消费者数量、预取数量的接收者，没有实现该接口的接收者使用defaultConsumerOptions。
+// config.yaml中 rabbitmq.consumers.<队列名> 的配置优先于接收者声明的默认值
+type ConcurrentReceiver interface {
+	ConsumerOptions() *ConsumerOptions
+}
+
+// 默认一个消费者，一次只处理一条消息，保证消息按照顺序处理
+var defaultConsumerOptions = ConsumerOptions{
+	Workers:  1,
+	Prefetch: 1,
+}
+
+// 每个队列的消费者配置，K为队列名。Init时从配置文件读取
+var consumerConf map[string]*settings.ConsumerConfig
+
+// getConsumerOptions 获取接收者的消费者数量以及预取数量
+func getConsumerOptions(receiver Receiver) ConsumerOptions {
+	options := defaultConsumerOptions
+	if concurrent, ok := receiver.(ConcurrentReceiver); ok {
+		if declared := concurrent.ConsumerOptions(); declared != nil {
+			options = *declared
+		}
+	}
+	if conf, ok := consumerConf[receiver.QueueName()]; ok && conf != nil {
+		if conf.Workers > 0 {
+			options.Workers = conf.Workers
+		}
+		if conf.Prefetch > 0 {
+			options.Prefetch = conf.Prefetch
+		}
+	}
+	if options.Workers < 1 {
+		options.Workers = 1
+	}
+	if options.Prefetch < 1 {
+		options.Prefetch = 1
+	}
+	return options
+}
@@ -1,16 +1,19 @@
 package rabbitmq
 
 import (
+	"context"
 	"encoding/json"
 	"go.uber.org/zap"
 	"shop-backend/dao/mysql"
+	"shop-backend/logger"
+	"shop-backend/metrics"
+	"time"
 )
 
 // DelayOrderReceiver 实现了Receiver接口，负责订单超时未支付回滚
 type DelayOrderReceiver struct {
 	queueName string
 	routerKey string
-	e         error
 	body      []byte
 }
 
@@ -32,41 +35,39 @@ func (r *DelayOrderReceiver) RoutingKey() string {
 	return r.routerKey
 }
 
-// OnError 将执行过程中产生的异常赋值到接收者
+// OnError 记录消费者初始化通道时产生的异常，该消费者会关闭通道并稍后重试，不影响其他消费者
 func (r *DelayOrderReceiver) OnError(e error) {
-	r.e = e
+	zap.L().Error("订单超时回滚的消费者初始化通道失败", zap.String("queue", r.queueName), zap.Error(e))
 }
 
-// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会反复处理消息，直到处理成功
-func (r *DelayOrderReceiver) OnReceive(body []byte) bool {
-	if r.e != nil {
-		zap.L().Error("消费订单超时回滚消息出现异常", zap.Error(r.e))
-		return false
+// RetryPolicy 订单超时回滚失败会导致库存无法释放，需要更多的重试次数
+func (r *DelayOrderReceiver) RetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay:   5 * time.Second,
+		MaxDelay:    10 * time.Minute,
 	}
+}
+
+// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会延时重试，失败次数达到上限后进入死信队列
+func (r *DelayOrderReceiver) OnReceive(body []byte) bool {
+	return r.OnReceiveContext(context.Background(), body)
+}
+
+// OnReceiveContext 在提交订单请求的链路中处理消息，订单状态的查询、修改以及库存回滚记录在同一条链路中
+func (r *DelayOrderReceiver) OnReceiveContext(ctx context.Context, body []byte) bool {
 	var orderNum int64
-	_ = json.Unmarshal(body, &orderNum)
-	orderStatus, err := mysql.SelectOrderOrderStatus(orderNum)
-	if err != nil || orderStatus == 0 {
-		// 获取订单状态失败
+	if err := json.Unmarshal(body, &orderNum); err != nil {
+		logger.Ctx(ctx).Error("解析订单超时消息失败，丢弃消息", zap.ByteString("body", body), zap.Error(err))
+		return true
+	}
+	// 订单仍然未支付时，在同一个事务中修改订单状态为超时并回滚库存。已支付或已经超时的订单不做处理
+	timedOut, err := mysql.TimeoutOrder(ctx, orderNum)
+	if err != nil {
 		return false
 	}
-	if orderStatus == 5 {
-		// 订单已经超时
-		return true
-	} else if orderStatus == 6 {
-		// 订单超时后仍然未支付，将订单状态设置为超时未支付
-		err := mysql.UpdateOrderOrderStatus(orderNum, 5)
-		if err != nil {
-			return false
-		}
-		// 回滚库存
-		err = mysql.RollbackOrderStock(orderNum)
-		if err != nil {
-			return false
-		}
-	} else {
-		// 订单已支付
-		return true
+	if timedOut {
+		metrics.OrderTimedOut()
 	}
 	return true
 }
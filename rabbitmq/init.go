@@ -1,133 +1,91 @@
 package rabbitmq
 
 import (
+	"context"
 	"fmt"
-	"github.com/streadway/amqp"
+	"shop-backend/lifecycle"
 	"shop-backend/settings"
+	"shop-backend/utils/concatstr"
+	"strconv"
 )
 
-var rabbitmqConn *amqp.Connection
-var rabbitmqChannel *amqp.Channel
-var rabbitmqChannel2 *amqp.Channel
-var rabbitmqChannel3 *amqp.Channel
-var rabbitmqChannel4 *amqp.Channel
-
-var rabbitmqChannel5 *amqp.Channel
-var err error
-
-// Init 初始化RabbitMQ
-func Init(cfg *settings.RabbitMQConfig) {
-	// 构造RabbitMQ连接url
-	url := fmt.Sprintf("amqp://%s:%s@%s:%d/",
-		cfg.User,
-		cfg.Password,
-		cfg.Host,
-		cfg.Port)
-	// 连接RabbitMQ
-	rabbitmqConn, err = amqp.Dial(url)
-	if err != nil {
-		panic("RabbitMQConfig 初始化失败: " + err.Error())
-	}
-
-	// 初始化channel
-	rabbitmqChannel, err = rabbitmqConn.Channel()
-	if err != nil {
-		panic("打开Channel失败: " + err.Error())
+// Init 根据配置创建消息总线，声明交换机并订阅所有接收者，然后启动发件箱中继。
+// 使用RabbitMQ时连接在后台建立，连接成功前发送消息会返回ErrorNotConnected
+func Init(cfg *settings.RabbitMQConfig) error {
+	consumerConf = cfg.Consumers
+	switch cfg.Driver {
+	case "", DriverAMQP:
+		// 构造RabbitMQ连接url
+		url := fmt.Sprintf("amqp://%s:%s@%s:%d/",
+			cfg.User,
+			cfg.Password,
+			cfg.Host,
+			cfg.Port)
+		return Setup(newAMQPBus(url))
+	case DriverMemory:
+		return Setup(NewMemoryBus())
+	default:
+		return fmt.Errorf("不支持的消息总线: %s", cfg.Driver)
 	}
+}
 
-	rabbitmqChannel2, err = rabbitmqConn.Channel()
-	if err != nil {
-		panic("打开Channel失败: " + err.Error())
+// Setup 使用指定的消息总线，声明交换机并订阅所有接收者，然后启动发件箱中继。
+// 服务退出时先等待消费者处理完正在处理的消息，再由中继发送发件箱中剩余的消息，最后关闭总线。
+// 单元测试中可以传入NewMemoryBus()创建的总线
+func Setup(b Bus) error {
+	bus = instrumentedBus{b}
+
+	// 声明交换机
+	exchanges := []struct{ name, kind string }{
+		{SmsExchangeName, SmsExchangeType},
+		{CanalCartExchangeName, CanalCartExchangeType},
+		{CartDelExchangeName, CartDelExchangeType},
+		{DelayOrderExchangeName, DelayOrderExchangeType},
+		{SecKillReqExchangeName, SecKillReqExchangeType},
+		{CDCExchangeName, CDCExchangeType},
 	}
-
-	rabbitmqChannel3, err = rabbitmqConn.Channel()
-	if err != nil {
-		panic("打开Channel失败: " + err.Error())
+	for _, exchange := range exchanges {
+		if err := bus.DeclareExchange(exchange.name, exchange.kind); err != nil {
+			return err
+		}
 	}
 
-	rabbitmqChannel4, err = rabbitmqConn.Channel()
-	if err != nil {
-		panic("打开Channel失败: " + err.Error())
+	// 每个接收者在每次连接成功后都会打开新的通道，断开后等待重连
+	subscriptions := []struct {
+		exchange string
+		receiver Receiver
+	}{
+		// 接受短信信息的接收者
+		{SmsExchangeName, NewSmsReceiver(SmsQueueName, SmsRoutingKey)},
+		// 接受用户购物车列表的接收者
+		{CanalCartExchangeName, NewCanalCartReceiver(CanalCartSelectQueueName, CanalCartSelectRoutingKey)},
+		// 提交订单后异步删除购物车的接收者
+		{CartDelExchangeName, NewCartDelReceiver(CartDeleteQueueName, CartDeleteRoutingKey)},
+		// 订单超时回滚的接收者
+		{DelayOrderExchangeName, NewDelayOrderReceiver(DelayOrderQueueName, DelayOrderRoutingKey)},
+		// 保存用户秒杀请求的接收者
+		{SecKillReqExchangeName, NewSecKillReceiver(SecKillReqQueueName, SecKillReqRoutingKey)},
+		// 接受数据库变更事件的接收者，每个接收者的队列只绑定其分发器中注册过的表
+		{CDCExchangeName, NewCDCReceiver(CDCCacheQueueName, cacheDispatcher)},
+		// 每个服务实例都维护自己的内存索引，使用独立的队列接收商品变更事件
+		{CDCExchangeName, NewCDCReceiver(
+			concatstr.ConcatString(CDCSearchIndexQueuePrefix, strconv.FormatInt(settings.Conf.MachineId, 10)),
+			searchIndexDispatcher)},
 	}
-
-	rabbitmqChannel5, err = rabbitmqConn.Channel()
-	if err != nil {
-		panic("打开Channel失败: " + err.Error())
+	for _, subscription := range subscriptions {
+		if err := bus.Subscribe(subscription.exchange, subscription.receiver); err != nil {
+			return err
+		}
 	}
 
-	// 初始化短信相关的RabbitMQ实体对象
-	smsMQ := NewSmsMQ()
-	// 将管道绑定到MQ对象上
-	smsMQ.channel = rabbitmqChannel
-	// 创建接受短信信息的接收者
-	smsReceiver := NewSmsReceiver(SmsQueueName, SmsRoutingKey)
-	// 将接收者绑定到RabbitMQ实体对象
-	smsMQ.RegisterReceiver(smsReceiver)
-	// 启动
-	go smsMQ.Start()
-
-	// 初始化canal购物车相关的RabbitMQ实体对象
-	canalCart := NewCanalCartMQ()
-	// 将管道绑定到MQ对象上
-	canalCart.channel = rabbitmqChannel2
-	// 创建接受数据库变更信息的接收者
-	cartReceiver1 := NewCanalCartReceiver(CanalCartInsertQueueName, CanalCartInsertRoutingKey)
-	cartReceiver2 := NewCanalCartReceiver(CanalCartDeleteQueueName, CanalCartDeleteRoutingKey)
-	cartReceiver3 := NewCanalCartReceiver(CanalCartSelectQueueName, CanalCartSelectRoutingKey)
-	// 将接收者绑定到RabbitMQ实体对象
-	canalCart.RegisterReceiver(cartReceiver1)
-	canalCart.RegisterReceiver(cartReceiver2)
-	canalCart.RegisterReceiver(cartReceiver3)
-	// 启动
-	go canalCart.Start()
-
-	// 初始化异步删除购物车相关的RabbitMQ实体对象
-	cartDel := NewCartDelMQ()
-	// 将管道绑定到MQ对象上
-	cartDel.channel = rabbitmqChannel3
-	// 创建接受数据库变更信息的接收者
-	cartDelReceiver := NewCartDelReceiver(CartDeleteQueueName, CartDeleteRoutingKey)
-	// 将接收者绑定到RabbitMQ实体对象
-	cartDel.RegisterReceiver(cartDelReceiver)
-	// 启动
-	go cartDel.Start()
-
-	// 初始化订单相关的RabbitMQ实体对象
-	order := NewOrderMQ()
-	// 将管道绑定到MQ对象上
-	order.channel = rabbitmqChannel4
-	// 暂时不需要消费普通订单队列中的消息
-	// orderReceiver := NewOrderReceiver(OrderQueueName, OrderRoutingKey)
-	// 将接收者绑定到RabbitMQ实体对象
-	// order.RegisterReceiver(orderReceiver)
-	go order.Start()
-
-	// 初始化订单超时回滚相关的RabbitMQ实体对象
-	delayOrder := NewDelayOrderMQ()
-	// 将管道绑定到MQ对象上
-	delayOrder.channel = rabbitmqChannel4
-	// 创建接受数据库变更信息的接收者
-	delayOrderReceiver := NewDelayOrderReceiver(DelayOrderQueueName, DelayOrderRoutingKey)
-	// 将接收者绑定到RabbitMQ实体对象
-	delayOrder.RegisterReceiver(delayOrderReceiver)
-	// 启动
-	go delayOrder.Start()
-
-	// 初始化秒杀商品相关的RabbitMQ实体对象
-	secKill := NewSecKillMQ()
-	// 将管道绑定到MQ对象上
-	secKill.channel = rabbitmqChannel5
-	// 创建接受数据库变更信息的接收者
-	secKillReceiver := NewSecKillReceiver(SecKillReqQueueName, SecKillReqRoutingKey)
-	// 将接收者绑定到RabbitMQ实体对象
-	secKill.RegisterReceiver(secKillReceiver)
-	// 启动
-	go secKill.Start()
+	lifecycle.Register("消息消费者", lifecycle.PhaseDrain, b.Drain)
+	// 启动发件箱中继，发送与业务数据在同一个事务中写入的消息
+	lifecycle.Go("发件箱中继", lifecycle.PhaseFlush, runOutboxRelay)
+	lifecycle.Register("消息总线", lifecycle.PhaseClose, b.Close)
+	return nil
 }
 
-// Destroy 销毁RabbitMQ连接和通道
-func Destroy() {
-	rabbitmqConn.Close()
-	rabbitmqChannel.Close()
-	rabbitmqChannel2.Close()
+// Destroy 关闭消息总线，使用RabbitMQ时关闭后不再重连。ctx结束时不再等待正在处理的消息
+func Destroy(ctx context.Context) error {
+	return bus.Close(ctx)
 }
@@ -0,0 +1,108 @@
+package rabbitmq
+
+import (
+	"context"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"shop-backend/metrics"
+	"shop-backend/tracing"
+	"time"
+)
+
+// instrumentedBus 在消息总线外层记录每个交换机、路由的发送结果，并为每次发送创建span
+type instrumentedBus struct {
+	Bus
+}
+
+func (b instrumentedBus) Publish(ctx context.Context, exchange, routingKey string, body []byte) error {
+	ctx, span := startPublishSpan(ctx, exchange, routingKey)
+	err := b.Bus.Publish(ctx, exchange, routingKey, body)
+	metrics.MQPublished(exchange, routingKey, 1, err)
+	tracing.End(span, err)
+	return err
+}
+
+func (b instrumentedBus) PublishBatch(ctx context.Context, exchange string, messages []*Message) error {
+	ctx, span := startPublishSpan(ctx, exchange, "")
+	span.SetAttributes(attribute.Int("messaging.batch_size", len(messages)))
+	err := b.Bus.PublishBatch(ctx, exchange, messages)
+	// 一批消息的路由可能不同，例如数据库变更事件的路由为cdc.<表名>.<变更类型>
+	counts := make(map[string]int)
+	for _, message := range messages {
+		counts[message.RoutingKey]++
+	}
+	for routingKey, count := range counts {
+		metrics.MQPublished(exchange, routingKey, count, err)
+	}
+	tracing.End(span, err)
+	return err
+}
+
+func (b instrumentedBus) PublishDelayed(ctx context.Context, exchange, routingKey string, body []byte, delay time.Duration) error {
+	ctx, span := startPublishSpan(ctx, exchange, routingKey)
+	span.SetAttributes(attribute.Int64("messaging.delay_ms", delay.Milliseconds()))
+	err := b.Bus.PublishDelayed(ctx, exchange, routingKey, body, delay)
+	metrics.MQPublished(exchange, routingKey, 1, err)
+	tracing.End(span, err)
+	return err
+}
+
+// startPublishSpan 创建发送消息的span，消费者的span是它的子span
+func startPublishSpan(ctx context.Context, exchange, routingKey string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, exchange+" send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("rabbitmq"),
+			semconv.MessagingDestinationKindTopic,
+			semconv.MessagingDestinationKey.String(exchange),
+			semconv.MessagingRabbitmqRoutingKeyKey.String(routingKey)))
+}
+
+// receive 在发送者传递的链路中调用接收者处理消息，实现了ContextReceiver的接收者可以在ctx中继续记录链路
+func receive(ctx context.Context, queueName string, receiver Receiver, body []byte) bool {
+	ctx, span := tracing.Tracer().Start(ctx, queueName+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("rabbitmq"),
+			semconv.MessagingOperationProcess,
+			attribute.String("messaging.rabbitmq.queue", queueName)))
+	defer span.End()
+	var ok bool
+	if contextReceiver, isContext := receiver.(ContextReceiver); isContext {
+		ok = contextReceiver.OnReceiveContext(ctx, body)
+	} else {
+		ok = receiver.OnReceive(body)
+	}
+	metrics.MQConsumed(queueName, ok)
+	if !ok {
+		span.SetStatus(codes.Error, "receiver 数据处理失败")
+	}
+	return ok
+}
+
+// injectHeaders 将ctx中的链路信息写入AMQP消息头，没有链路时返回nil
+func injectHeaders(ctx context.Context) amqp.Table {
+	carrier := tracing.Inject(ctx)
+	if carrier == nil {
+		return nil
+	}
+	headers := make(amqp.Table, len(carrier))
+	for key, value := range carrier {
+		headers[key] = value
+	}
+	return headers
+}
+
+// extractHeaders 从AMQP消息头中恢复链路信息，延时重试、死信重新发送的消息会保留原来的消息头
+func extractHeaders(headers amqp.Table) context.Context {
+	carrier := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if s, ok := value.(string); ok {
+			carrier[key] = s
+		}
+	}
+	return tracing.Extract(context.Background(), carrier)
+}
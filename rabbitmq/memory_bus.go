@@ -0,0 +1,478 @@
+package rabbitmq
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/metrics"
+	"shop-backend/models/vo"
+	"shop-backend/tracing"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MemoryBus 进程内的消息总线，与RabbitMQ的语义保持一致：按照交换机类型路由消息，
+// 支持延时发送、处理失败后按照重试策略延时重试、失败次数达到上限后进入死信队列，以及队列的最大长度。
+// 消息只保存在内存中，用于单元测试以及没有RabbitMQ的开发环境
+type MemoryBus struct {
+	mu        sync.RWMutex
+	exchanges map[string]*memoryExchange
+	queues    map[string]*memoryQueue
+
+	// 等待延时发送、等待重试的消息
+	delayMu sync.Mutex
+	delayID int64
+	delayed map[int64]*memoryDelayed
+
+	// 还没有处理完成的消息数量，包括等待延时发送、等待重试的消息
+	inflight int64
+	closed   chan struct{}
+	once     sync.Once
+	// 关闭后消费协程不再获取新的消息
+	stop     chan struct{}
+	stopOnce sync.Once
+	// 所有队列的消费协程
+	workers sync.WaitGroup
+}
+
+// memoryExchange 交换机以及绑定到交换机的队列
+type memoryExchange struct {
+	kind     string
+	bindings []*memoryBinding
+}
+
+// memoryBinding 队列与交换机的绑定
+type memoryBinding struct {
+	routingKey string
+	queue      *memoryQueue
+}
+
+// memoryQueue 队列，每个队列由一个协程按照顺序消费
+type memoryQueue struct {
+	name     string
+	receiver Receiver
+	policy   *RetryPolicy
+	// 队列的最大长度，队列满时拒绝发送新的消息，0表示不限制
+	maxLength int
+
+	mu          sync.Mutex
+	messages    []*memoryMessage
+	deadLetters []*memoryMessage
+	// 有新消息时通知消费协程，缓冲区为1
+	notify chan struct{}
+}
+
+// memoryMessage 队列中的消息
+type memoryMessage struct {
+	body []byte
+	// 链路信息
+	headers map[string]string
+	// 已经失败的次数
+	attempt  int
+	deadTime time.Time
+}
+
+// memoryDelayed 等待延时发送的消息
+type memoryDelayed struct {
+	timer *time.Timer
+	fn    func()
+}
+
+// NewMemoryBus 创建进程内的消息总线
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		exchanges: make(map[string]*memoryExchange),
+		queues:    make(map[string]*memoryQueue),
+		delayed:   make(map[int64]*memoryDelayed),
+		closed:    make(chan struct{}),
+		stop:      make(chan struct{}),
+	}
+}
+
+// DeclareExchange 声明交换机，重复声明时保留已有的绑定
+func (b *MemoryBus) DeclareExchange(exchange, kind string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.exchanges[exchange]; !ok {
+		b.exchanges[exchange] = &memoryExchange{kind: kind}
+	}
+	return nil
+}
+
+// Publish 发送消息到交换机，路由到的队列已满时返回ErrorPublishRejected
+func (b *MemoryBus) Publish(ctx context.Context, exchange, routingKey string, body []byte) error {
+	b.mu.RLock()
+	ex, ok := b.exchanges[exchange]
+	if !ok {
+		b.mu.RUnlock()
+		return ErrorExchangeNotExist
+	}
+	queues := make([]*memoryQueue, 0, 1)
+	for _, binding := range ex.bindings {
+		if routeMatch(ex.kind, binding.routingKey, routingKey) {
+			queues = append(queues, binding.queue)
+		}
+	}
+	b.mu.RUnlock()
+
+	if len(queues) == 0 {
+		zap.L().Warn("消息没有匹配的队列，已丢弃", zap.String("exchange", exchange), zap.String("routingKey", routingKey))
+		return nil
+	}
+	var err error
+	for _, queue := range queues {
+		if !b.enqueue(queue, &memoryMessage{body: body, headers: tracing.Inject(ctx)}, true) {
+			err = ErrorPublishRejected
+		}
+	}
+	return err
+}
+
+// PublishBatch 依次发送一批消息到交换机
+func (b *MemoryBus) PublishBatch(ctx context.Context, exchange string, messages []*Message) error {
+	for _, message := range messages {
+		if err := b.Publish(ctx, exchange, message.RoutingKey, message.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PublishDelayed 在delay后将消息发送到交换机
+func (b *MemoryBus) PublishDelayed(ctx context.Context, exchange, routingKey string, body []byte, delay time.Duration) error {
+	b.mu.RLock()
+	_, ok := b.exchanges[exchange]
+	b.mu.RUnlock()
+	if !ok {
+		return ErrorExchangeNotExist
+	}
+	b.schedule(delay, func() {
+		if err := b.Publish(ctx, exchange, routingKey, body); err != nil {
+			zap.L().Error("发送延时消息失败", zap.String("exchange", exchange), zap.String("routingKey", routingKey), zap.Error(err))
+		}
+	})
+	return nil
+}
+
+// Subscribe 声明接收者的队列并绑定到交换机，启动一个协程消费队列中的消息
+func (b *MemoryBus) Subscribe(exchange string, receiver Receiver) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ex, ok := b.exchanges[exchange]
+	if !ok {
+		return ErrorExchangeNotExist
+	}
+	queue, ok := b.queues[receiver.QueueName()]
+	if !ok {
+		queue = &memoryQueue{
+			name:     receiver.QueueName(),
+			receiver: receiver,
+			policy:   getRetryPolicy(receiver),
+			notify:   make(chan struct{}, 1),
+		}
+		if maxLength, ok := queueArgs(queue.name)["x-max-length"].(int); ok {
+			queue.maxLength = maxLength
+		}
+		b.queues[queue.name] = queue
+		// 与RabbitMQ一样，每个消费者一个协程，多个消费者时消息不再按照顺序处理
+		for i := 0; i < getConsumerOptions(receiver).Workers; i++ {
+			b.workers.Add(1)
+			go b.consume(queue)
+		}
+	}
+	routingKeys := []string{receiver.RoutingKey()}
+	if multi, ok := receiver.(MultiRoutingReceiver); ok {
+		routingKeys = multi.RoutingKeys()
+	}
+	for _, routingKey := range routingKeys {
+		ex.bindings = append(ex.bindings, &memoryBinding{routingKey: routingKey, queue: queue})
+	}
+	return nil
+}
+
+// Connected 进程内的消息总线总是可用的
+func (b *MemoryBus) Connected() bool {
+	select {
+	case <-b.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+// Ping 关闭后返回ErrorNotConnected
+func (b *MemoryBus) Ping() error {
+	if !b.Connected() {
+		return ErrorNotConnected
+	}
+	return nil
+}
+
+// Drain 停止消费并等待正在处理的消息处理完成，队列中未处理的消息保留到Close
+func (b *MemoryBus) Drain(ctx context.Context) error {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+	})
+	return waitDone(ctx, b.workers.Wait)
+}
+
+// Close 停止消费并等待正在处理的消息处理完成，丢弃队列中未处理以及等待延时发送的消息。
+// ctx结束时不再等待并返回ctx.Err()
+func (b *MemoryBus) Close(ctx context.Context) error {
+	b.once.Do(func() {
+		b.stopOnce.Do(func() {
+			close(b.stop)
+		})
+		close(b.closed)
+		b.delayMu.Lock()
+		for id, delayed := range b.delayed {
+			delayed.timer.Stop()
+			delete(b.delayed, id)
+		}
+		b.delayMu.Unlock()
+	})
+	return waitDone(ctx, b.workers.Wait)
+}
+
+// WaitIdle 等待所有消息处理完成(包括等待延时发送、等待重试的消息)，超时返回false。用于单元测试
+func (b *MemoryBus) WaitIdle(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&b.inflight) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+// FlushDelayed 立即发送所有等待延时发送、等待重试的消息，用于单元测试中跳过等待时间。返回发送的消息数量
+func (b *MemoryBus) FlushDelayed() int {
+	b.delayMu.Lock()
+	fns := make([]func(), 0, len(b.delayed))
+	for id, delayed := range b.delayed {
+		// 已经触发但还没有执行的定时器，在map中找不到时会跳过执行
+		delayed.timer.Stop()
+		fns = append(fns, delayed.fn)
+		delete(b.delayed, id)
+	}
+	b.delayMu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+	return len(fns)
+}
+
+// ListDeadLetterQueues 获取所有死信队列以及其中的消息数量
+func (b *MemoryBus) ListDeadLetterQueues() ([]*vo.DeadLetterQueueVO, error) {
+	b.mu.RLock()
+	queues := make([]*memoryQueue, 0, len(b.queues))
+	for _, queue := range b.queues {
+		queues = append(queues, queue)
+	}
+	b.mu.RUnlock()
+	sort.Slice(queues, func(i, j int) bool { return queues[i].name < queues[j].name })
+
+	result := make([]*vo.DeadLetterQueueVO, 0, len(queues))
+	for _, queue := range queues {
+		queue.mu.Lock()
+		count := len(queue.deadLetters)
+		queue.mu.Unlock()
+		result = append(result, &vo.DeadLetterQueueVO{
+			Queue:           queue.name,
+			DeadLetterQueue: deadLetterQueueName(queue.name),
+			Count:           count,
+		})
+	}
+	return result, nil
+}
+
+// PeekDeadLetters 查看死信队列中的前count条消息，消息仍然保留在死信队列中
+func (b *MemoryBus) PeekDeadLetters(queueName string, count int) ([]*vo.DeadLetterVO, error) {
+	queue, err := b.queue(queueName)
+	if err != nil {
+		return nil, err
+	}
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if count > len(queue.deadLetters) {
+		count = len(queue.deadLetters)
+	}
+	result := make([]*vo.DeadLetterVO, 0, count)
+	for _, msg := range queue.deadLetters[:count] {
+		result = append(result, &vo.DeadLetterVO{
+			Queue:       queueName,
+			Attempts:    msg.attempt,
+			ContentType: "application/json",
+			Body:        string(msg.body),
+			DeadTime:    msg.deadTime.Format(time.RFC3339),
+		})
+	}
+	return result, nil
+}
+
+// ReplayDeadLetters 将死信队列中的前count条消息重新发送到原队列，失败次数清零。返回重新发送的消息数量
+func (b *MemoryBus) ReplayDeadLetters(queueName string, count int) (int, error) {
+	queue, err := b.queue(queueName)
+	if err != nil {
+		return 0, err
+	}
+	queue.mu.Lock()
+	if count > len(queue.deadLetters) {
+		count = len(queue.deadLetters)
+	}
+	replay := queue.deadLetters[:count]
+	queue.deadLetters = append([]*memoryMessage(nil), queue.deadLetters[count:]...)
+	queue.mu.Unlock()
+	for _, msg := range replay {
+		b.enqueue(queue, &memoryMessage{body: msg.body, headers: msg.headers}, false)
+	}
+	zap.L().Info("重新发送死信队列中的消息", zap.String("queue", queueName), zap.Int("count", count))
+	return count, nil
+}
+
+// PurgeDeadLetters 清空死信队列，返回删除的消息数量
+func (b *MemoryBus) PurgeDeadLetters(queueName string) (int, error) {
+	queue, err := b.queue(queueName)
+	if err != nil {
+		return 0, err
+	}
+	queue.mu.Lock()
+	count := len(queue.deadLetters)
+	queue.deadLetters = nil
+	queue.mu.Unlock()
+	zap.L().Info("清空死信队列", zap.String("queue", queueName), zap.Int("count", count))
+	return count, nil
+}
+
+// queue 根据名称获取队列，不存在时返回ErrorQueueNotExist
+func (b *MemoryBus) queue(queueName string) (*memoryQueue, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	queue, ok := b.queues[queueName]
+	if !ok {
+		return nil, ErrorQueueNotExist
+	}
+	return queue, nil
+}
+
+// enqueue 将消息放入队列，checkLength为true时队列满则拒绝，返回是否成功
+func (b *MemoryBus) enqueue(queue *memoryQueue, msg *memoryMessage, checkLength bool) bool {
+	queue.mu.Lock()
+	if checkLength && queue.maxLength > 0 && len(queue.messages) >= queue.maxLength {
+		queue.mu.Unlock()
+		return false
+	}
+	atomic.AddInt64(&b.inflight, 1)
+	queue.messages = append(queue.messages, msg)
+	queue.mu.Unlock()
+	select {
+	case queue.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// consume 按照顺序消费队列中的消息，处理失败后延时重试，失败次数达到上限后进入死信队列
+func (b *MemoryBus) consume(queue *memoryQueue) {
+	defer b.workers.Done()
+	for {
+		select {
+		case <-b.stop:
+			return
+		default:
+		}
+		queue.mu.Lock()
+		if len(queue.messages) == 0 {
+			queue.mu.Unlock()
+			select {
+			case <-queue.notify:
+				continue
+			case <-b.stop:
+				return
+			}
+		}
+		msg := queue.messages[0]
+		queue.messages = queue.messages[1:]
+		queue.mu.Unlock()
+
+		ctx := tracing.Extract(context.Background(), msg.headers)
+		ok := receive(ctx, queue.name, queue.receiver, msg.body)
+		if !ok {
+			msg.attempt++
+			if msg.attempt < queue.policy.MaxAttempts {
+				metrics.MQRetried(queue.name)
+				delay := queue.policy.delay(msg.attempt)
+				logger.Ctx(ctx).Warn("receiver 数据处理失败，将要重试", zap.String("queue", queue.name), zap.Int("attempt", msg.attempt), zap.Duration("delay", delay))
+				b.schedule(delay, func() { b.enqueue(queue, msg, false) })
+			} else {
+				metrics.MQDeadLettered(queue.name)
+				logger.Ctx(ctx).Error("receiver 数据处理失败次数达到上限，进入死信队列", zap.String("queue", queue.name), zap.Int("attempt", msg.attempt))
+				msg.deadTime = time.Now()
+				queue.mu.Lock()
+				queue.deadLetters = append(queue.deadLetters, msg)
+				queue.mu.Unlock()
+			}
+		}
+		atomic.AddInt64(&b.inflight, -1)
+	}
+}
+
+// schedule 在delay后执行fn，执行前计入未处理完成的消息
+func (b *MemoryBus) schedule(delay time.Duration, fn func()) {
+	atomic.AddInt64(&b.inflight, 1)
+	b.delayMu.Lock()
+	defer b.delayMu.Unlock()
+	b.delayID++
+	id := b.delayID
+	delayed := &memoryDelayed{fn: func() {
+		fn()
+		atomic.AddInt64(&b.inflight, -1)
+	}}
+	delayed.timer = time.AfterFunc(delay, func() {
+		b.delayMu.Lock()
+		_, ok := b.delayed[id]
+		delete(b.delayed, id)
+		b.delayMu.Unlock()
+		if ok {
+			delayed.fn()
+		}
+	})
+	b.delayed[id] = delayed
+}
+
+// routeMatch 路由是否与绑定匹配。direct要求完全相同，fanout总是匹配，
+// topic中*匹配一个单词，#匹配零个或多个单词
+func routeMatch(kind, pattern, routingKey string) bool {
+	switch kind {
+	case "fanout":
+		return true
+	case "topic":
+		return topicMatch(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+	default:
+		return pattern == routingKey
+	}
+}
+
+// topicMatch 按照单词匹配topic路由
+func topicMatch(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if topicMatch(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && topicMatch(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && topicMatch(pattern[1:], words[1:])
+	}
+}
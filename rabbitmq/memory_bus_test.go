@@ -0,0 +1,443 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"shop-backend/errs"
+	"shop-backend/models/dto"
+	"shop-backend/models/pojo"
+	"shop-backend/utils/gen"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 测试中等待消息处理完成的最长时间
+const testIdleTimeout = 2 * time.Second
+
+// testReceiver 记录收到的消息，handle为nil时总是处理成功
+type testReceiver struct {
+	queueName  string
+	routingKey string
+	policy     *RetryPolicy
+	handle     func(body []byte) bool
+
+	mu       sync.Mutex
+	received []string
+}
+
+func newTestReceiver(queueName, routingKey string) *testReceiver {
+	return &testReceiver{queueName: queueName, routingKey: routingKey}
+}
+
+func (r *testReceiver) QueueName() string { return r.queueName }
+
+func (r *testReceiver) RoutingKey() string { return r.routingKey }
+
+func (r *testReceiver) OnError(error) {}
+
+func (r *testReceiver) RetryPolicy() *RetryPolicy { return r.policy }
+
+func (r *testReceiver) OnReceive(body []byte) bool {
+	r.mu.Lock()
+	r.received = append(r.received, string(body))
+	r.mu.Unlock()
+	if r.handle == nil {
+		return true
+	}
+	return r.handle(body)
+}
+
+// messages 返回收到的所有消息，包括重试
+func (r *testReceiver) messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.received...)
+}
+
+// newTestBus 创建进程内的消息总线并声明交换机，测试结束时关闭
+func newTestBus(t *testing.T, exchanges map[string]string) *MemoryBus {
+	t.Helper()
+	b := NewMemoryBus()
+	t.Cleanup(func() { _ = b.Close(context.Background()) })
+	for exchange, kind := range exchanges {
+		if err := b.DeclareExchange(exchange, kind); err != nil {
+			t.Fatalf("声明交换机 %s 失败: %v", exchange, err)
+		}
+	}
+	return b
+}
+
+// useBus 将包级别的消息总线替换为b，测试结束时恢复
+func useBus(t *testing.T, b Bus) {
+	t.Helper()
+	previous := bus
+	bus = instrumentedBus{b}
+	t.Cleanup(func() { bus = previous })
+}
+
+func subscribe(t *testing.T, b Bus, exchange string, receiver Receiver) {
+	t.Helper()
+	if err := b.Subscribe(exchange, receiver); err != nil {
+		t.Fatalf("订阅交换机 %s 失败: %v", exchange, err)
+	}
+}
+
+func publish(t *testing.T, b Bus, exchange, routingKey, body string) {
+	t.Helper()
+	if err := b.Publish(context.Background(), exchange, routingKey, []byte(body)); err != nil {
+		t.Fatalf("发送消息到交换机 %s 失败: %v", exchange, err)
+	}
+}
+
+func waitIdle(t *testing.T, b *MemoryBus) {
+	t.Helper()
+	if !b.WaitIdle(testIdleTimeout) {
+		t.Fatal("等待消息处理完成超时")
+	}
+}
+
+// waitMessages 等待接收者收到n条消息
+func waitMessages(t *testing.T, receiver *testReceiver, n int) {
+	t.Helper()
+	deadline := time.Now().Add(testIdleTimeout)
+	for len(receiver.messages()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("队列 %s 等待 %d 条消息超时，收到 %q", receiver.queueName, n, receiver.messages())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// flushUntilIdle 反复跳过重试的等待时间，直到所有消息处理完成或者进入死信队列
+func flushUntilIdle(t *testing.T, b *MemoryBus) {
+	t.Helper()
+	deadline := time.Now().Add(testIdleTimeout)
+	for !b.WaitIdle(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("等待消息处理完成超时")
+		}
+		b.FlushDelayed()
+	}
+}
+
+func assertMessages(t *testing.T, receiver *testReceiver, want ...string) {
+	t.Helper()
+	got := receiver.messages()
+	if len(got) != len(want) {
+		t.Fatalf("队列 %s 收到消息 %q，期望 %q", receiver.queueName, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("队列 %s 收到消息 %q，期望 %q", receiver.queueName, got, want)
+		}
+	}
+}
+
+func TestMemoryBusRouting(t *testing.T) {
+	b := newTestBus(t, map[string]string{
+		"direct": "direct",
+		"topic":  "topic",
+		"fanout": "fanout",
+	})
+	directA := newTestReceiver("direct.a", "a")
+	directB := newTestReceiver("direct.b", "b")
+	topicOne := newTestReceiver("topic.one", "order.*")
+	topicAll := newTestReceiver("topic.all", "order.#")
+	topicPaid := newTestReceiver("topic.paid", "*.paid")
+	fanoutA := newTestReceiver("fanout.a", "ignored")
+	fanoutB := newTestReceiver("fanout.b", "")
+	subscribe(t, b, "direct", directA)
+	subscribe(t, b, "direct", directB)
+	subscribe(t, b, "topic", topicOne)
+	subscribe(t, b, "topic", topicAll)
+	subscribe(t, b, "topic", topicPaid)
+	subscribe(t, b, "fanout", fanoutA)
+	subscribe(t, b, "fanout", fanoutB)
+
+	publish(t, b, "direct", "a", "1")
+	publish(t, b, "direct", "c", "2")
+	publish(t, b, "topic", "order", "3")
+	publish(t, b, "topic", "order.paid", "4")
+	publish(t, b, "topic", "order.item.paid", "5")
+	publish(t, b, "fanout", "any", "6")
+	waitIdle(t, b)
+
+	assertMessages(t, directA, "1")
+	assertMessages(t, directB)
+	assertMessages(t, topicOne, "4")
+	assertMessages(t, topicAll, "3", "4", "5")
+	assertMessages(t, topicPaid, "4")
+	assertMessages(t, fanoutA, "6")
+	assertMessages(t, fanoutB, "6")
+
+	err := b.Publish(context.Background(), "missing", "a", []byte("7"))
+	if !errors.Is(err, ErrorExchangeNotExist) {
+		t.Fatalf("发送到不存在的交换机返回 %v，期望 %v", err, ErrorExchangeNotExist)
+	}
+}
+
+func TestMemoryBusPublishDelayed(t *testing.T) {
+	b := newTestBus(t, map[string]string{"delay": "direct"})
+	receiver := newTestReceiver("delay.queue", "key")
+	subscribe(t, b, "delay", receiver)
+
+	// 短延时的消息不会被前面长延时的消息阻塞
+	ctx := context.Background()
+	if err := b.PublishDelayed(ctx, "delay", "key", []byte("long"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PublishDelayed(ctx, "delay", "key", []byte("short"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := receiver.messages(); len(got) != 0 {
+		t.Fatalf("延时消息提前到达: %q", got)
+	}
+	waitMessages(t, receiver, 1)
+	assertMessages(t, receiver, "short")
+	if b.WaitIdle(50 * time.Millisecond) {
+		t.Fatal("还有等待延时发送的消息时WaitIdle返回了true")
+	}
+
+	if n := b.FlushDelayed(); n != 1 {
+		t.Fatalf("FlushDelayed发送了 %d 条消息，期望 1 条", n)
+	}
+	waitIdle(t, b)
+	assertMessages(t, receiver, "short", "long")
+	if n := b.FlushDelayed(); n != 0 {
+		t.Fatalf("没有延时消息时FlushDelayed发送了 %d 条消息", n)
+	}
+}
+
+func TestMemoryBusRetryDeadLetter(t *testing.T) {
+	b := newTestBus(t, map[string]string{"retry": "direct"})
+	policy := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+
+	// 第二次处理成功的消息不会进入死信队列
+	var attempts int
+	flaky := newTestReceiver("retry.flaky", "flaky")
+	flaky.policy = policy
+	flaky.handle = func([]byte) bool {
+		attempts++
+		return attempts >= 2
+	}
+	failing := newTestReceiver("retry.failing", "failing")
+	failing.policy = policy
+	failing.handle = func([]byte) bool { return false }
+	subscribe(t, b, "retry", flaky)
+	subscribe(t, b, "retry", failing)
+
+	publish(t, b, "retry", "flaky", "f")
+	publish(t, b, "retry", "failing", "x")
+	// 重试的等待时间为1小时，通过FlushDelayed跳过
+	flushUntilIdle(t, b)
+
+	assertMessages(t, flaky, "f", "f")
+	assertMessages(t, failing, "x", "x", "x")
+	letters, err := b.PeekDeadLetters("retry.failing", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].Body != "x" || letters[0].Attempts != policy.MaxAttempts {
+		t.Fatalf("死信队列中的消息不正确: %+v", letters)
+	}
+	if letters, _ = b.PeekDeadLetters("retry.flaky", 10); len(letters) != 0 {
+		t.Fatalf("重试成功的消息进入了死信队列: %+v", letters)
+	}
+
+	// 重新发送死信，处理成功后死信队列为空
+	failing.handle = func([]byte) bool { return true }
+	if n, err := b.ReplayDeadLetters("retry.failing", 10); err != nil || n != 1 {
+		t.Fatalf("重新发送死信返回 %d, %v", n, err)
+	}
+	waitIdle(t, b)
+	assertMessages(t, failing, "x", "x", "x", "x")
+	if letters, _ = b.PeekDeadLetters("retry.failing", 10); len(letters) != 0 {
+		t.Fatalf("重新发送后死信队列不为空: %+v", letters)
+	}
+	if _, err = b.PeekDeadLetters("missing", 10); !errors.Is(err, ErrorQueueNotExist) {
+		t.Fatalf("查看不存在的队列返回 %v，期望 %v", err, ErrorQueueNotExist)
+	}
+}
+
+func TestMemoryBusMaxLength(t *testing.T) {
+	b := newTestBus(t, map[string]string{SecKillReqExchangeName: SecKillReqExchangeType})
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	// 阻塞消费者，使消息留在队列中
+	receiver := newTestReceiver(SecKillReqQueueName, SecKillReqRoutingKey)
+	receiver.handle = func([]byte) bool {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return true
+	}
+	subscribe(t, b, SecKillReqExchangeName, receiver)
+	// 先于Close执行，否则Close会一直等待阻塞的消费者
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	publish(t, b, SecKillReqExchangeName, SecKillReqRoutingKey, "0")
+	<-started
+	for i := 1; i <= SecKillStore; i++ {
+		publish(t, b, SecKillReqExchangeName, SecKillReqRoutingKey, strconv.Itoa(i))
+	}
+	err := b.Publish(context.Background(), SecKillReqExchangeName, SecKillReqRoutingKey, []byte("full"))
+	if !errors.Is(err, ErrorPublishRejected) {
+		t.Fatalf("队列已满时发送消息返回 %v，期望 %v", err, ErrorPublishRejected)
+	}
+	if b.WaitIdle(50 * time.Millisecond) {
+		t.Fatal("还有未处理的消息时WaitIdle返回了true")
+	}
+
+	close(release)
+	waitIdle(t, b)
+	if got := len(receiver.messages()); got != SecKillStore+1 {
+		t.Fatalf("处理了 %d 条消息，期望 %d 条", got, SecKillStore+1)
+	}
+}
+
+// TestSubmitOrderFlow 提交订单时写入发件箱的删除购物车消息、订单超时消息，经过中继发送后到达对应的队列
+func TestSubmitOrderFlow(t *testing.T) {
+	if err := gen.Init("2022-01-01", 1); err != nil {
+		t.Fatal(err)
+	}
+	b := newTestBus(t, map[string]string{
+		CartDelExchangeName:    CartDelExchangeType,
+		DelayOrderExchangeName: DelayOrderExchangeType,
+	})
+	useBus(t, b)
+	cartDel := newTestReceiver(CartDeleteQueueName, CartDeleteRoutingKey)
+	delayOrder := newTestReceiver(DelayOrderQueueName, DelayOrderRoutingKey)
+	subscribe(t, bus, CartDelExchangeName, cartDel)
+	subscribe(t, bus, DelayOrderExchangeName, delayOrder)
+
+	ctx := context.Background()
+	cart := &dto.CartProductListDTO{
+		UserID:          1,
+		CartProductList: []*dto.CartProduct{{SkuID: "10", Specification: "{}"}},
+	}
+	cartMessage := NewCartDelOutboxMessage(ctx, cart)
+	orderMessage := NewDelayOrderOutboxMessage(ctx, 20)
+	orderMessage.CreatedTime = time.Now()
+	for _, message := range []*pojo.OutboxMessage{cartMessage, orderMessage} {
+		if err := publishOutbox(message); err != nil {
+			t.Fatalf("发送发件箱消息失败: %v", err)
+		}
+	}
+
+	// 删除购物车的消息立即到达，订单超时消息在延时结束前不会到达
+	waitMessages(t, cartDel, 1)
+	if b.WaitIdle(50 * time.Millisecond) {
+		t.Fatal("订单超时消息没有延时发送")
+	}
+	got := cartDel.messages()
+	if len(got) != 1 {
+		t.Fatalf("删除购物车队列收到 %d 条消息，期望 1 条", len(got))
+	}
+	received := new(dto.CartProductListDTO)
+	if err := json.Unmarshal([]byte(got[0]), received); err != nil {
+		t.Fatal(err)
+	}
+	if received.UserID != cart.UserID || len(received.CartProductList) != 1 || received.CartProductList[0].SkuID != "10" {
+		t.Fatalf("删除购物车消息不正确: %s", got[0])
+	}
+	assertMessages(t, delayOrder)
+
+	if n := b.FlushDelayed(); n != 1 {
+		t.Fatalf("FlushDelayed发送了 %d 条消息，期望 1 条", n)
+	}
+	waitIdle(t, b)
+	assertMessages(t, delayOrder, "20")
+}
+
+// TestSecKillFlow 秒杀请求发送到秒杀队列，队列满后返回秒杀已结束，消息总线不可用时返回服务不可用
+func TestSecKillFlow(t *testing.T) {
+	useBus(t, disconnectedBus{})
+	err := SendSecKillReqMess2MQ(context.Background(), &dto.SecKillMQ{SkuID: 1, UID: 1})
+	if errs.KindOf(err) != errs.KindUnavailable {
+		t.Fatalf("消息总线不可用时返回 %v，期望服务不可用", err)
+	}
+
+	b := newTestBus(t, map[string]string{SecKillReqExchangeName: SecKillReqExchangeType})
+	useBus(t, b)
+	release := make(chan struct{})
+	receiver := newTestReceiver(SecKillReqQueueName, SecKillReqRoutingKey)
+	receiver.handle = func([]byte) bool {
+		<-release
+		return true
+	}
+	subscribe(t, bus, SecKillReqExchangeName, receiver)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	ctx := context.Background()
+	var accepted int
+	// 一个请求正在处理，队列中最多保存SecKillStore个请求
+	for i := 0; i < SecKillStore+2; i++ {
+		if err = SendSecKillReqMess2MQ(ctx, &dto.SecKillMQ{SkuID: 1, UID: int64(i)}); err != nil {
+			break
+		}
+		accepted++
+	}
+	if !errors.Is(err, ErrorSecKillFinished) {
+		t.Fatalf("秒杀队列已满时返回 %v，期望 %v", err, ErrorSecKillFinished)
+	}
+	if accepted < SecKillStore || accepted > SecKillStore+1 {
+		t.Fatalf("秒杀队列接收了 %d 个请求，期望 %d 或 %d 个", accepted, SecKillStore, SecKillStore+1)
+	}
+
+	close(release)
+	waitIdle(t, b)
+	got := receiver.messages()
+	if len(got) != accepted {
+		t.Fatalf("处理了 %d 个秒杀请求，期望 %d 个", len(got), accepted)
+	}
+	data := new(dto.SecKillMQ)
+	if err = json.Unmarshal([]byte(got[0]), data); err != nil || data.SkuID != 1 {
+		t.Fatalf("秒杀请求不正确: %s", got[0])
+	}
+}
+
+func TestMemoryBusCloseDeadline(t *testing.T) {
+	b := NewMemoryBus()
+	if err := b.DeclareExchange("close", "direct"); err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	receiver := newTestReceiver("close.queue", "key")
+	receiver.handle = func([]byte) bool {
+		close(started)
+		<-release
+		return true
+	}
+	subscribe(t, b, "close", receiver)
+	publish(t, b, "close", "key", "1")
+	<-started
+
+	// 正在处理的消息没有完成时，ctx结束后不再等待
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close返回 %v，期望 %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatalf("消息处理完成后Close返回 %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"shop-backend/dao/mysql"
+	"shop-backend/logger"
+	"shop-backend/models/pojo"
+	"shop-backend/tracing"
+	"shop-backend/utils/gen"
+	"strconv"
+	"time"
+)
+
+const (
+	// 中继每次读取的待发送消息数量
+	outboxBatchSize = 100
+	// 没有新消息通知时，中继轮询发件箱的间隔。用于发送重启前未发送以及发送失败等待重试的消息
+	outboxPollInterval = 5 * time.Second
+	// 领取消息后的租期，租期内其他服务实例不会发送该消息
+	outboxClaimLease = 30 * time.Second
+	// 已发送消息的保留时间，超过后清理
+	outboxRetention = 7 * 24 * time.Hour
+	// 清理已发送消息的间隔
+	outboxCleanInterval = time.Hour
+)
+
+// 发件箱消息发送失败后的重试间隔，消息不会被丢弃，一直重试直到发送成功
+var outboxRetryPolicy = &RetryPolicy{
+	BaseDelay: time.Second,
+	MaxDelay:  time.Minute,
+}
+
+// 新消息写入发件箱的通知，缓冲区为1，多次通知合并为一次
+var outboxNotify = make(chan struct{}, 1)
+
+// NewDelayOrderOutboxMessage 创建订单超时回滚的延时发件箱消息，超时未支付后回滚库存和修改订单状态为超时未支付
+func NewDelayOrderOutboxMessage(ctx context.Context, orderNum int64) *pojo.OutboxMessage {
+	return newOutboxMessage(ctx, DelayOrderExchangeName, DelayOrderRoutingKey, orderNum, DelayOrderTTL)
+}
+
+// NewCartDelOutboxMessage 创建异步删除购物车的发件箱消息
+func NewCartDelOutboxMessage(ctx context.Context, data interface{}) *pojo.OutboxMessage {
+	return newOutboxMessage(ctx, CartDelExchangeName, CartDeleteRoutingKey, data, "")
+}
+
+// newOutboxMessage 创建一条待发送的发件箱消息，记录ctx中的链路信息，中继发送时继续这条链路
+func newOutboxMessage(ctx context.Context, exchange, routingKey string, data interface{}, expiration string) *pojo.OutboxMessage {
+	// 转换为json数据
+	dataJson, _ := json.Marshal(data)
+	var headers []byte
+	if carrier := tracing.Inject(ctx); carrier != nil {
+		headers, _ = json.Marshal(carrier)
+	}
+	return &pojo.OutboxMessage{
+		ID:            gen.GenSnowflakeID(),
+		Exchange:      exchange,
+		RoutingKey:    routingKey,
+		Body:          string(dataJson),
+		Headers:       string(headers),
+		Expiration:    expiration,
+		Status:        pojo.OutboxStatusPending,
+		NextRetryTime: time.Now(),
+	}
+}
+
+// NotifyOutbox 通知中继有新消息写入了发件箱，在写入消息的事务提交后调用
+func NotifyOutbox() {
+	select {
+	case outboxNotify <- struct{}{}:
+	default:
+	}
+}
+
+// runOutboxRelay 发件箱中继，将待发送的消息发送到RabbitMQ，收到发布确认后标记为已发送。
+// 服务启动时会发送重启前未发送的消息，发送失败的消息按照退避时间重试。
+// ctx取消后再发送一次剩余的消息然后返回，消费者在退出前写入发件箱的消息不需要等到下次启动
+func runOutboxRelay(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	lastClean := time.Time{}
+	for {
+		relayOutbox()
+		if time.Since(lastClean) >= outboxCleanInterval {
+			lastClean = time.Now()
+			if count, err := mysql.DeleteSentOutboxBefore(ctx, time.Now().Add(-outboxRetention)); err == nil && count > 0 {
+				zap.L().Info("清理已发送的发件箱消息", zap.Int64("count", count))
+			}
+		}
+		select {
+		case <-outboxNotify:
+		case <-ticker.C:
+		case <-ctx.Done():
+			relayOutbox()
+			return
+		}
+	}
+}
+
+// relayOutbox 发送所有到达发送时间的待发送消息。中继在ctx取消后还会再发送一次，这里不使用中继的ctx
+func relayOutbox() {
+	ctx := context.Background()
+	for {
+		messages, err := mysql.SelectPendingOutbox(ctx, outboxBatchSize)
+		if err != nil || len(messages) == 0 {
+			return
+		}
+		for _, message := range messages {
+			ok, err := mysql.ClaimOutbox(ctx, message, outboxClaimLease)
+			if err != nil {
+				return
+			}
+			if !ok {
+				// 已经被其他服务实例领取
+				continue
+			}
+			if err = publishOutbox(message); err != nil {
+				logger.Ctx(outboxContext(message)).Error("发送发件箱消息到RabbitMQ失败",
+					zap.Int64("id", message.ID),
+					zap.String("exchange", message.Exchange),
+					zap.Int("attempts", message.Attempts+1),
+					zap.Error(err))
+				_ = mysql.UpdateOutboxRetry(ctx, message.ID, outboxRetryPolicy.delay(message.Attempts+1))
+				if err == ErrorNotConnected {
+					// 未连接时后面的消息也无法发送，等待下一次轮询
+					return
+				}
+				continue
+			}
+			// 标记失败时消息会在租期结束后再次发送，消费者需要保证幂等
+			_ = mysql.UpdateOutboxSent(ctx, message.ID)
+		}
+		if len(messages) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// publishOutbox 发送一条发件箱消息，消息被确认接收后返回。设置了过期时间的消息延时发送
+func publishOutbox(message *pojo.OutboxMessage) error {
+	ctx := outboxContext(message)
+	if message.Expiration == "" {
+		return bus.Publish(ctx, message.Exchange, message.RoutingKey, []byte(message.Body))
+	}
+	return bus.PublishDelayed(ctx, message.Exchange, message.RoutingKey, []byte(message.Body), outboxDelay(message))
+}
+
+// outboxContext 恢复写入消息时的链路以及请求ID
+func outboxContext(message *pojo.OutboxMessage) context.Context {
+	ctx := context.Background()
+	if message.Headers != "" {
+		carrier := make(map[string]string)
+		_ = json.Unmarshal([]byte(message.Headers), &carrier)
+		ctx = tracing.Extract(ctx, carrier)
+	}
+	return ctx
+}
+
+// outboxDelay 延时从写入发件箱时开始计算，扣除消息在发件箱中等待的时间
+func outboxDelay(message *pojo.OutboxMessage) time.Duration {
+	ttl, err := strconv.ParseInt(message.Expiration, 10, 64)
+	if err != nil {
+		zap.L().Error("发件箱消息的过期时间不合法", zap.Int64("id", message.ID), zap.String("expiration", message.Expiration))
+		return 0
+	}
+	delay := time.Duration(ttl)*time.Millisecond - time.Since(message.CreatedTime)
+	if delay < 0 {
+		// 已经超过过期时间，立即处理
+		return 0
+	}
+	return delay
+}
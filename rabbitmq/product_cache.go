@@ -0,0 +1,52 @@
+package rabbitmq
+
+import (
+	"context"
+	"shop-backend/cdc"
+	"shop-backend/dao/mysql"
+	"shop-backend/dao/redis"
+	"time"
+)
+
+// 商品分类变更的表名
+const categoryTable = "pms_product_category"
+
+// 第一次删除缓存后，再次删除缓存的延时。
+// 删除缓存时可能有请求正在使用旧数据重建缓存，延时后再删除一次，避免旧数据一直留在缓存中(延时双删)
+const productCacheDelayDelete = time.Second
+
+// handleProductCacheEvent 根据pms_spu、pms_sku、pms_sku_pic的变更事件删除商品详情缓存。
+// 同时处理变更前后的ID，sku更换所属spu时新旧spu的详情缓存都会被删除
+func handleProductCacheEvent(event *cdc.Event) error {
+	var spuIDs, skuIDs []int64
+	switch event.Table {
+	case productTableSpu:
+		spuIDs = event.Int64s("id")
+	case productTableSku:
+		spuIDs, skuIDs = event.Int64s("spu_id"), event.Int64s("id")
+	case productTableSkuPic:
+		skuIDs = event.Int64s("sku_id")
+		// 商品图片表中没有spuID，需要通过sku查询所属的spu
+		ids, err := mysql.SelectSpuIDBySkuIDs(context.Background(), skuIDs)
+		if err != nil {
+			return err
+		}
+		spuIDs = ids
+	}
+	if err := redis.DelProductDetail(context.Background(), spuIDs, skuIDs); err != nil {
+		return err
+	}
+	time.AfterFunc(productCacheDelayDelete, func() {
+		_ = redis.DelProductDetail(context.Background(), spuIDs, skuIDs)
+	})
+	return nil
+}
+
+// handleCategoryCacheEvent 商品分类发生变更(包括直接修改数据库)时，递增分类版本号并删除分类缓存
+func handleCategoryCacheEvent(event *cdc.Event) error {
+	// 递增版本号，防止变更前开始构建的旧分类树写入缓存
+	if _, err := redis.IncrCategoryVersion(context.Background()); err != nil {
+		return err
+	}
+	return redis.DelCategoryList(context.Background())
+}
@@ -0,0 +1,32 @@
+package rabbitmq
+
+import (
+	"shop-backend/cdc"
+	"shop-backend/search"
+)
+
+// 商品变更的表名
+const (
+	productTableSku    = "pms_sku"
+	productTableSpu    = "pms_spu"
+	productTableSkuPic = "pms_sku_pic"
+)
+
+// handleProductIndexEvent 根据pms_sku、pms_spu的变更事件更新全文索引
+func handleProductIndexEvent(event *cdc.Event) error {
+	id := event.Int64("id")
+	if id == 0 {
+		return nil
+	}
+	switch {
+	case event.Table == productTableSku && event.Type == cdc.EventDelete:
+		search.RemoveSku(id)
+	case event.Table == productTableSku:
+		return search.IndexSku(id)
+	case event.Table == productTableSpu && event.Type == cdc.EventDelete:
+		search.RemoveSpu(id)
+	case event.Table == productTableSpu:
+		return search.IndexSpu(id)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package rabbitmq
 
 import (
+	"context"
 	"fmt"
 	"github.com/streadway/amqp"
 	"go.uber.org/zap"
@@ -15,71 +16,47 @@ import (
 type Receiver interface {
 	QueueName() string     // 获取接收者需要监听的队列
 	RoutingKey() string    // 这个队列绑定的路由
-	OnError(error)         // 处理遇到的错误，当RabbitMQ对象发生了错误，他需要告诉接收者处理错误
+	OnError(error)         // 消费者初始化通道失败时通知接收者，该消费者关闭通道后重试，接收者只需要记录错误
 	OnReceive([]byte) bool // 处理收到的消息, 这里需要告知RabbitMQ对象消息是否处理成功
 }
 
+// ContextReceiver 需要链路信息的接收者，实现后使用OnReceiveContext代替OnReceive处理消息。
+// ctx中包含发送者传递的链路，处理消息时的数据库操作会记录在同一条链路中
+type ContextReceiver interface {
+	OnReceiveContext(ctx context.Context, body []byte) bool
+}
+
+// MultiRoutingReceiver 需要将队列绑定到多个路由的接收者，实现后会忽略RoutingKey()的返回值
+type MultiRoutingReceiver interface {
+	RoutingKeys() []string
+}
+
 // RabbitMQ 用于管理和维护RabbitMQ的对象(被观察者)
 type RabbitMQ struct {
 	wg           sync.WaitGroup
-	channel      *amqp.Channel
+	manager      *connManager
 	exchangeName string // exchange的名称
 	exchangeType string // exchange的类型
 	receivers    []Receiver
+	// 关闭后消费者停止接收新的消息
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
-// NewSmsMQ 创建一个用于发送短信业务的新的操作RabbitMQ的对象
-func NewSmsMQ() *RabbitMQ {
+// newRabbitMQ 创建一个消费exchange中消息的RabbitMQ对象
+func newRabbitMQ(manager *connManager, exchangeName, exchangeType string) *RabbitMQ {
 	return &RabbitMQ{
-		exchangeName: SmsExchangeName,
-		exchangeType: SmsExchangeType,
-	}
-}
-
-// NewCanalCartMQ 创建一个用于Canal购物车业务的新的操作RabbitMQ的对象
-func NewCanalCartMQ() *RabbitMQ {
-	return &RabbitMQ{
-		exchangeName: CanalCartExchangeName,
-		exchangeType: CanalCartExchangeType,
-	}
-}
-
-// NewCartDelMQ 创建一个用于异步删除购物车的新的操作RabbitMQ的对象
-func NewCartDelMQ() *RabbitMQ {
-	return &RabbitMQ{
-		exchangeName: CartDelExchangeName,
-		exchangeType: CartDelExchangeType,
-	}
-}
-
-// NewOrderMQ 创建一个用于订单超时回滚的新的操作RabbitMQ的对象
-func NewOrderMQ() *RabbitMQ {
-	return &RabbitMQ{
-		exchangeName: OrderExchangeName,
-		exchangeType: OrderExchangeType,
-	}
-}
-
-// NewDelayOrderMQ 创建一个用于订单超时回滚的新的操作RabbitMQ的对象
-func NewDelayOrderMQ() *RabbitMQ {
-	return &RabbitMQ{
-		exchangeName: DelayOrderExchangeName,
-		exchangeType: DelayOrderExchangeType,
-	}
-}
-
-// NewSecKillMQ 创建一个用于保存用户秒杀请求的新的操作RabbitMQ的对象
-func NewSecKillMQ() *RabbitMQ {
-	return &RabbitMQ{
-		exchangeName: SecKillReqExchangeName,
-		exchangeType: SecKillReqExchangeType,
+		manager:      manager,
+		exchangeName: exchangeName,
+		exchangeType: exchangeType,
+		stop:         make(chan struct{}),
 	}
 }
 
 // 准备RabbitMQ的交换机
-func (mq *RabbitMQ) prepareExchange() error {
+func (mq *RabbitMQ) prepareExchange(channel *amqp.Channel) error {
 	// 声明交换机
-	err := mq.channel.ExchangeDeclare(
+	err := channel.ExchangeDeclare(
 		mq.exchangeName, // exchange
 		mq.exchangeType, // type
 		true,            // durable
@@ -95,41 +72,64 @@ func (mq *RabbitMQ) prepareExchange() error {
 	return nil
 }
 
-// run 开始获取连接并初始化相关操作
-func (mq *RabbitMQ) run() {
-	mq.prepareExchange()
-	if mq.exchangeName == OrderExchangeName {
-		// 超时订单服务，不需要接收者
-		forever := make(chan bool)
-		<-forever
-	}
+// Start 启动RabbitMQ的客户端，为每个接收者启动配置数量的消费者，每个消费者使用独立的通道
+func (mq *RabbitMQ) Start() {
 	for _, receiver := range mq.receivers {
-		// 一个RabbitMQ对象可以对应多个消费者，每个消费者的加入都会使得WaitGroup+1
-		mq.wg.Add(1)
-		// 每个接收者单独启动一个goroutine用来初始化queue并接收消息
-		// listen方法完成时会执行mq.wg.Done()
-		zap.L().Info("开启一个新的协程处理RabbitMQ队列中的消息")
-		go mq.listen(receiver)
+		options := getConsumerOptions(receiver)
+		for i := 0; i < options.Workers; i++ {
+			// 一个RabbitMQ对象可以对应多个消费者，每个消费者的加入都会使得WaitGroup+1
+			// work方法完成时会执行mq.wg.Done()
+			mq.wg.Add(1)
+			go mq.work(receiver, options)
+		}
+		zap.L().Info("开启协程处理RabbitMQ队列中的消息",
+			zap.String("queue", receiver.QueueName()),
+			zap.Int("workers", options.Workers),
+			zap.Int("prefetch", options.Prefetch))
 	}
+}
 
-	// 一直在此等待，除非所有的消费者都意外退出
-	mq.wg.Wait()
+// Stop 停止接收新的消息，正在处理的消息处理完成后消费者退出。不等待消费者退出
+func (mq *RabbitMQ) Stop() {
+	mq.stopOnce.Do(func() {
+		close(mq.stop)
+	})
+}
 
-	zap.L().Error("所有处理queue的任务都意外退出了")
+// Wait 等待所有消费者退出
+func (mq *RabbitMQ) Wait() {
+	mq.wg.Wait()
+}
 
-	// 理论上mq.run()在程序的执行过程中是不会结束的
-	// 一旦结束就说明所有的接收者都退出了，那么意味着程序与RabbitMQ的连接断开
-	// 那么则需要重新连接，这里尝试销毁当前连接
-	Destroy()
+// stopped 是否已经调用了Stop
+func (mq *RabbitMQ) stopped() bool {
+	select {
+	case <-mq.stop:
+		return true
+	default:
+		return false
+	}
 }
 
-// Start 启动RabbitMQ的客户端
-func (mq *RabbitMQ) Start() {
+// work 一个消费者：每次连接成功后打开新的通道，初始化交换机、队列并开始消费，直到调用Stop
+func (mq *RabbitMQ) work(receiver Receiver, options ConsumerOptions) {
+	defer mq.wg.Done()
 	for {
-		mq.run()
-		// 一旦连接断开，那么需要隔一段时间去重连
-		// 这里最好有一个时间间隔
-		time.Sleep(3 * time.Second)
+		channel, err := mq.manager.waitChannel(mq.stop)
+		if err != nil {
+			if mq.stopped() {
+				return
+			}
+			zap.L().Error("打开Channel失败", zap.String("exchange", mq.exchangeName), zap.Error(err))
+		} else {
+			mq.listen(channel, receiver, options)
+		}
+		// 通道关闭后隔一段时间再重新打开，避免连接异常时频繁重试
+		select {
+		case <-mq.stop:
+			return
+		case <-time.After(3 * time.Second):
+		}
 	}
 }
 
@@ -140,65 +140,82 @@ func (mq *RabbitMQ) RegisterReceiver(receiver Receiver) {
 }
 
 // Listen 监听指定路由发来的消息
-// 这里需要针对每一个接收者启动一个goroutine来执行listen
-// 该方法负责从每一个接收者监听的队列中获取数据，并负责重试
-func (mq *RabbitMQ) listen(receiver Receiver) {
-	defer mq.wg.Done()
+// 每一个消费者在独立的通道上执行listen，直到通道关闭或者调用Stop才返回
+// 该方法负责从接收者监听的队列中获取数据，并负责重试
+func (mq *RabbitMQ) listen(channel *amqp.Channel, receiver Receiver, options ConsumerOptions) {
+	// 关闭通道时，已经预取但未应答的消息会回到队列
+	defer channel.Close()
+	if err := mq.prepareExchange(channel); err != nil {
+		return
+	}
 
 	// 这里获取每个接收者需要监听的队列和路由
 	queueName := receiver.QueueName()
 	routerKey := receiver.RoutingKey()
 
-	var args = make(amqp.Table, 0)
-	if queueName == OrderQueueName {
-		// 如果为订单相关操作的队列。指定死信队列相关信息
-		args["x-dead-letter-exchange"] = DelayOrderExchangeName
-		args["x-dead-letter-routing-key"] = DelayOrderRoutingKey
-	}
-
-	if queueName == SecKillReqQueueName {
-		// 如果为保存秒杀请求的队列。
-		// 最大消息为秒杀商品库存的两倍
-		args["x-max-length"] = SecKillStore
-		// 当队列满时，拒绝新的请求
-		args["x-overflow"] = SecKillOverflow
-	}
-
+	// 初始化失败时只关闭当前消费者的通道，隔一段时间后重试，不会应答或者丢弃消息。
+	// 异常只通知接收者记录，不保存在接收者中，多个消费者共享同一个接收者
 	// 声明Queue
-	_, err := mq.channel.QueueDeclare(
-		queueName, // name
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive(排他性队列)
-		false,     // no-wait
-		args,      // arguments
+	_, err := channel.QueueDeclare(
+		queueName,            // name
+		true,                 // durable
+		false,                // delete when unused
+		false,                // exclusive(排他性队列)
+		false,                // no-wait
+		queueArgs(queueName), // arguments
 	)
 	if nil != err {
-		// 当队列初始化失败的时候，需要告诉这个接收者相应的错误
 		receiver.OnError(fmt.Errorf("初始化队列 %s 失败: %s", queueName, err.Error()))
+		return
 	}
 
 	// 将Queue绑定到Exchange上去
-	err = mq.channel.QueueBind(
-		queueName,       // queue name
-		routerKey,       // routing key
-		mq.exchangeName, // exchange
-		false,           // no-wait
-		nil,
-	)
-	if nil != err {
-		receiver.OnError(fmt.Errorf("绑定队列 [%s - %s] 到交换机失败: %s", queueName, routerKey, err.Error()))
+	routerKeys := []string{routerKey}
+	if multi, ok := receiver.(MultiRoutingReceiver); ok {
+		routerKeys = multi.RoutingKeys()
+	}
+	for _, key := range routerKeys {
+		err = channel.QueueBind(
+			queueName,       // queue name
+			key,             // routing key
+			mq.exchangeName, // exchange
+			false,           // no-wait
+			nil,
+		)
+		if nil != err {
+			receiver.OnError(fmt.Errorf("绑定队列 [%s - %s] 到交换机失败: %s", queueName, key, err.Error()))
+			return
+		}
+	}
+
+	// 重试消息发送到延时重试队列、死信队列后需要等待确认，才能应答原消息
+	if err = channel.Confirm(false); err != nil {
+		receiver.OnError(fmt.Errorf("队列 %s 的通道开启发布确认失败: %s", queueName, err.Error()))
+		return
 	}
+	confirms := channel.NotifyPublish(make(chan amqp.Confirmation, 1))
 
-	// 消费者流控
-	mq.channel.Qos(
-		1,    // 当前消费者一次能接受的最大消息数量
-		0,    // 服务器传递的最大容量(以八字节为单位)
-		true) // 设置为true，对全局channel可用
+	// 声明延时重试队列以及死信队列，在开始消费之前完成，保证处理失败的消息可以重试
+	policy := getRetryPolicy(receiver)
+	if err = declareRetryQueues(channel, queueName, policy); err != nil {
+		receiver.OnError(fmt.Errorf("初始化队列 %s 的重试队列失败: %s", queueName, err.Error()))
+		return
+	}
 
-	msgs, err := mq.channel.Consume(
-		queueName, // queue
-		strconv.FormatInt(gen.GenSnowflakeID(), 10), // consumer
+	// 消费者流控，每个消费者使用独立的通道，所以只对当前消费者生效
+	err = channel.Qos(
+		options.Prefetch, // 当前消费者一次能接受的最大消息数量
+		0,                // 服务器传递的最大容量(以八字节为单位)
+		false)            // 设置为false，只对当前通道上的消费者生效
+	if err != nil {
+		receiver.OnError(fmt.Errorf("设置队列 %s 的预取数量失败: %s", queueName, err.Error()))
+		return
+	}
+
+	consumerTag := strconv.FormatInt(gen.GenSnowflakeID(), 10)
+	msgs, err := channel.Consume(
+		queueName,   // queue
+		consumerTag, // consumer
 
 		false, // auto-ack 关闭自动应答
 		false, // exclusive
@@ -208,23 +225,53 @@ func (mq *RabbitMQ) listen(receiver Receiver) {
 	)
 	if nil != err {
 		receiver.OnError(fmt.Errorf("获取队列 %s 的消费通道失败: %s", queueName, err.Error()))
+		return
 	}
 
 	// 使用callback消费数据
-	for msg := range msgs {
-		// 当接收者消息处理失败的时候，
-		// 比如网络问题导致的数据库连接失败，redis连接失败等等这种
-		// 通过重试可以成功的操作，那么这个时候是需要重试的
-		// 直到数据处理成功后再返回，然后才会回复rabbitmq ack
-		times := 10
-		for !receiver.OnReceive(msg.Body) && times >= 0 {
-			zap.L().Error("receiver 数据处理失败，将要重试")
-			times--
-			time.Sleep(1 * time.Second)
+	for {
+		select {
+		case <-mq.stop:
+			// 停止接收新的消息，当前的消息已经处理完成
+			_ = channel.Cancel(consumerTag, false)
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				// 通道关闭
+				return
+			}
+			// 当接收者消息处理失败的时候，
+			// 比如网络问题导致的数据库连接失败，redis连接失败等等这种通过重试可以成功的操作，
+			// 将消息发送到延时重试队列，等待一段时间后回到原队列再次处理，不会阻塞后面的消息。
+			// 失败次数达到上限后进入死信队列，由管理员查看、重新发送或清空
+			if receive(extractHeaders(msg.Headers), queueName, receiver, msg.Body) {
+				// 为false表示确认当前消息,rq就会删除
+				msg.Ack(false)
+				continue
+			}
+			if !retryOrDeadLetter(channel, confirms, queueName, policy, msg) {
+				// 关闭通道，未应答的消息回到队列，隔一段时间后在新的通道上继续消费
+				return
+			}
 		}
+	}
+}
+
+// queueArgs 声明队列时的参数
+func queueArgs(queueName string) amqp.Table {
+	var args = make(amqp.Table, 0)
+	if queueName == OrderQueueName {
+		// 如果为订单相关操作的队列。指定死信队列相关信息
+		args["x-dead-letter-exchange"] = DelayOrderExchangeName
+		args["x-dead-letter-routing-key"] = DelayOrderRoutingKey
+	}
 
-		// 如果为true表示确认所有未确认的消息,一般用在批量消费中
-		// 为false表示确认当前消息,rq就会删除
-		msg.Ack(false)
+	if queueName == SecKillReqQueueName {
+		// 如果为保存秒杀请求的队列。
+		// 最大消息为秒杀商品库存的两倍
+		args["x-max-length"] = SecKillStore
+		// 当队列满时，拒绝新的请求
+		args["x-overflow"] = SecKillOverflow
 	}
+	return args
 }
@@ -0,0 +1,197 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+	"shop-backend/errs"
+	"shop-backend/logger"
+	"shop-backend/metrics"
+	"shop-backend/models/vo"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// 记录消息已经失败次数的消息头
+	retryAttemptHeader = "x-retry-attempt"
+	// 进入死信队列的消息记录原队列、进入时间的消息头
+	originalQueueHeader = "x-original-queue"
+	deadTimeHeader      = "x-dead-time"
+
+	// 延时重试队列名称：<队列名>.retry.<延时毫秒数>
+	retryQueueInfix = ".retry."
+	// 死信队列名称：<队列名>.dlq
+	deadLetterQueueSuffix = ".dlq"
+)
+
+var ErrorQueueNotExist = errs.NotFound("队列不存在")
+
+// RetryPolicy 消息处理失败后的重试策略。第n次失败后等待 BaseDelay*2^(n-1)(不超过MaxDelay) 再重试，
+// 失败MaxAttempts次后进入死信队列
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// RetryableReceiver 自定义重试策略的接收者，没有实现该接口的接收者使用defaultRetryPolicy
+type RetryableReceiver interface {
+	RetryPolicy() *RetryPolicy
+}
+
+var defaultRetryPolicy = &RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    5 * time.Minute,
+}
+
+var (
+	retryQueuesMu sync.RWMutex
+	// 已经声明了死信队列的队列
+	retryQueues = make(map[string]bool)
+)
+
+// delay 第attempt次失败后的等待时间
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// getRetryPolicy 获取接收者的重试策略
+func getRetryPolicy(receiver Receiver) *RetryPolicy {
+	if retryable, ok := receiver.(RetryableReceiver); ok {
+		if policy := retryable.RetryPolicy(); policy != nil && policy.MaxAttempts > 0 {
+			return policy
+		}
+	}
+	return defaultRetryPolicy
+}
+
+// retryQueueName 延时重试队列的名称
+func retryQueueName(queueName string, delay time.Duration) string {
+	return queueName + retryQueueInfix + strconv.FormatInt(delay.Milliseconds(), 10)
+}
+
+// deadLetterQueueName 死信队列的名称
+func deadLetterQueueName(queueName string) string {
+	return queueName + deadLetterQueueSuffix
+}
+
+// declareRetryQueues 声明队列的延时重试队列以及死信队列。
+// 延时重试队列中的消息过期后，通过默认交换机以原队列名称为路由回到原队列
+func declareRetryQueues(channel *amqp.Channel, queueName string, policy *RetryPolicy) error {
+	for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+		delay := policy.delay(attempt)
+		_, err := channel.QueueDeclare(retryQueueName(queueName, delay), true, false, false, false, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queueName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := channel.QueueDeclare(deadLetterQueueName(queueName), true, false, false, false, nil); err != nil {
+		return err
+	}
+	retryQueuesMu.Lock()
+	retryQueues[queueName] = true
+	retryQueuesMu.Unlock()
+	return nil
+}
+
+// retryOrDeadLetter 消息处理失败后，根据已经失败的次数发送到延时重试队列或死信队列，收到发布确认后应答原消息。
+// channel需要开启发布确认模式，confirms为该通道的发布确认。发送失败或没有收到确认时原消息退回原队列并返回false，
+// 此时通道上的确认可能已经错位，调用方需要关闭通道
+func retryOrDeadLetter(channel *amqp.Channel, confirms <-chan amqp.Confirmation, queueName string, policy *RetryPolicy, msg amqp.Delivery) bool {
+	attempt := getRetryAttempt(msg.Headers) + 1
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[retryAttemptHeader] = int32(attempt)
+
+	routingKey := deadLetterQueueName(queueName)
+	if attempt < policy.MaxAttempts {
+		routingKey = retryQueueName(queueName, policy.delay(attempt))
+	} else {
+		headers[originalQueueHeader] = queueName
+		headers[deadTimeHeader] = time.Now().Format(time.RFC3339)
+	}
+	err := channel.Publish("", routingKey, false, false, amqp.Publishing{
+		Headers:      headers,
+		DeliveryMode: 2, // 2 表示消息持久化
+		ContentType:  msg.ContentType,
+		Body:         msg.Body,
+	})
+	if err == nil {
+		err = waitConfirm(confirms)
+	}
+	// 日志带有发送消息的请求ID
+	lg := logger.Ctx(extractHeaders(msg.Headers))
+	if err != nil {
+		// 发送失败或RabbitMQ没有确认接收时退回原队列，不能丢失消息
+		lg.Error("发送消息到重试队列失败，退回原队列", zap.String("queue", queueName), zap.Error(err))
+		_ = msg.Nack(false, true)
+		return false
+	}
+	if attempt < policy.MaxAttempts {
+		metrics.MQRetried(queueName)
+		lg.Warn("receiver 数据处理失败，将要重试", zap.String("queue", queueName), zap.Int("attempt", attempt), zap.Duration("delay", policy.delay(attempt)))
+	} else {
+		metrics.MQDeadLettered(queueName)
+		lg.Error("receiver 数据处理失败次数达到上限，进入死信队列", zap.String("queue", queueName), zap.Int("attempt", attempt))
+	}
+	_ = msg.Ack(false)
+	return true
+}
+
+// waitConfirm 等待通道上最近一条消息的发布确认
+func waitConfirm(confirms <-chan amqp.Confirmation) error {
+	timer := time.NewTimer(confirmTimeout)
+	defer timer.Stop()
+	select {
+	case confirm, ok := <-confirms:
+		if !ok || !confirm.Ack {
+			return ErrorPublishNotConfirmed
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("等待RabbitMQ发布确认超时: %w", ErrorPublishNotConfirmed)
+	}
+}
+
+// getRetryAttempt 获取消息已经失败的次数
+func getRetryAttempt(headers amqp.Table) int {
+	switch v := headers[retryAttemptHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// buildDeadLetterVO 构建死信消息展示对象
+func buildDeadLetterVO(queueName string, msg amqp.Delivery) *vo.DeadLetterVO {
+	deadLetter := &vo.DeadLetterVO{
+		Queue:       queueName,
+		Attempts:    getRetryAttempt(msg.Headers),
+		ContentType: msg.ContentType,
+		Body:        string(msg.Body),
+	}
+	if deadTime, ok := msg.Headers[deadTimeHeader].(string); ok {
+		deadLetter.DeadTime = deadTime
+	}
+	return deadLetter
+}
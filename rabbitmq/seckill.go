@@ -1,18 +1,26 @@
 package rabbitmq
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/streadway/amqp"
+	"errors"
 	"go.uber.org/zap"
 	"shop-backend/dao/mysql"
+	"shop-backend/errs"
+	"shop-backend/logger"
+	"shop-backend/metrics"
 	"shop-backend/models/dto"
 )
 
+var (
+	ErrorSecKillFinished    = errs.Conflict("秒杀已结束")
+	ErrorSecKillUnavailable = errs.Unavailable("秒杀服务暂时不可用")
+)
+
 // SecKillReceiver 实现了Receiver接口，负责消费存入秒杀请求的队列
 type SecKillReceiver struct {
 	queueName string
 	routerKey string
-	e         error
 	body      []byte
 }
 
@@ -34,43 +42,61 @@ func (r *SecKillReceiver) RoutingKey() string {
 	return r.routerKey
 }
 
-// OnError 将执行过程中产生的异常赋值到接收者
+// OnError 记录消费者初始化通道时产生的异常，该消费者会关闭通道并稍后重试，不影响其他消费者
 func (r *SecKillReceiver) OnError(e error) {
-	r.e = e
+	zap.L().Error("秒杀请求的消费者初始化通道失败", zap.String("queue", r.queueName), zap.Error(e))
+}
+
+// ConsumerOptions 秒杀请求集中在短时间内到达，使用多个消费者扣减库存，乐观锁冲突的请求会重试
+func (r *SecKillReceiver) ConsumerOptions() *ConsumerOptions {
+	return &ConsumerOptions{Workers: 4, Prefetch: 8}
 }
 
-// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会反复处理消息，直到处理成功
+// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会延时重试，失败次数达到上限后进入死信队列
 func (r *SecKillReceiver) OnReceive(body []byte) bool {
-	if r.e != nil {
-		zap.L().Error("消费秒杀队列中的消息出现异常", zap.Error(r.e))
+	return r.OnReceiveContext(context.Background(), body)
+}
+
+// OnReceiveContext 在秒杀请求的链路中扣减库存
+func (r *SecKillReceiver) OnReceiveContext(ctx context.Context, body []byte) bool {
+	data := new(dto.SecKillMQ)
+	if err := json.Unmarshal(body, data); err != nil {
+		logger.Ctx(ctx).Error("解析秒杀请求失败，丢弃消息", zap.ByteString("body", body), zap.Error(err))
 		return true
 	}
-	data := new(dto.SecKillMQ)
-	_ = json.Unmarshal(body, &data)
-	_ = mysql.UpdateSecKillProductStock(data.SkuID)
-	return true
+	err := mysql.UpdateSecKillProductStock(ctx, data.SkuID)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, mysql.ErrorSecKillSkuNotExist), errors.Is(err, mysql.ErrorSecKillSoldOut):
+		// 重试也不会成功的秒杀请求
+		logger.Ctx(ctx).Warn("秒杀请求无法完成，丢弃消息", zap.Int64("skuID", data.SkuID), zap.Int64("uid", data.UID), zap.Error(err))
+		return true
+	default:
+		// 乐观锁多次冲突、数据库连接断开、死锁等错误延时重试，不能丢弃秒杀请求
+		logger.Ctx(ctx).Error("扣减秒杀商品库存失败，将要重试", zap.Int64("skuID", data.SkuID), zap.Int64("uid", data.UID), zap.Error(err))
+		return false
+	}
 }
 
-// SendSecKillReqMess2MQ 负责发送用户秒杀请求到RabbitMQ
-func SendSecKillReqMess2MQ(data *dto.SecKillMQ) error {
+// SendSecKillReqMess2MQ 负责发送用户秒杀请求到RabbitMQ。
+// 秒杀队列已满时返回ErrorSecKillFinished，RabbitMQ未连接、发布确认超时等错误返回ErrorSecKillUnavailable
+func SendSecKillReqMess2MQ(ctx context.Context, data *dto.SecKillMQ) error {
 	// 转换为json数据
 	dataJson, _ := json.Marshal(data)
 	// 发送消息
-	err = rabbitmqChannel5.Publish(
-		SecKillReqExchangeName,
-		SecKillReqRoutingKey,
-		false,
-		false,
-		amqp.Publishing{
-			DeliveryMode: 2, // 2 表示消息持久化
-			ContentType:  "application/json",
-			Body:         dataJson,
-		},
-	)
+	// 队列已满时RabbitMQ会拒绝消息，返回ErrorPublishRejected
+	err := bus.Publish(ctx, SecKillReqExchangeName, SecKillReqRoutingKey, dataJson)
+	if errors.Is(err, ErrorPublishRejected) {
+		metrics.SecKillRejected()
+		logger.Ctx(ctx).Info("秒杀服务，秒杀队列已满，拒绝秒杀请求", zap.Int64("skuID", data.SkuID))
+		return ErrorSecKillFinished
+	}
 	if err != nil {
-		zap.L().Error("秒杀服务，发送消息到RabbitMQ失败", zap.Error(err))
-		return err
+		logger.Ctx(ctx).Error("秒杀服务，发送消息到RabbitMQ失败", zap.Error(err))
+		return ErrorSecKillUnavailable
 	}
-	zap.L().Info("秒杀服务，发送消息到RabbitMQ成功")
+	metrics.SecKillAccepted()
+	logger.Ctx(ctx).Info("秒杀服务，发送消息到RabbitMQ成功")
 	return nil
 }
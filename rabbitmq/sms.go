@@ -1,9 +1,11 @@
 package rabbitmq
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/streadway/amqp"
 	"go.uber.org/zap"
+	"shop-backend/logger"
+	"shop-backend/metrics"
 	"shop-backend/utils/sms"
 )
 
@@ -11,7 +13,6 @@ import (
 type SmsReceiver struct {
 	queueName string
 	routerKey string
-	e         error
 	body      []byte
 }
 
@@ -33,32 +34,39 @@ func (r *SmsReceiver) RoutingKey() string {
 	return r.routerKey
 }
 
-// OnError 将执行过程中产生的异常赋值到接收者
+// OnError 记录消费者初始化通道时产生的异常，该消费者会关闭通道并稍后重试，不影响其他消费者
 func (r *SmsReceiver) OnError(e error) {
-	r.e = e
+	zap.L().Error("短信服务的消费者初始化通道失败", zap.String("queue", r.queueName), zap.Error(e))
 }
 
-// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会反复处理消息，直到处理成功
+// ConsumerOptions 调用阿里云SMS的耗时主要是网络等待，使用多个消费者同时发送短信
+func (r *SmsReceiver) ConsumerOptions() *ConsumerOptions {
+	return &ConsumerOptions{Workers: 4, Prefetch: 4}
+}
+
+// OnReceive 处理队列中的消息，处理成功返回true，则会应答。否则返回false，会延时重试，失败次数达到上限后进入死信队列
 // 这里的接收者是负责处理短信发送，所以不需要反复处理，如果一次发送失败，让用户再次获取验证码
 func (r *SmsReceiver) OnReceive(body []byte) bool {
-	if r.e != nil {
-		zap.L().Error("SMS短信服务，SmsReceiver中有异常", zap.Error(r.e))
-		return true
-	}
+	return r.OnReceiveContext(context.Background(), body)
+}
+
+// OnReceiveContext 发送短信的日志带有获取验证码请求的请求ID
+func (r *SmsReceiver) OnReceiveContext(ctx context.Context, body []byte) bool {
 	data := make(map[string]string)
 	// 反序列json
 	err := json.Unmarshal(body, &data)
 	if err != nil {
-		zap.L().Error("SMS短信服务，解析json失败", zap.Error(err))
+		logger.Ctx(ctx).Error("SMS短信服务，解析json失败", zap.Error(err))
 		return true
 	}
 	err = sms.SendSms(data["phone"], data["code"])
+	metrics.SmsSent(err)
 	if err != nil {
-		zap.L().Error("SMS短信服务，调用阿里云SMS发送短信失败", zap.Error(err),
+		logger.Ctx(ctx).Error("SMS短信服务，调用阿里云SMS发送短信失败", zap.Error(err),
 			zap.String("phone", data["phone"]))
 		return true
 	}
-	zap.L().Info("SMS短信服务，调用阿里云SMS发送短信成功", zap.Error(err),
+	logger.Ctx(ctx).Info("SMS短信服务，调用阿里云SMS发送短信成功", zap.Error(err),
 		zap.String("phone", data["phone"]),
 		zap.String("phone", data["phone"]),
 	)
@@ -66,29 +74,19 @@ func (r *SmsReceiver) OnReceive(body []byte) bool {
 }
 
 // SendSms 发送手机号和验证码到RabbitMQ中
-func SendSms(phone, code string) error {
+func SendSms(ctx context.Context, phone, code string) error {
 	// 使用map存储手机号和验证码
 	data := map[string]string{"phone": phone, "code": code}
 	// 转换为json数据
 	dataJson, err := json.Marshal(data)
 	if err != nil {
-		zap.L().Error("SMS短信服务，手机号和验证码转换为json格式失败", zap.Error(err))
+		logger.Ctx(ctx).Error("SMS短信服务，手机号和验证码转换为json格式失败", zap.Error(err))
 		return err
 	}
 	// 发送消息
-	err = rabbitmqChannel.Publish(
-		SmsExchangeName,
-		SmsRoutingKey,
-		false,
-		false,
-		amqp.Publishing{
-			DeliveryMode: 2, // 2 表示消息持久化
-			ContentType:  "application/json",
-			Body:         dataJson,
-		},
-	)
+	err = bus.Publish(ctx, SmsExchangeName, SmsRoutingKey, dataJson)
 	if err != nil {
-		zap.L().Error("SMS短信服务，发送消息到RabbitMQ失败", zap.Error(err))
+		logger.Ctx(ctx).Error("SMS短信服务，发送消息到RabbitMQ失败", zap.Error(err))
 		return err
 	}
 	return nil
@@ -4,11 +4,13 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
-	"net/http"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"shop-backend/controller"
 	_ "shop-backend/docs"
 	"shop-backend/logger"
+	"shop-backend/metrics"
 	"shop-backend/middleware"
+	"shop-backend/settings"
 )
 
 func SetupRouter(mode string) *gin.Engine {
@@ -18,10 +20,17 @@ func SetupRouter(mode string) *gin.Engine {
 	}
 
 	r := gin.New()
-	// 添加跨域、日志、恢复中间件
-	r.Use(middleware.Cors(), logger.GinLogger(), logger.GinRecovery(true))
+	// 添加指标、链路追踪、请求ID、跨域、日志、恢复中间件，指标中间件在最外层，记录被跨域中间件直接返回的请求。
+	// 链路追踪中间件从请求头中恢复上游的链路，并将请求的span放入c.Request.Context()，请求ID中间件在其之后，日志中可以带有trace_id
+	r.Use(middleware.Metrics(), otelgin.Middleware(settings.Conf.Name), middleware.RequestID(), middleware.Cors(), logger.GinLogger(), logger.GinRecovery(true))
 	// swagger接口文档路由
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// 存活检查，进程可以处理请求即返回成功
+	r.GET("/healthz", controller.HealthzHandler)
+	// 就绪检查，所有依赖都可用时返回成功
+	r.GET("/readyz", controller.ReadyzHandler)
+	// Prometheus指标
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	// 普通路由组，只包含跨域、日志、恢复中间件
 	commonGroup := r.Group("/api")
@@ -48,9 +57,17 @@ func SetupRouter(mode string) *gin.Engine {
 		jwtGroup.POST("/infos/update/avatar", controller.UserInfoUpdateAvatarHandler)
 		// 用户退出
 		jwtGroup.DELETE("/exit", controller.UserSignOutHandler)
+		// 获取用户搜索历史
+		jwtGroup.GET("/history/search/list", controller.UserSearchHistoryListHandler)
+		// 清空用户搜索历史
+		jwtGroup.DELETE("/history/search/clear", controller.UserSearchHistoryClearHandler)
+		// 获取用户浏览历史
+		jwtGroup.GET("/history/browse/list", controller.UserBrowseHistoryListHandler)
+		// 清空用户浏览历史
+		jwtGroup.DELETE("/history/browse/clear", controller.UserBrowseHistoryClearHandler)
 	}
 
-	// 商品路由组
+	// 商品路由组，搜索、商品详情接口使用可选的JWT认证，登录用户会记录搜索、浏览历史
 	pmsGroup := commonGroup.Group("/pms/product")
 	{
 		// 商品分类
@@ -58,9 +75,88 @@ func SetupRouter(mode string) *gin.Engine {
 		// 商品分类的属性列表
 		pmsGroup.GET("/attribute/bycategory/:categoryID", controller.ProductAttributeByCategoryIDHandler)
 		// 商品搜索接口
-		pmsGroup.POST("/search", controller.ProductSearchHandler)
+		pmsGroup.POST("/search", middleware.JWTOptionalMiddleware(), controller.ProductSearchHandler)
+		// 搜索补全接口
+		pmsGroup.GET("/search/suggest", controller.ProductSearchSuggestHandler)
+		// 热搜词接口
+		pmsGroup.GET("/search/hot", controller.ProductSearchHotHandler)
 		// 商品详情接口
-		pmsGroup.GET("/detail/:skuID", controller.ProductDetailHandler)
+		pmsGroup.GET("/detail/:skuID", middleware.JWTOptionalMiddleware(), controller.ProductDetailHandler)
+		// 商品分类下的品牌列表
+		pmsGroup.GET("/brand/bycategory/:categoryID", controller.ProductBrandByCategoryIDHandler)
+	}
+
+	// 商品评价路由组，评价列表不需要鉴权
+	commentGroup := commonGroup.Group("/pms/comment")
+	{
+		// 商品评价列表
+		commentGroup.GET("/list/:spuID", controller.ProductCommentListHandler)
+	}
+	commentJWTGroup := commentGroup.Use(middleware.JWTAuthMiddleware())
+	{
+		// 发表商品评价
+		commentJWTGroup.POST("/add", controller.ProductCommentAddHandler)
+		// 追加商品评价
+		commentJWTGroup.POST("/append", controller.ProductCommentAppendHandler)
+		// 评价有用投票
+		commentJWTGroup.POST("/helpful/:id", controller.ProductCommentHelpfulHandler)
+		// 上传评价图片
+		commentJWTGroup.POST("/pic", controller.ProductCommentPicUploadHandler)
+	}
+
+	// 后台管理路由组，需要鉴权并且只允许管理员访问
+	adminGroup := commonGroup.Group("/admin").Use(middleware.JWTAuthMiddleware(), middleware.AdminAuthMiddleware())
+	{
+		// 获取所有品牌
+		adminGroup.GET("/brand/list", controller.AdminBrandListHandler)
+		// 新增品牌
+		adminGroup.POST("/brand/add", controller.AdminBrandAddHandler)
+		// 修改品牌
+		adminGroup.PUT("/brand/update", controller.AdminBrandUpdateHandler)
+		// 删除品牌
+		adminGroup.DELETE("/brand/del/:id", controller.AdminBrandDelHandler)
+		// 上传品牌Logo
+		adminGroup.POST("/brand/logo", controller.AdminBrandLogoUploadHandler)
+		// 新增商品分类
+		adminGroup.POST("/category/add", controller.AdminCategoryAddHandler)
+		// 修改商品分类
+		adminGroup.PUT("/category/update", controller.AdminCategoryUpdateHandler)
+		// 移动商品分类
+		adminGroup.PUT("/category/move", controller.AdminCategoryMoveHandler)
+		// 修改商品分类排序
+		adminGroup.PUT("/category/sort", controller.AdminCategorySortHandler)
+		// 显示/隐藏商品分类
+		adminGroup.PUT("/category/status", controller.AdminCategoryShowStatusHandler)
+		// 删除商品分类
+		adminGroup.DELETE("/category/del/:id", controller.AdminCategoryDelHandler)
+		// 获取所有商品属性
+		adminGroup.GET("/attribute/list", controller.AdminAttributeListHandler)
+		// 新增商品属性
+		adminGroup.POST("/attribute/add", controller.AdminAttributeAddHandler)
+		// 修改商品属性
+		adminGroup.PUT("/attribute/update", controller.AdminAttributeUpdateHandler)
+		// 删除商品属性
+		adminGroup.DELETE("/attribute/del/:id", controller.AdminAttributeDelHandler)
+		// 为商品spu分配属性值
+		adminGroup.PUT("/spu/attribute", controller.AdminSpuAttributeAssignHandler)
+		// 获取被屏蔽的搜索关键字
+		adminGroup.GET("/search/blocked/list", controller.AdminSearchBlockedListHandler)
+		// 屏蔽搜索关键字
+		adminGroup.POST("/search/blocked/add", controller.AdminSearchBlockedAddHandler)
+		// 取消屏蔽搜索关键字
+		adminGroup.DELETE("/search/blocked/del", controller.AdminSearchBlockedDelHandler)
+		// 商家回复商品评价
+		adminGroup.PUT("/comment/reply", controller.AdminCommentReplyHandler)
+		// 获取canal最后处理的binlog位置
+		adminGroup.GET("/canal/position", controller.AdminCanalPositionHandler)
+		// 获取所有死信队列
+		adminGroup.GET("/mq/deadletter/list", controller.AdminDeadLetterQueueListHandler)
+		// 查看死信队列中的消息
+		adminGroup.GET("/mq/deadletter/peek", controller.AdminDeadLetterPeekHandler)
+		// 重新发送死信队列中的消息
+		adminGroup.POST("/mq/deadletter/replay", controller.AdminDeadLetterReplayHandler)
+		// 清空死信队列
+		adminGroup.DELETE("/mq/deadletter/purge", controller.AdminDeadLetterPurgeHandler)
 	}
 
 	// 购物车路由组，需要鉴权
@@ -129,7 +225,7 @@ func SetupRouter(mode string) *gin.Engine {
 		secKillTestGroup.POST("/buy", controller.SecKillBuyHandler)
 	}
 	r.NoRoute(func(c *gin.Context) {
-		controller.ResponseErrorWithMsg(c, http.StatusBadRequest, gin.H{"msg": "404"})
+		controller.ResponseError(c, controller.CodeRouteNotFound)
 	})
 	return r
 }
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+var (
+	highlightPreTag  = "<em>"
+	highlightPostTag = "</em>"
+)
+
+// Highlight 使用<em></em>标签包裹text中与keyword匹配的片段。
+// text中的字符都经过HTML转义，只有高亮标签是HTML，前端可以直接作为HTML渲染
+func Highlight(text, keyword string) string {
+	tokens := tokenizeQuery(keyword)
+	if len(tokens) == 0 || text == "" {
+		return html.EscapeString(text)
+	}
+	original := []rune(text)
+	lower := make([]rune, len(original))
+	for i, r := range original {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 标记需要高亮的字符
+	marked := make([]bool, len(original))
+	for _, token := range tokens {
+		tokenRunes := []rune(token)
+		for i := 0; i+len(tokenRunes) <= len(lower); i++ {
+			if runesEqual(lower[i:i+len(tokenRunes)], tokenRunes) {
+				for j := i; j < i+len(tokenRunes); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	// 按照是否高亮将text切分为连续的片段，每个片段转义后写入
+	var builder strings.Builder
+	for start := 0; start < len(original); {
+		end := start + 1
+		for end < len(original) && marked[end] == marked[start] {
+			end++
+		}
+		segment := html.EscapeString(string(original[start:end]))
+		if marked[start] {
+			builder.WriteString(highlightPreTag)
+			builder.WriteString(segment)
+			builder.WriteString(highlightPostTag)
+		} else {
+			builder.WriteString(segment)
+		}
+		start = end
+	}
+	return builder.String()
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
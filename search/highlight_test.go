@@ -0,0 +1,29 @@
+package search
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		keyword string
+		want    string
+	}{
+		{"中文匹配", "华为Mate40 手机", "手机", "华为Mate40 <em>手机</em>"},
+		{"重叠的二元词合并为一段", "华为手机壳", "华为手机", "<em>华为手机</em>壳"},
+		{"多处匹配", "手机壳手机", "手机", "<em>手机</em>壳<em>手机</em>"},
+		{"忽略大小写并保留原文", "Apple iPhone 13", "IPHONE", "Apple <em>iPhone</em> 13"},
+		{"相邻的多个词项", "小米Max3", "小米 max3", "<em>小米Max3</em>"},
+		{"没有匹配", "华为手机", "苹果", "华为手机"},
+		{"转义HTML", "<b>手机</b> & 壳", "手机", "&lt;b&gt;<em>手机</em>&lt;/b&gt; &amp; 壳"},
+		{"转义高亮片段", "a<b>手机", "b", "a&lt;<em>b</em>&gt;手机"},
+		{"空关键词也转义", "<script>", "", "&lt;script&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.keyword); got != tt.want {
+				t.Errorf("Highlight(%q, %q) = %q，期望 %q", tt.text, tt.keyword, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// 各字段的权重，商品标题最重要
+const (
+	titleBoost    = 3.0
+	spuNameBoost  = 2.0
+	brandBoost    = 2.0
+	categoryBoost = 1.5
+	subTitleBoost = 1.0
+)
+
+// Document 被索引的商品文档，一个默认规格的sku对应一个文档
+type Document struct {
+	SkuID        int64
+	SpuID        int64
+	Title        string
+	SpuName      string
+	SubTitle     string
+	BrandName    string
+	CategoryName string
+}
+
+// Hit 搜索命中的商品
+type Hit struct {
+	SkuID int64
+	Score float64
+}
+
+// indexedDoc 索引中保存的文档信息
+type indexedDoc struct {
+	spuID  int64
+	length float64
+	terms  []string
+}
+
+// Index 内存倒排索引，并发安全
+type Index struct {
+	mu sync.RWMutex
+	// K: skuID V: 文档信息
+	docs map[int64]*indexedDoc
+	// K: 词项 V: (K: skuID V: 加权词频)
+	postings map[string]map[int64]float64
+	// 所有文档的加权长度之和，用于计算平均文档长度
+	totalLength float64
+}
+
+// NewIndex 创建一个空的倒排索引
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[int64]*indexedDoc),
+		postings: make(map[string]map[int64]float64),
+	}
+}
+
+// Size 返回索引中的文档数量
+func (idx *Index) Size() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Add 添加或覆盖一个文档
+func (idx *Index) Add(doc *Document) {
+	// 按字段权重累加词频
+	tf := make(map[string]float64)
+	var length float64
+	addField := func(text string, boost float64) {
+		for _, token := range Tokenize(text) {
+			tf[token] += boost
+			length += boost
+		}
+	}
+	addField(doc.Title, titleBoost)
+	addField(doc.SpuName, spuNameBoost)
+	addField(doc.BrandName, brandBoost)
+	addField(doc.CategoryName, categoryBoost)
+	addField(doc.SubTitle, subTitleBoost)
+
+	terms := make([]string, 0, len(tf))
+	for term := range tf {
+		terms = append(terms, term)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(doc.SkuID)
+	for term, freq := range tf {
+		posting, ok := idx.postings[term]
+		if !ok {
+			posting = make(map[int64]float64)
+			idx.postings[term] = posting
+		}
+		posting[doc.SkuID] = freq
+	}
+	idx.docs[doc.SkuID] = &indexedDoc{spuID: doc.SpuID, length: length, terms: terms}
+	idx.totalLength += length
+}
+
+// Remove 删除一个文档
+func (idx *Index) Remove(skuID int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(skuID)
+}
+
+// RemoveSpu 删除spu下的所有文档
+func (idx *Index) RemoveSpu(spuID int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for skuID, doc := range idx.docs {
+		if doc.spuID == spuID {
+			idx.removeLocked(skuID)
+		}
+	}
+}
+
+func (idx *Index) removeLocked(skuID int64) {
+	doc, ok := idx.docs[skuID]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		posting := idx.postings[term]
+		delete(posting, skuID)
+		if len(posting) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLength -= doc.length
+	delete(idx.docs, skuID)
+}
+
+// Search 使用BM25算法对查询变体进行打分，返回按相关度降序排列的前limit个结果。
+// variants中的每一项是一个查询变体(原始查询、同义词替换后的查询)的词项集合，weights为对应变体的权重，文档得分取各变体得分的最大值。
+// 一个文档至少需要匹配变体中minShouldMatch比例的词项才会被召回
+func (idx *Index) Search(variants [][]string, weights []float64, minShouldMatch float64, limit int) []Hit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := float64(len(idx.docs))
+	if n == 0 {
+		return nil
+	}
+	avgLength := idx.totalLength / n
+
+	best := make(map[int64]float64)
+	for i, terms := range variants {
+		terms = uniqueTerms(terms)
+		if len(terms) == 0 {
+			continue
+		}
+		required := int(math.Ceil(float64(len(terms)) * minShouldMatch))
+		scores := make(map[int64]float64)
+		matched := make(map[int64]int)
+		for _, term := range terms {
+			posting, ok := idx.postings[term]
+			if !ok {
+				continue
+			}
+			df := float64(len(posting))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for skuID, freq := range posting {
+				length := idx.docs[skuID].length
+				scores[skuID] += idf * freq * (bm25K1 + 1) / (freq + bm25K1*(1-bm25B+bm25B*length/avgLength))
+				matched[skuID]++
+			}
+		}
+		for skuID, score := range scores {
+			if matched[skuID] < required {
+				continue
+			}
+			score *= weights[i]
+			if score > best[skuID] {
+				best[skuID] = score
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(best))
+	for skuID, score := range best {
+		hits = append(hits, Hit{SkuID: skuID, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].SkuID < hits[j].SkuID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// uniqueTerms 词项去重
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	result := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+	return result
+}
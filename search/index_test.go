@@ -0,0 +1,145 @@
+package search
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newTestIndex 创建包含docs的索引
+func newTestIndex(docs ...*Document) *Index {
+	idx := NewIndex()
+	for _, doc := range docs {
+		idx.Add(doc)
+	}
+	return idx
+}
+
+// hitIDs 按照顺序返回命中的skuID
+func hitIDs(hits []Hit) []int64 {
+	ids := make([]int64, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.SkuID)
+	}
+	return ids
+}
+
+func search(idx *Index, keyword string, minShouldMatch float64) []int64 {
+	return hitIDs(idx.Search([][]string{tokenizeQuery(keyword)}, []float64{1}, minShouldMatch, 0))
+}
+
+func TestIndexTitleBoost(t *testing.T) {
+	idx := newTestIndex(
+		// 副标题中匹配
+		&Document{SkuID: 1, SpuID: 1, Title: "快充充电器", SubTitle: "华为手机"},
+		// 标题中匹配，标题的权重高于副标题
+		&Document{SkuID: 2, SpuID: 2, Title: "华为手机", SubTitle: "快充充电器"},
+	)
+	if got := search(idx, "华为手机", 0.6); !reflect.DeepEqual(got, []int64{2, 1}) {
+		t.Errorf("搜索返回 %v，期望标题匹配的商品排在前面 [2 1]", got)
+	}
+}
+
+func TestIndexMinShouldMatch(t *testing.T) {
+	idx := newTestIndex(
+		// 匹配全部3个词项：华为、为手、手机
+		&Document{SkuID: 1, Title: "华为手机"},
+		// 匹配2个词项：华为、手机
+		&Document{SkuID: 2, Title: "华为平板 手机支架"},
+		// 匹配1个词项：手机
+		&Document{SkuID: 3, Title: "小米手机"},
+		&Document{SkuID: 4, Title: "苹果平板", BrandName: "Apple"},
+	)
+	tests := []struct {
+		name           string
+		keyword        string
+		minShouldMatch float64
+		want           []int64
+	}{
+		{"全部匹配", "华为手机", 1, []int64{1}},
+		{"至少匹配60%", "华为手机", 0.6, []int64{1, 2}},
+		{"不限制最少匹配比例", "华为手机", 0, []int64{1, 2, 3}},
+		{"品牌名匹配", "apple", 0.6, []int64{4}},
+		{"没有匹配", "耳机", 0, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := search(idx, tt.keyword, tt.minShouldMatch)
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("搜索 %q 返回 %v，期望 %v", tt.keyword, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexLimit(t *testing.T) {
+	idx := newTestIndex(
+		&Document{SkuID: 1, Title: "手机"},
+		&Document{SkuID: 2, Title: "手机"},
+		&Document{SkuID: 3, Title: "手机"},
+	)
+	// 得分相同时按照skuID升序
+	hits := idx.Search([][]string{tokenizeQuery("手机")}, []float64{1}, 0.6, 2)
+	if got := hitIDs(hits); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Errorf("限制2条结果返回 %v，期望 [1 2]", got)
+	}
+}
+
+func TestIndexAddRemove(t *testing.T) {
+	idx := newTestIndex(
+		&Document{SkuID: 1, SpuID: 10, Title: "华为手机"},
+		&Document{SkuID: 2, SpuID: 10, Title: "华为手机 套装"},
+		&Document{SkuID: 3, SpuID: 20, Title: "小米手机"},
+	)
+	steps := []struct {
+		name   string
+		change func()
+		size   int
+		want   []int64
+	}{
+		{"初始", func() {}, 3, []int64{1, 2, 3}},
+		{"删除sku", func() { idx.Remove(1) }, 2, []int64{2, 3}},
+		{"重复删除", func() { idx.Remove(1) }, 2, []int64{2, 3}},
+		{"覆盖后不再匹配", func() { idx.Add(&Document{SkuID: 3, SpuID: 20, Title: "小米平板"}) }, 2, []int64{2}},
+		{"重新添加", func() { idx.Add(&Document{SkuID: 1, SpuID: 10, Title: "华为手机"}) }, 3, []int64{1, 2}},
+		{"删除spu", func() { idx.RemoveSpu(10) }, 1, []int64{}},
+	}
+	for _, step := range steps {
+		step.change()
+		if size := idx.Size(); size != step.size {
+			t.Fatalf("%s: 索引中有 %d 个文档，期望 %d 个", step.name, size, step.size)
+		}
+		got := search(idx, "手机", 0.6)
+		sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		if !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("%s: 搜索返回 %v，期望 %v", step.name, got, step.want)
+		}
+	}
+	// 删除所有文档后不再保留词项
+	idx.Remove(3)
+	if len(idx.postings) != 0 || idx.totalLength != 0 {
+		t.Errorf("删除所有文档后仍有 %d 个词项，总长度 %v", len(idx.postings), idx.totalLength)
+	}
+}
+
+func TestSynonymVariants(t *testing.T) {
+	previous := synonyms
+	synonyms = parseSynonyms([]string{"手机, 电话", "single"})
+	defer func() { synonyms = previous }()
+
+	variants, weights := buildVariants(" 华为手机 ")
+	wantVariants := [][]string{{"华为", "为手", "手机"}, {"华为", "为电", "电话"}}
+	if !reflect.DeepEqual(variants, wantVariants) || !reflect.DeepEqual(weights, []float64{1, synonymWeight}) {
+		t.Fatalf("查询变体为 %q %v，期望 %q [1 %v]", variants, weights, wantVariants, synonymWeight)
+	}
+
+	// 同义词召回，原始查询匹配的文档排在前面
+	idx := newTestIndex(
+		&Document{SkuID: 1, Title: "华为电话"},
+		&Document{SkuID: 2, Title: "华为手机"},
+	)
+	if got := hitIDs(idx.Search(variants, weights, 0.6, 0)); !reflect.DeepEqual(got, []int64{2, 1}) {
+		t.Errorf("同义词搜索返回 %v，期望 [2 1]", got)
+	}
+}
@@ -0,0 +1,267 @@
+package search
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"shop-backend/dao/mysql"
+	"shop-backend/lifecycle"
+	"shop-backend/models/vo"
+	"shop-backend/settings"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 同义词替换后的查询变体权重略低于原始查询
+const synonymWeight = 0.9
+
+// 最多生成的查询变体数量
+const maxVariants = 8
+
+var (
+	// 当前使用的索引，全量重建时整体替换
+	index = NewIndex()
+	// 索引是否已经构建完成
+	ready int32
+	conf  = &settings.SearchConfig{MaxHits: 1000, MinShouldMatch: 0.6}
+
+	// 全量重建期间发生变化的sku、spu，重建完成后需要重新索引
+	mu         sync.Mutex
+	rebuilding bool
+	dirtySku   = make(map[int64]bool)
+	dirtySpu   = make(map[int64]bool)
+	synonyms   [][]string
+)
+
+// Init 在后台构建全文索引，并按照配置的间隔定时全量重建
+func Init(cfg *settings.SearchConfig) {
+	if cfg == nil || !cfg.Enable {
+		zap.L().Info("未开启全文索引，商品搜索使用MySQL模糊查询")
+		return
+	}
+	conf = cfg
+	synonyms = parseSynonyms(cfg.Synonyms)
+	lifecycle.Go("全文索引重建", lifecycle.PhaseIntake, func(ctx context.Context) {
+		rebuildLoop(ctx, cfg)
+	})
+}
+
+// rebuildLoop 首次构建失败时稍后重试，构建成功后按照配置的间隔定时全量重建，ctx取消后返回
+func rebuildLoop(ctx context.Context, cfg *settings.SearchConfig) {
+	for {
+		if err := Rebuild(ctx); err != nil {
+			zap.L().Error("全量构建全文索引失败", zap.Error(err))
+		}
+		if cfg.RebuildInterval <= 0 && Ready() {
+			return
+		}
+		interval := time.Duration(cfg.RebuildInterval) * time.Minute
+		if !Ready() || interval <= 0 {
+			// 首次构建失败，稍后重试
+			interval = 10 * time.Second
+		}
+		if !lifecycle.Sleep(ctx, interval) {
+			return
+		}
+	}
+}
+
+// Ready 全文索引是否可用
+func Ready() bool {
+	return atomic.LoadInt32(&ready) == 1
+}
+
+// Rebuild 从MySQL中全量构建全文索引，构建完成后替换当前索引
+func Rebuild(ctx context.Context) error {
+	mu.Lock()
+	rebuilding = true
+	mu.Unlock()
+
+	start := time.Now()
+	docs, err := mysql.SelectSearchDocuments(ctx, nil, nil)
+	if err != nil {
+		mu.Lock()
+		rebuilding = false
+		mu.Unlock()
+		return err
+	}
+	newIndex := NewIndex()
+	for _, doc := range docs {
+		newIndex.Add(buildDocument(doc))
+	}
+
+	mu.Lock()
+	index = newIndex
+	rebuilding = false
+	skuIDs := keys(dirtySku)
+	spuIDs := keys(dirtySpu)
+	dirtySku = make(map[int64]bool)
+	dirtySpu = make(map[int64]bool)
+	mu.Unlock()
+	atomic.StoreInt32(&ready, 1)
+
+	// 重新索引构建期间发生变化的商品
+	if len(skuIDs) != 0 {
+		if err = IndexSku(skuIDs...); err != nil {
+			return err
+		}
+	}
+	if len(spuIDs) != 0 {
+		if err = IndexSpu(spuIDs...); err != nil {
+			return err
+		}
+	}
+	zap.L().Info("全量构建全文索引成功", zap.Int("docs", len(docs)), zap.Duration("cost", time.Since(start)))
+	return nil
+}
+
+// Query 全文检索，返回按相关度降序排列的商品。索引不可用时ok为false，调用方需要降级查询MySQL
+func Query(keyword string) (hits []Hit, ok bool) {
+	if !Ready() {
+		return nil, false
+	}
+	variants, weights := buildVariants(keyword)
+	return currentIndex().Search(variants, weights, conf.MinShouldMatch, conf.MaxHits), true
+}
+
+// IndexSku 从MySQL中重新加载sku并更新索引，不再是默认规格的sku会从索引中删除
+func IndexSku(skuIDs ...int64) error {
+	if markDirty(skuIDs, nil) {
+		return nil
+	}
+	docs, err := mysql.SelectSearchDocuments(context.Background(), skuIDs, nil)
+	if err != nil {
+		return err
+	}
+	idx := currentIndex()
+	found := make(map[int64]bool, len(docs))
+	for _, doc := range docs {
+		idx.Add(buildDocument(doc))
+		found[doc.SkuID] = true
+	}
+	for _, skuID := range skuIDs {
+		if !found[skuID] {
+			idx.Remove(skuID)
+		}
+	}
+	return nil
+}
+
+// IndexSpu 从MySQL中重新加载spu下所有的sku并更新索引
+func IndexSpu(spuIDs ...int64) error {
+	if markDirty(nil, spuIDs) {
+		return nil
+	}
+	docs, err := mysql.SelectSearchDocuments(context.Background(), nil, spuIDs)
+	if err != nil {
+		return err
+	}
+	idx := currentIndex()
+	for _, spuID := range spuIDs {
+		idx.RemoveSpu(spuID)
+	}
+	for _, doc := range docs {
+		idx.Add(buildDocument(doc))
+	}
+	return nil
+}
+
+// RemoveSku 从索引中删除sku
+func RemoveSku(skuIDs ...int64) {
+	markDirty(skuIDs, nil)
+	idx := currentIndex()
+	for _, skuID := range skuIDs {
+		idx.Remove(skuID)
+	}
+}
+
+// RemoveSpu 从索引中删除spu下所有的sku
+func RemoveSpu(spuIDs ...int64) {
+	markDirty(nil, spuIDs)
+	idx := currentIndex()
+	for _, spuID := range spuIDs {
+		idx.RemoveSpu(spuID)
+	}
+}
+
+// markDirty 如果正在全量重建，记录发生变化的商品并返回true，由重建完成后统一重新索引
+func markDirty(skuIDs, spuIDs []int64) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if !rebuilding {
+		return false
+	}
+	for _, skuID := range skuIDs {
+		dirtySku[skuID] = true
+	}
+	for _, spuID := range spuIDs {
+		dirtySpu[spuID] = true
+	}
+	return true
+}
+
+func currentIndex() *Index {
+	mu.Lock()
+	defer mu.Unlock()
+	return index
+}
+
+// buildVariants 生成查询变体：原始查询以及同义词替换后的查询
+func buildVariants(keyword string) ([][]string, []float64) {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	variants := [][]string{tokenizeQuery(keyword)}
+	weights := []float64{1}
+	for _, group := range synonyms {
+		for _, word := range group {
+			if !strings.Contains(keyword, word) {
+				continue
+			}
+			for _, other := range group {
+				if other == word || len(variants) >= maxVariants {
+					continue
+				}
+				variants = append(variants, tokenizeQuery(strings.ReplaceAll(keyword, word, other)))
+				weights = append(weights, synonymWeight)
+			}
+		}
+	}
+	return variants, weights
+}
+
+// parseSynonyms 解析同义词配置
+func parseSynonyms(lines []string) [][]string {
+	groups := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		group := make([]string, 0)
+		for _, word := range strings.Split(line, ",") {
+			if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+				group = append(group, word)
+			}
+		}
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+func buildDocument(doc *vo.SearchDocumentVO) *Document {
+	return &Document{
+		SkuID:        doc.SkuID,
+		SpuID:        doc.SpuID,
+		Title:        doc.Title,
+		SpuName:      doc.SpuName,
+		SubTitle:     doc.SubTitle,
+		BrandName:    doc.BrandName,
+		CategoryName: doc.CategoryName,
+	}
+}
+
+func keys(m map[int64]bool) []int64 {
+	result := make([]int64, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"github.com/mozillazg/go-pinyin"
+	"strings"
+	"unicode"
+)
+
+// SuggestKeys 生成文本用于前缀补全的索引key：规范化后的原文、全拼以及拼音首字母。
+// 例如"皇家猫粮"会生成"皇家猫粮"、"huangjiamaoliang"、"hjml"，用户输入其中任意一个的前缀都能匹配
+func SuggestKeys(text string) []string {
+	normalized := NormalizeSuggest(text)
+	if normalized == "" {
+		return nil
+	}
+	keys := []string{normalized}
+	if !containsHan(normalized) {
+		return keys
+	}
+	// 非中文字符原样保留，使"k36猫粮"生成"k36maoliang"、"k36ml"
+	args := pinyin.NewArgs()
+	args.Fallback = func(r rune, a pinyin.Args) []string {
+		return []string{string(r)}
+	}
+	full := strings.Join(pinyin.LazyPinyin(normalized, args), "")
+	args.Style = pinyin.FirstLetter
+	initials := strings.Join(pinyin.LazyPinyin(normalized, args), "")
+	for _, key := range []string{full, initials} {
+		if key != "" && key != keys[len(keys)-1] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// NormalizeSuggest 规范化前缀补全的文本：转为小写并去除所有空白字符
+func NormalizeSuggest(text string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// containsHan 文本中是否包含中文字符
+func containsHan(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
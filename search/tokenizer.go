@@ -0,0 +1,80 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize 分词，用于建立索引。
+// 英文、数字按单词切分并转为小写；连续的中文同时切分为单字和相邻两字组成的二元词，不依赖词典也能匹配任意中文片段
+func Tokenize(text string) []string {
+	tokens := make([]string, 0)
+	for _, run := range splitRuns(text) {
+		if !run.cjk {
+			tokens = append(tokens, string(run.runes))
+			continue
+		}
+		for i := range run.runes {
+			tokens = append(tokens, string(run.runes[i]))
+			if i+1 < len(run.runes) {
+				tokens = append(tokens, string(run.runes[i:i+2]))
+			}
+		}
+	}
+	return tokens
+}
+
+// tokenizeQuery 分词，用于查询。
+// 连续的中文只切分为二元词，只有单个中文字符时才使用单字，避免只包含其中一个字的商品被大量召回
+func tokenizeQuery(text string) []string {
+	tokens := make([]string, 0)
+	for _, run := range splitRuns(text) {
+		if !run.cjk || len(run.runes) == 1 {
+			tokens = append(tokens, string(run.runes))
+			continue
+		}
+		for i := 0; i+1 < len(run.runes); i++ {
+			tokens = append(tokens, string(run.runes[i:i+2]))
+		}
+	}
+	return tokens
+}
+
+// run 一段连续的中文或者连续的英文、数字
+type run struct {
+	runes []rune
+	cjk   bool
+}
+
+// splitRuns 将文本切分为连续的中文片段和英文、数字片段，其余字符作为分隔符
+func splitRuns(text string) []run {
+	runs := make([]run, 0)
+	var current []rune
+	var currentCJK bool
+	flush := func() {
+		if len(current) > 0 {
+			runs = append(runs, run{runes: current, cjk: currentCJK})
+			current = nil
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if !currentCJK {
+				flush()
+			}
+			currentCJK = true
+			current = append(current, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if currentCJK {
+				flush()
+			}
+			currentCJK = false
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return runs
+}
@@ -0,0 +1,48 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"中文单字和二元词", "华为手机", []string{"华", "华为", "为", "为手", "手", "手机", "机"}},
+		{"单个中文", "壳", []string{"壳"}},
+		{"英文转为小写", "iPhone13 Pro", []string{"iphone13", "pro"}},
+		{"中英文混合", "小米Max3手机", []string{"小", "小米", "米", "max3", "手", "手机", "机"}},
+		{"标点作为分隔符", "华为-P50,128G", []string{"华", "华为", "为", "p50", "128g"}},
+		{"空文本", " ,. ", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %q，期望 %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"中文只使用二元词", "华为手机", []string{"华为", "为手", "手机"}},
+		{"单个中文使用单字", "壳", []string{"壳"}},
+		{"中英文混合", "Apple 苹果手机", []string{"apple", "苹果", "果手", "手机"}},
+		{"英文数字相连", "Mate40手机", []string{"mate40", "手机"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizeQuery(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenizeQuery(%q) = %q，期望 %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
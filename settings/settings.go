@@ -16,6 +16,7 @@ type AppConfig struct {
 	Version         string `mapstructure:"version"`
 	StartTime       string `mapstructure:"start_time"`
 	MachineId       int64  `mapstructure:"machine_id"`
+	ShutdownTimeout int    `mapstructure:"shutdown_timeout"` // 服务退出时等待所有子系统关闭的最长时间，单位秒
 	*LogConfig      `mapstructure:"log"`
 	*MySQLConfig    `mapstructure:"mysql"`
 	*RedisConfig    `mapstructure:"redis"`
@@ -24,6 +25,9 @@ type AppConfig struct {
 	*RabbitMQConfig `mapstructure:"rabbitmq"`
 	*CanalConfig    `mapstructure:"canal"`
 	*AliPayConfig   `mapstructure:"alipay"`
+	*AdminConfig    `mapstructure:"admin"`
+	*SearchConfig   `mapstructure:"search"`
+	*TracingConfig  `mapstructure:"tracing"`
 }
 
 type LogConfig struct {
@@ -63,6 +67,17 @@ type RabbitMQConfig struct {
 	Host     string `mapstructure:"host"`
 	User     string `mapstructure:"user"`
 	Password string `mapstructure:"password"`
+	// 消息总线的实现：amqp(默认，使用RabbitMQ)、memory(进程内，用于单元测试)
+	Driver string `mapstructure:"driver"`
+	// 每个队列的消费者配置，K为队列名。没有配置的队列使用接收者声明的默认值
+	Consumers map[string]*ConsumerConfig `mapstructure:"consumers"`
+}
+
+type ConsumerConfig struct {
+	// 消费者(协程)数量，每个消费者使用独立的通道
+	Workers int `mapstructure:"workers"`
+	// 每个消费者未应答的最大消息数量
+	Prefetch int `mapstructure:"prefetch"`
 }
 
 type CanalConfig struct {
@@ -71,6 +86,12 @@ type CanalConfig struct {
 	User        string `mapstructure:"user"`
 	Password    string `mapstructure:"password"`
 	Destination string `mapstructure:"destination"`
+	// 监听的数据库名，默认为shop
+	Schema string `mapstructure:"schema"`
+	// 数据库变更来源：canal(默认)、poll(轮询updated_time)、none(不监听)
+	Source string `mapstructure:"source"`
+	// 轮询模式下的轮询间隔(秒)
+	PollInterval int `mapstructure:"poll_interval"`
 }
 
 type AliyunConfig struct {
@@ -85,6 +106,8 @@ type OSSConfig struct {
 	Endpoint         string `mapstructure:"endpoint"`
 	BucketName       string `mapstructure:"bucket_name"`
 	UserAvatarPrefix string `mapstructure:"user_avatar_prefix"`
+	BrandLogoPrefix  string `mapstructure:"brand_logo_prefix"`
+	CommentPicPrefix string `mapstructure:"comment_pic_prefix"`
 }
 
 type AliPayConfig struct {
@@ -93,6 +116,45 @@ type AliPayConfig struct {
 	AppID      string `mapstructure:"app_id"`
 }
 
+type AdminConfig struct {
+	// 后台管理员用户ID
+	UserIDs []int64 `mapstructure:"user_ids"`
+}
+
+type SearchConfig struct {
+	// 是否开启全文索引，关闭时使用MySQL模糊查询
+	Enable bool `mapstructure:"enable"`
+	// 单次全文检索最多召回的商品数量
+	MaxHits int `mapstructure:"max_hits"`
+	// 商品至少需要匹配的查询词项比例
+	MinShouldMatch float64 `mapstructure:"min_should_match"`
+	// 全量重建索引的间隔(分钟)，0表示只在启动时构建
+	RebuildInterval int `mapstructure:"rebuild_interval"`
+	// 同义词组，每组使用英文逗号分隔
+	Synonyms []string `mapstructure:"synonyms"`
+	// 搜索补全、热搜词返回的最大数量
+	SuggestSize int `mapstructure:"suggest_size"`
+	// 重建搜索补全前缀索引的间隔(分钟)
+	SuggestRebuildInterval int `mapstructure:"suggest_rebuild_interval"`
+	// 热搜词热度衰减的间隔(分钟)
+	HotDecayInterval int `mapstructure:"hot_decay_interval"`
+	// 每次衰减时热度乘以的系数，取值(0,1)
+	HotDecayFactor float64 `mapstructure:"hot_decay_factor"`
+}
+
+type TracingConfig struct {
+	// 链路的导出方式：none(不导出)、otlp(发送到OTLP HTTP接收端，例如Jaeger、OpenTelemetry Collector)、file(写入本地文件，用于离线排查)
+	Exporter string `mapstructure:"exporter"`
+	// OTLP HTTP接收端的地址，例如127.0.0.1:4318
+	Endpoint string `mapstructure:"endpoint"`
+	// 是否使用HTTP而不是HTTPS连接接收端
+	Insecure bool `mapstructure:"insecure"`
+	// file导出方式写入的文件，每行一个span的json
+	File string `mapstructure:"file"`
+	// 采样比例，取值[0,1]，上游已经采样的请求总是采样
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
 func Init() (err error) {
 	viper.SetConfigFile("config.yaml") // 指定配置文件
 	err = viper.ReadInConfig()         // 读取配置信息
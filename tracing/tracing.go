@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"os"
+	"shop-backend/lifecycle"
+	"shop-backend/settings"
+)
+
+// 链路的导出方式
+const (
+	// ExporterNone 不导出，仍然会传递上游的链路信息，默认
+	ExporterNone = "none"
+	// ExporterOTLP 通过OTLP HTTP发送到接收端
+	ExporterOTLP = "otlp"
+	// ExporterFile 写入本地文件，用于离线排查
+	ExporterFile = "file"
+)
+
+// 创建span时使用的instrumentation名称
+const instrumentationName = "shop-backend"
+
+// Init 根据配置创建链路的导出器，并设置全局的TracerProvider以及W3C Trace Context传播格式。
+// 服务退出时最后关闭，导出剩余的span
+func Init(serviceName, version string, cfg *settings.TracingConfig) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg == nil {
+		return nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	var err error
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return nil
+	case ExporterOTLP:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+	case ExporterFile:
+		file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return fmt.Errorf("不支持的链路导出方式: %s", cfg.Exporter)
+	}
+	if err != nil {
+		return err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+			semconv.ServiceVersionKey.String(version))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	lifecycle.Register("链路追踪", lifecycle.PhaseStorage, func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			_ = file.Close()
+		}
+		return err
+	})
+	zap.L().Info("初始化链路追踪成功", zap.String("exporter", cfg.Exporter), zap.Float64("sampleRatio", cfg.SampleRatio))
+	return nil
+}
+
+// Tracer 获取创建span的Tracer，Init之前获取的Tracer在Init之后同样生效
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Traced ctx中是否有链路，没有链路时数据库、缓存不创建span，避免产生大量孤立的span
+func Traced(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// Inject 将ctx中的链路信息写入map，用于通过消息、发件箱等传递链路。没有链路时返回nil
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract 从Inject写入的map中恢复链路信息
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// End 记录错误并结束span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,21 +1,22 @@
 package build
 
 import (
-	"go.uber.org/zap"
+	"context"
 	"shop-backend/dao/mysql"
+	"shop-backend/logger"
 	"shop-backend/models/pojo"
 	"shop-backend/models/vo"
 )
 
 // CreateCartProductVO 多协程构建购物车商品展示对象
-func CreateCartProductVO(product *pojo.Cart, channel chan *vo.CartProductVO) *vo.CartProductVO {
+func CreateCartProductVO(ctx context.Context, product *pojo.Cart, channel chan *vo.CartProductVO) *vo.CartProductVO {
 	cartVO := new(vo.CartProductVO)
 	cartVO.WG.Add(2)
 	// 将此对象放入到通道中
 	channel <- cartVO
 	// 开启两个协程，并发构建购物车商品展示对象
-	go setSkuInfo(channel, product.SkuID)
-	go setSpuInfo(channel, product.SkuID)
+	go setSkuInfo(ctx, channel, product.SkuID)
+	go setSpuInfo(ctx, channel, product.SkuID)
 	// 阻塞在此，直到VO对象完成sku、spu信息的填充
 	cartVO.WG.Wait()
 
@@ -28,14 +29,14 @@ func CreateCartProductVO(product *pojo.Cart, channel chan *vo.CartProductVO) *vo
 	cartVO.ProductSkuSpecification = product.Specification
 
 	if cartVO.Err != nil {
-		zap.L().Error("生成购物车商品展示对象失败")
+		logger.Ctx(ctx).Error("生成购物车商品展示对象失败")
 	}
 	return cartVO
 }
 
 // 完成对购物车商品对象中sku属性的赋值
-func setSkuInfo(channel chan *vo.CartProductVO, skuId int64) {
-	sku, err := mysql.SelectSkuBySkuID(skuId)
+func setSkuInfo(ctx context.Context, channel chan *vo.CartProductVO, skuId int64) {
+	sku, err := mysql.SelectSkuBySkuID(ctx, skuId)
 	// 从通道中获取对象
 	cartVO := <-channel
 	if err != nil {
@@ -52,8 +53,8 @@ func setSkuInfo(channel chan *vo.CartProductVO, skuId int64) {
 }
 
 // 完成对购物车商品对象中spu属性的赋值
-func setSpuInfo(channel chan *vo.CartProductVO, skuId int64) {
-	spu, err := mysql.SelectSpuBySkuID(skuId)
+func setSpuInfo(ctx context.Context, channel chan *vo.CartProductVO, skuId int64) {
+	spu, err := mysql.SelectSpuBySkuID(ctx, skuId)
 
 	// 从通道中获取对象
 	cartVO := <-channel
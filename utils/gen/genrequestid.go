@@ -0,0 +1,18 @@
+package gen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// GenRequestID 生成32位十六进制的请求ID
+func GenRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// 系统随机数不可用时退化为时间戳，只用于关联日志
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -8,10 +8,13 @@ import (
 	"shop-backend/utils/concatstr"
 	"shop-backend/utils/gen"
 	"strconv"
+	"strings"
 )
 
 var bucket *oss.Bucket
 var userAvatarPrefix string
+var brandLogoPrefix string
+var commentPicPrefix string
 var commonPrefix = "https://llshop-project.oss-cn-zhangjiakou.aliyuncs.com/"
 
 // Init 初始化阿里云OSS服务
@@ -22,6 +25,8 @@ func Init(cfg *settings.AliyunConfig) error {
 		cfg.AccessKeySecret,
 	)
 	userAvatarPrefix = cfg.UserAvatarPrefix
+	brandLogoPrefix = cfg.BrandLogoPrefix
+	commentPicPrefix = cfg.CommentPicPrefix
 	if err != nil {
 		zap.L().Error("init AliyunConfig OSS failed", zap.Error(err))
 		return err
@@ -32,12 +37,32 @@ func Init(cfg *settings.AliyunConfig) error {
 
 // UploadPic 上传文件到阿里云服务器
 func UploadPic(file io.Reader) (string, error) {
+	return uploadPicWithPrefix(file, userAvatarPrefix)
+}
+
+// UploadBrandLogo 上传品牌Logo到阿里云服务器
+func UploadBrandLogo(file io.Reader) (string, error) {
+	return uploadPicWithPrefix(file, brandLogoPrefix)
+}
+
+// UploadCommentPic 上传商品评价图片到阿里云服务器
+func UploadCommentPic(file io.Reader) (string, error) {
+	return uploadPicWithPrefix(file, commentPicPrefix)
+}
+
+// IsCommentPic 判断URL是否为上传到阿里云服务器的商品评价图片
+func IsCommentPic(url string) bool {
+	return strings.HasPrefix(url, concatstr.ConcatString(commonPrefix, commentPicPrefix))
+}
+
+// uploadPicWithPrefix 上传文件到阿里云服务器的prefix目录下
+func uploadPicWithPrefix(file io.Reader, prefix string) (string, error) {
 	// 雪花算法生成全局唯一图片名称
 	id := gen.GenSnowflakeID()
 	// 将int64转换为字符串
 	idStr := strconv.FormatInt(id, 10)
 	// 生成文件名
-	fileName := concatstr.ConcatString(userAvatarPrefix, idStr, ".png")
+	fileName := concatstr.ConcatString(prefix, idStr, ".png")
 	// 上传文件
 	if err := bucket.PutObject(fileName, file); err != nil {
 		// 上传失败
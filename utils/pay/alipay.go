@@ -4,6 +4,7 @@ import (
 	"github.com/smartwalle/alipay/v3"
 	"go.uber.org/zap"
 	"shop-backend/settings"
+	"sync/atomic"
 )
 
 var privateKey string
@@ -11,6 +12,9 @@ var appId string
 var Client *alipay.Client
 var err error
 
+// 支付模块是否已经初始化完成，1为已完成
+var ready int32
+
 func Init(cfg *settings.AliPayConfig) {
 	privateKey = cfg.PrivateKey
 	appId = cfg.AppID
@@ -23,6 +27,12 @@ func Init(cfg *settings.AliPayConfig) {
 		panic("初始化支付宝支付模块失败")
 		return
 	}
+	atomic.StoreInt32(&ready, 1)
+}
+
+// Ready 支付模块是否已经初始化完成，Init在后台执行，完成前Client不可用
+func Ready() bool {
+	return atomic.LoadInt32(&ready) == 1
 }
 
 // AliPay 生成支付宝网站支付页面